@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/app"
+)
+
+// configCommand runs the config subcommands.
+func configCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate":
+			return validateConfig(args[1:])
+		case "print":
+			return printConfig(args[1:])
+		}
+	}
+
+	return usageError{err: errors.New("usage: ddns config validate|print [--redacted]")}
+}
+
+// validateConfig reads and validates the config without calling the
+// providers.
+func validateConfig(args []string) error {
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	cnf, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	simpleDDNS, err := cnf.GetSimpleDDNSConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := app.Validate(simpleDDNS); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	_, err = fmt.Printf("%s is valid\n", cnf.File())
+	return err
+}
+
+// printConfig prints the config as read, with the defaults of the file
+// format applied.
+func printConfig(args []string) error {
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redact := flags.Bool("redacted", false, "replace credentials with REDACTED")
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	cnf, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return cnf.WriteYAML(os.Stdout, *redact)
+}
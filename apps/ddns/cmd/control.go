@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/app"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/control"
+)
+
+const controlTimeout = 10 * time.Second
+
+// controlCommand sends a command to the running daemon through the control
+// socket: force a reconcile, pause or resume the updates or print the live
+// status.
+func controlCommand(args []string) error {
+	flags := flag.NewFlagSet("control", flag.ContinueOnError)
+	socket := flags.String("socket", "", "path of the control socket, by default ddns.control.socket")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: ddns control [--socket path] force|pause|resume|status\n\n") //nolint:errcheck
+		flags.PrintDefaults()
+	}
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return usageError{err: errors.New("control needs one of force, pause, resume or status")}
+	}
+
+	path := *socket
+	if path == "" {
+		simpleDDNS, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if path = simpleDDNS.DDNS.Control.Socket; path == "" {
+			return errors.New("the control socket is not enabled, set ddns.control.socket")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+
+	response, err := control.Send(ctx, path, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if response.Status == nil {
+		_, err := fmt.Println(response.Message)
+		return err
+	}
+
+	return printLiveStatus(response.Status)
+}
+
+func printLiveStatus(status *control.Status) error {
+	lastUpdate := "never"
+	if !status.LastUpdate.IsZero() {
+		lastUpdate = status.LastUpdate.Local().Format(time.RFC3339)
+	}
+
+	state := "running"
+	if status.Paused {
+		state = "paused"
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Updates:\t%s\n", state)                       //nolint:errcheck
+	fmt.Fprintf(writer, "IPv4:\t%s\n", dash(status.IPv4))              //nolint:errcheck
+	fmt.Fprintf(writer, "IPv6:\t%s\n", dash(status.IPv6))              //nolint:errcheck
+	fmt.Fprintf(writer, "Last update:\t%s\n", lastUpdate)              //nolint:errcheck
+	fmt.Fprintf(writer, "Last error:\t%s\n\n", dash(status.Error))     //nolint:errcheck
+	fmt.Fprintln(writer, "RECORD\tTYPE\tVALUE\tZONE\tSTATUS\tUPDATED") //nolint:errcheck
+	for _, record := range status.Records {
+		updated := ""
+		if !record.UpdatedAt.IsZero() {
+			updated = record.UpdatedAt.Local().Format(time.RFC3339)
+		}
+
+		recordStatus := record.Status
+		if record.Error != "" {
+			recordStatus += ": " + record.Error
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+			record.FQDN,
+			record.RecordType,
+			dash(record.Value),
+			dash(record.Zone),
+			recordStatus,
+			dash(updated),
+		)
+	}
+
+	return writer.Flush()
+}
+
+// serveControl serves the control socket when configured and reconciles
+// on SIGUSR1, until ctx is done.
+func serveControl(ctx context.Context, socket string, ddns *app.DDNS) error {
+	if socket != "" {
+		if err := control.Serve(ctx, socket, ddns); err != nil {
+			return err
+		}
+	}
+
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+
+	go func() {
+		defer signal.Stop(usr1)
+
+		for {
+			select {
+			case <-usr1:
+				if err := ddns.Reconcile(); err != nil {
+					log.Warnf("received SIGUSR1, not reconciling: %v", err)
+					continue
+				}
+				log.Info("received SIGUSR1, reconciling")
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
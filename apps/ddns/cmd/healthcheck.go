@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const healthcheckTimeout = 5 * time.Second
+
+// healthcheck queries the health endpoint of a running daemon, for use as
+// the HEALTHCHECK of images without curl. It fails unless the daemon answers
+// 200.
+func healthcheck(args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	ready := flags.Bool("ready", false, "check readiness instead of liveness")
+	url := flags.String("url", "", "base URL of the daemon, by default built from ddns.http.listen")
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	base := *url
+	if base == "" {
+		simpleDDNS, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if base, err = localURL(simpleDDNS.DDNS.HTTP.Listen); err != nil {
+			return err
+		}
+	}
+
+	path := healthPath
+	if *ready {
+		path = readyPath
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// localURL returns the URL reaching a listen address from the same host.
+func localURL(listen string) (string, error) {
+	if listen == "" {
+		return "", errors.New("http listener is not enabled, set ddns.http.listen")
+	}
+
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", err
+	}
+
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	return "http://" + net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/history"
+)
+
+// showHistory prints the address changes and record updates kept in the
+// history, optionally only those of a record or since a point in time.
+func showHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	fqdn := flags.String("fqdn", "", "only show the updates of this record")
+	since := flags.String("since", "", "only show events since this time, RFC 3339 or a duration ago such as 24h")
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	filter := domain.HistoryFilter{FQDN: *fqdn}
+	if *since != "" {
+		sinceTime, err := parseSince(*since, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = sinceTime
+	}
+
+	simpleDDNS, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	historyConfig := simpleDDNS.DDNS.History
+	if historyConfig.Path == "" {
+		return errors.New("history is not enabled, set ddns.history.path")
+	}
+
+	h, err := history.NewJSONL(historyConfig.Path, historyConfig.MaxSizeMB, historyConfig.MaxFiles)
+	if err != nil {
+		return err
+	}
+
+	events, err := h.Query(filter)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tEVENT\tRECORD\tTYPE\tOLD\tNEW\tPROVIDER\tZONE\tOUTCOME\tCHANGES\tDURATION") //nolint:errcheck
+	for _, event := range events {
+		outcome := event.Outcome
+		if event.Error != "" {
+			outcome += ": " + event.Error
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+			event.Time.Local().Format(time.RFC3339),
+			event.Kind,
+			dash(event.FQDN),
+			dash(event.RecordType),
+			dash(event.OldValue),
+			dash(event.NewValue),
+			dash(event.Provider),
+			dash(event.Zone),
+			dash(outcome),
+			dash(strings.Join(event.ChangeIDs, ",")),
+			dash(durationString(event.Duration)),
+		)
+	}
+
+	return writer.Flush()
+}
+
+// parseSince accepts an RFC 3339 time or a duration before now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+
+	ago, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q, use RFC 3339 or a duration such as 24h", value)
+	}
+
+	return now.Add(-ago), nil
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return d.String()
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/hooks"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/updater/r53"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+// iamPolicy prints the least privilege IAM policy for one of the configured
+// AWS accounts, including the security groups its hooks update.
+func iamPolicy(args []string) error {
+	flags := flag.NewFlagSet("iam-policy", flag.ContinueOnError)
+	accountName := flags.String("account", "", "account-name of the AWS account, required when more than one is configured")
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	simpleDDNS, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	account, err := selectAccount(simpleDDNS.DDNS.AWS, *accountName)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	policy := r53.IAMPolicy(account)
+	if statement := hooks.IAMStatement(account.AccountName, simpleDDNS.DDNS.Hooks); statement != nil {
+		policy.Statement = append(policy.Statement, *statement)
+	}
+
+	return encoder.Encode(policy)
+}
+
+func selectAccount(accounts []config.AWSConfig, name string) (config.AWSConfig, error) {
+	if name == "" {
+		switch len(accounts) {
+		case 0:
+			return config.AWSConfig{}, errors.New("no aws accounts configured")
+		case 1:
+			return accounts[0], nil
+		}
+	}
+
+	names := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		if account.AccountName == name {
+			return account, nil
+		}
+		names = append(names, account.AccountName)
+	}
+
+	if name == "" {
+		return config.AWSConfig{}, fmt.Errorf("--account is required, configured accounts: %s", strings.Join(names, ", "))
+	}
+
+	return config.AWSConfig{}, fmt.Errorf("account %q not found, configured accounts: %s", name, strings.Join(names, ", "))
+}
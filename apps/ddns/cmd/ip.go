@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// showAddresses prints the addresses detected from the public address
+// detector and from every other source of the configured records.
+func showAddresses(args []string) error {
+	flags := flag.NewFlagSet("ip", flag.ContinueOnError)
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	ddns, err := createApp()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(ddns.UpdateTimeout())
+	defer cancel()
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SOURCE\tIPV4\tIPV6\tERROR") //nolint:errcheck
+	for _, detected := range ddns.DetectAddresses(ctx) {
+		errMsg := ""
+		if detected.Err != nil {
+			errMsg = strings.ReplaceAll(detected.Err.Error(), "\n", "; ")
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", //nolint:errcheck
+			sourceName(detected.Source.Type, detected.Source.Interface),
+			dash(detected.IPv4),
+			dash(detected.IPv6),
+			dash(errMsg),
+		)
+	}
+
+	return writer.Flush()
+}
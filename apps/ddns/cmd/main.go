@@ -2,23 +2,184 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/app"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/tracing"
 	"github.com/jorgesanchez-e/localenvironment/config"
 )
 
+// exit codes, once exits with exitPartial when only some records could be
+// updated.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	exitPartial = 3
+)
+
+// global flags, they go before the command.
+var (
+	configPath string
+	logLevel   string
+	logFormat  string
+)
+
+// commands are the subcommands of ddns, run is used when none is given.
+var commands = map[string]func(args []string) error{
+	"run":         run,
+	"once":        once,
+	"status":      status,
+	"records":     records,
+	"ip":          showAddresses,
+	"config":      configCommand,
+	"iam-policy":  iamPolicy,
+	"history":     showHistory,
+	"healthcheck": healthcheck,
+	"plan":        plan,
+	"control":     controlCommand,
+}
+
+// usageError is a mistake in the command line.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
 func main() {
+	flags := flag.NewFlagSet("ddns", flag.ContinueOnError)
+	flags.StringVar(&configPath, "config", "", "path of the config file, by default it is searched in the usual locations")
+	flags.StringVar(&logLevel, "log-level", "", "log level, overrides ddns.log-level of the config")
+	flags.StringVar(&logFormat, "log-format", "", "text or json, overrides ddns.log-format of the config")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: ddns [--config path] [--log-level level] [--log-format text|json] [command]\n\ncommands: %s\n\n", strings.Join(commandNames(), ", ")) //nolint:errcheck
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(exitCode(parseError(err)))
+	}
+
+	if err := configureLog("", ""); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err) //nolint:errcheck
+		os.Exit(exitUsage)
+	}
+
+	name, args := "run", flags.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q, commands: %s\n", name, strings.Join(commandNames(), ", ")) //nolint:errcheck
+		os.Exit(exitUsage)
+	}
+
+	err := command(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err) //nolint:errcheck
+	}
+
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	var usage usageError
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, app.ErrPartialUpdate):
+		return exitPartial
+	}
+
+	return exitFailure
+}
+
+// parseError marks the errors of parsing the flags as usage errors.
+func parseError(err error) error {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+
+	return usageError{err: err}
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// run keeps the records updated until the process is stopped.
+func run(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
 	setLog()
-	app := createApp()
+	cnf, simpleDDNS, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	ddns, err := app.NewDDNS(simpleDDNS)
+	if err != nil {
+		return fmt.Errorf("failed to create DDNS app: %w", err)
+	}
+
+	stopTracing, err := startTracing(simpleDDNS.DDNS.Tracing)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	watchConfig(ctx, cnf, ddns)
+	if err := serveControl(ctx, simpleDDNS.DDNS.Control.Socket, ddns); err != nil {
+		return err
+	}
+
+	if listen := simpleDDNS.DDNS.HTTP.Listen; listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		mux.Handle(healthPath, checkHandler(func() error { return ddns.Healthy(time.Now()) }))
+		mux.Handle(readyPath, checkHandler(ddns.Ready))
+		// metrics and health stay available until the running update drained
+		stopHTTP := serveHTTP(listen, mux)
+		defer stopHTTP()
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
@@ -26,43 +187,173 @@ func main() {
 		sig := <-sigCh
 		log.Infof("received signal %v, shutting down", sig)
 		cancel()
+
+		sig = <-sigCh
+		log.Warnf("received signal %v again, exiting without waiting for the running update", sig)
+		os.Exit(exitFailure)
 	}()
 
-	app.Run(ctx)
+	ddns.Run(ctx)
+	return nil
+}
+
+// once runs a single cycle and exits, for running ddns from cron or a
+// systemd timer.
+func once(args []string) error {
+	flags := flag.NewFlagSet("once", flag.ContinueOnError)
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	setLog()
+	simpleDDNS, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ddns, err := app.NewDDNS(simpleDDNS)
+	if err != nil {
+		return fmt.Errorf("failed to create DDNS app: %w", err)
+	}
+
+	stopTracing, err := startTracing(simpleDDNS.DDNS.Tracing)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	ctx, cancel := commandContext(ddns.UpdateTimeout())
+	defer cancel()
+
+	return ddns.Once(ctx)
 }
 
+// startTracing exports the spans when tracing is configured. The returned
+// function flushes the pending spans.
+func startTracing(tracingConfig config.TracingConfig) (func(), error) {
+	shutdown, err := tracing.Setup(context.Background(), tracingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := shutdown(ctx); err != nil {
+			log.Errorf("failed to flush traces: %v", err)
+		}
+	}, nil
+}
+
+// setLog sends the logs of the daemon to stdout instead of the default
+// stderr.
 func setLog() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.TextFormatter{
-		FullTimestamp:          true,
-		TimestampFormat:        "2006-01-02 15:04:05",
-		DisableColors:          true,
-		DisableLevelTruncation: true,
-	})
-
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
 	log.SetOutput(os.Stdout)
+}
+
+// configureLog uses the level and format of the flags, then the ones of the
+// config, and info level text logs when neither is set.
+func configureLog(configLevel, configFormat string) error {
+	level := log.InfoLevel
+	if name := firstSet(logLevel, configLevel); name != "" {
+		var err error
+		if level, err = log.ParseLevel(name); err != nil {
+			return fmt.Errorf("invalid log level: %w", err)
+		}
+	}
+
+	switch format := firstSet(logFormat, configFormat); format {
+	case "", "text":
+		log.SetFormatter(&log.TextFormatter{
+			FullTimestamp:          true,
+			TimestampFormat:        "2006-01-02 15:04:05",
+			DisableColors:          true,
+			DisableLevelTruncation: true,
+		})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	default:
+		return fmt.Errorf("invalid log format %q, use text or json", format)
+	}
+
+	log.SetLevel(level)
+	return nil
+}
+
+func firstSet(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// configFile is the config as read from its file.
+type configFile interface {
+	File() string
+	GetSimpleDDNSConfig() (*config.SimpleDDNS, error)
+	WriteYAML(w io.Writer, redact bool) error
+	Reload() error
+	Watch(ctx context.Context, onChange func(err error)) error
+}
+
+// readConfig reads the file given with --config or the first one found in
+// the default locations.
+func readConfig() (configFile, error) {
+	if configPath != "" {
+		return config.NewFromFile(configPath)
+	}
 
-	// Only log the warning severity or above.
-	log.SetLevel(log.InfoLevel)
+	return config.New()
 }
 
-func createApp() *app.DDNS {
-	cnf, err := config.New()
+func loadConfig() (*config.SimpleDDNS, error) {
+	_, simpleDDNS, err := loadConfigFile()
+	return simpleDDNS, err
+}
+
+// loadConfigFile is loadConfig keeping the file, to read it again later.
+func loadConfigFile() (configFile, *config.SimpleDDNS, error) {
+	cnf, err := readConfig()
 	if err != nil {
-		log.Fatalf("failed to read config: %v", err)
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
 	simpleDDNS, err := cnf.GetSimpleDDNSConfig()
 	if err != nil {
-		log.Fatalf("Failed to get simple DDNS config: %v", err)
+		return nil, nil, fmt.Errorf("failed to get simple DDNS config: %w", err)
+	}
+
+	if err := configureLog(simpleDDNS.DDNS.LogLevel, simpleDDNS.DDNS.LogFormat); err != nil {
+		return nil, nil, err
 	}
 
-	app, err := app.NewDDNS(simpleDDNS)
+	return cnf, simpleDDNS, nil
+}
+
+func createApp() (*app.DDNS, error) {
+	simpleDDNS, err := loadConfig()
 	if err != nil {
-		log.Fatalf("failed to create DDNS app: %v", err)
+		return nil, err
+	}
+
+	ddns, err := app.NewDDNS(simpleDDNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DDNS app: %w", err)
+	}
+
+	return ddns, nil
+}
+
+// commandContext limits the provider calls of a command to the configured
+// timeout.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
 	}
 
-	return app
+	return context.WithTimeout(context.Background(), timeout)
 }
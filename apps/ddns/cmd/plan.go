@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+type plannedChange struct {
+	Provider      string   `json:"provider"`
+	Zone          string   `json:"zone"`
+	Action        string   `json:"action"`
+	FQDN          string   `json:"fqdn"`
+	Type          string   `json:"type"`
+	TTL           int      `json:"ttl"`
+	Values        []string `json:"values"`
+	Previous      string   `json:"previous,omitempty"`
+	HealthCheck   string   `json:"health_check,omitempty"`
+	HealthCheckID string   `json:"health_check_id,omitempty"`
+}
+
+// plan prints the changes ddns would send to the providers with the current
+// addresses, without changing anything.
+func plan(args []string) error {
+	flags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "print the changes as JSON")
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	ddns, err := createApp()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(ddns.UpdateTimeout())
+	defer cancel()
+
+	changes, err := ddns.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		return printPlanJSON(os.Stdout, changes)
+	}
+
+	return printPlan(os.Stdout, changes)
+}
+
+func printPlanJSON(w io.Writer, changes []domain.PlannedChange) error {
+	output := make([]plannedChange, 0, len(changes))
+	for _, change := range changes {
+		output = append(output, plannedChange(change))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(output)
+}
+
+// printPlan prints the changes grouped by provider and zone: + for new
+// values, ~ for changed ones and - for removed ones, followed by what happens
+// to the health check of the record.
+func printPlan(w io.Writer, changes []domain.PlannedChange) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	zones := 0
+	current := ""
+	for _, change := range changes {
+		if zone := change.Provider + " zone " + change.Zone; zone != current {
+			current = zone
+			zones++
+			if _, err := fmt.Fprintf(w, "%s:\n", zone); err != nil {
+				return err
+			}
+		}
+
+		values := strings.Join(change.Values, " ")
+		line := fmt.Sprintf("  + %s %s %d: %s", change.FQDN, change.Type, change.TTL, values)
+		switch {
+		case change.Action == "DELETE":
+			line = fmt.Sprintf("  - %s %s %d: %s", change.FQDN, change.Type, change.TTL, values)
+		case change.Previous != "":
+			line = fmt.Sprintf("  ~ %s %s %d: %s -> %s", change.FQDN, change.Type, change.TTL, change.Previous, values)
+		}
+
+		if check := healthCheck(change); check != "" {
+			line += " (" + check + ")"
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d changes in %d zones.\n", len(changes), zones)
+	return err
+}
+
+// healthCheck describes what happens to the health check of a change.
+func healthCheck(change domain.PlannedChange) string {
+	switch {
+	case change.HealthCheck == "":
+		return ""
+	case change.HealthCheckID == "":
+		return change.HealthCheck + " health check"
+	default:
+		return change.HealthCheck + " health check " + change.HealthCheckID
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// records runs the records subcommands.
+func records(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return usageError{err: errors.New("usage: ddns records list")}
+	}
+
+	return listRecords(args[1:])
+}
+
+// listRecords prints the configured records as currently published by the
+// providers.
+func listRecords(args []string) error {
+	flags := flag.NewFlagSet("records list", flag.ContinueOnError)
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	ddns, err := createApp()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(ddns.UpdateTimeout())
+	defer cancel()
+
+	published, err := ddns.GetRecords(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RECORD\tTYPE\tVALUE\tZONE\tSOURCE") //nolint:errcheck
+	for _, record := range published {
+		source := "public"
+		if record.Source != nil {
+			source = sourceName(record.Source.Type, record.Source.Interface)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", record.FQDN, record.IPType, dash(record.IP), dash(record.Zone), source) //nolint:errcheck
+	}
+
+	return writer.Flush()
+}
+
+func sourceName(sourceType, iface string) string {
+	if iface == "" {
+		return sourceType
+	}
+
+	return sourceType + " " + iface
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/app"
+)
+
+// watchConfig applies the config file to ddns every time it is written or
+// SIGHUP is received, until ctx is done. A config that cannot be applied is
+// logged and the running one kept.
+func watchConfig(ctx context.Context, cnf configFile, ddns *app.DDNS) {
+	written := make(chan error, 1)
+	err := cnf.Watch(ctx, func(err error) {
+		select {
+		case written <- err:
+		default:
+		}
+	})
+	if err != nil {
+		log.Errorf("not watching config file %s, reload it with SIGHUP: %v", cnf.File(), err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case err := <-written:
+				log.Infof("config file %s changed, reloading", cnf.File())
+				if err != nil {
+					log.Errorf("failed to read config, keeping the running config: %v", err)
+					continue
+				}
+			case <-hup:
+				log.Info("received SIGHUP, reloading the config")
+				if err := cnf.Reload(); err != nil {
+					log.Errorf("failed to read config, keeping the running config: %v", err)
+					continue
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			reload(cnf, ddns)
+		}
+	}()
+}
+
+func reload(cnf configFile, ddns *app.DDNS) {
+	simpleDDNS, err := cnf.GetSimpleDDNSConfig()
+	if err != nil {
+		log.Errorf("invalid config, keeping the running config: %v", err)
+		return
+	}
+
+	changed, err := ddns.Reload(simpleDDNS)
+	if err != nil {
+		log.Errorf("failed to apply config, keeping the running config: %v", err)
+		return
+	}
+
+	if err := configureLog(simpleDDNS.DDNS.LogLevel, simpleDDNS.DDNS.LogFormat); err != nil {
+		log.Errorf("keeping the running log settings: %v", err)
+	}
+
+	if len(changed) == 0 {
+		log.Info("config reloaded, nothing changed")
+		return
+	}
+
+	log.Infof("config reloaded, changed: %s", strings.Join(changed, ", "))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/state"
+)
+
+// status prints the addresses and records kept in the state by the daemon.
+func status(args []string) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	if err := parseError(flags.Parse(args)); err != nil {
+		return err
+	}
+
+	simpleDDNS, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if simpleDDNS.DDNS.StateDir == "" {
+		return errors.New("state is not enabled, set ddns.state-dir")
+	}
+
+	store, err := state.NewFileStore(simpleDDNS.DDNS.StateDir)
+	if err != nil {
+		return err
+	}
+
+	current, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		_, err := fmt.Println("No state saved yet.")
+		return err
+	}
+
+	reconciled := "never"
+	if !current.ReconciledAt.IsZero() {
+		reconciled = current.ReconciledAt.Local().Format(time.RFC3339)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "IPv4:\t%s\n", dash(current.IPv4))                //nolint:errcheck
+	fmt.Fprintf(writer, "IPv6:\t%s\n", dash(current.IPv6))                //nolint:errcheck
+	fmt.Fprintf(writer, "Reconciled:\t%s\n\n", reconciled)                //nolint:errcheck
+	fmt.Fprintln(writer, "RECORD\tTYPE\tVALUE\tZONE\tPUBLISHED\tCHANGES") //nolint:errcheck
+	for _, record := range current.Records {
+		published := ""
+		if !record.PublishedAt.IsZero() {
+			published = record.PublishedAt.Local().Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+			record.FQDN,
+			record.IPType,
+			dash(record.IP),
+			dash(record.Zone),
+			dash(published),
+			dash(strings.Join(record.ChangeIDs, ",")),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+var ErrPaused = errors.New("updates are paused")
+
+// Reconcile starts a cycle right away that reads the records from the
+// providers instead of the state.
+func (ddns *DDNS) Reconcile() error {
+	if ddns.paused.Load() {
+		return ErrPaused
+	}
+
+	ddns.reconcile.Store(true)
+	ddns.Force()
+	return nil
+}
+
+// Pause skips the checks until Resume is called, the running cycle
+// finishes.
+func (ddns *DDNS) Pause() {
+	if !ddns.paused.Swap(true) {
+		log.Info("updates paused")
+	}
+}
+
+// Resume starts checking again, right away.
+func (ddns *DDNS) Resume() {
+	if ddns.paused.Swap(false) {
+		log.Info("updates resumed")
+		ddns.Force()
+	}
+}
+
+// Status is the status after the last cycle. It does not wait for the
+// running one.
+func (ddns *DDNS) Status() domain.Status {
+	ddns.statusMu.Lock()
+	status := ddns.status
+	ddns.statusMu.Unlock()
+
+	status.Records = append([]domain.RecordStatus(nil), status.Records...)
+	status.Paused = ddns.paused.Load()
+	return status
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+func TestDDNS_PauseResume(t *testing.T) {
+	provider := &mockProvider{
+		records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"}},
+	}
+	ddns := &DDNS{
+		IPGetter: &mockIPGetter{ipv4: "192.0.2.10"},
+		DDNS:     provider,
+		force:    make(chan struct{}, 1),
+	}
+
+	ddns.Pause()
+	ddns.do(context.Background(), nil)
+	assert.Zero(t, provider.getCalls)
+	assert.True(t, ddns.Status().Paused)
+	assert.ErrorIs(t, ddns.Reconcile(), ErrPaused)
+	assert.Empty(t, ddns.force)
+
+	ddns.Resume()
+	assert.Len(t, ddns.force, 1)
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 1, provider.getCalls)
+	assert.False(t, ddns.Status().Paused)
+}
+
+func TestDDNS_Reconcile(t *testing.T) {
+	provider := &mockProvider{
+		records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10", Zone: "Z123"}},
+	}
+	ddns := &DDNS{
+		reconcileEvery: time.Hour,
+		store:          &mockStateStore{},
+		IPGetter:       &mockIPGetter{ipv4: "192.0.2.10"},
+		DDNS:           provider,
+		force:          make(chan struct{}, 1),
+	}
+	ddns.loadState()
+
+	ddns.do(context.Background(), nil)
+	ddns.do(context.Background(), nil)
+	require.Equal(t, 1, provider.getCalls)
+
+	// a reconcile reads the provider although the state is fresh
+	require.NoError(t, ddns.Reconcile())
+	assert.Len(t, ddns.force, 1)
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 2, provider.getCalls)
+
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 2, provider.getCalls)
+}
+
+func TestDDNS_Status(t *testing.T) {
+	provider := &mockProvider{
+		records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"}},
+	}
+	ddns := &DDNS{
+		IPGetter: &mockIPGetter{ipv4: "192.0.2.10"},
+		DDNS:     provider,
+	}
+
+	assert.Empty(t, ddns.Status().Records)
+
+	require.NoError(t, ddns.Once(context.Background()))
+
+	status := ddns.Status()
+	assert.Equal(t, "192.0.2.10", status.IPv4)
+	assert.False(t, status.LastUpdate.IsZero())
+	require.Len(t, status.Records, 1)
+	assert.Equal(t, "192.0.2.10", status.Records[0].Value)
+	assert.Equal(t, domain.RecordOK, status.Records[0].Status)
+}
@@ -2,135 +2,462 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/history"
 	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/ipgetter"
-	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/updater"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/mqtt"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/schedule"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/state"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/tracing"
 	"github.com/jorgesanchez-e/localenvironment/config"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
 	ErrCheckInSecondsMustBeLessThanUpdateTimeoutSeconds = fmt.Errorf("checkInSeconds must be less than updateTimeoutSeconds")
 	ErrCheckInSecondsMustBeGreaterThanZero              = fmt.Errorf("checkInSeconds must be greater than zero")
+	// ErrPartialUpdate is returned when some records were updated and others
+	// failed.
+	ErrPartialUpdate = errors.New("some records failed to update")
+	// ErrShuttingDown is returned by the cycles started after Run was asked
+	// to stop.
+	ErrShuttingDown = errors.New("shutting down")
 )
 
 type DDNS struct {
 	elapseTimeToCheck time.Duration
 	updateTimeout     time.Duration
+	reconcileEvery    time.Duration
+	shutdownGrace     time.Duration
+	retryInitial      time.Duration
+	jitterPercent     int
+	recordSchedules   map[string][]string
+	schedules         map[string]schedule.Schedule
+	healthIntervals   int
+	dryRun            bool
+	interfaceAddrs    func(name string) ([]net.Addr, error)
+	sources           []domain.IPSource
+	store             domain.StateStore
+	state             *domain.State
+	history           domain.History
+	public            addresses
+	configHash        string
+	health            health
+	notifier          domain.Notifier
+	failureThreshold  int
+	failures          int
+	pending           []domain.Notification
+	background        sync.WaitGroup
+	hooks             domain.Hooks
+	hooksMu           sync.Mutex
+	changes           []domain.AddressChange
+	hooksRunning      bool
+	publisher         domain.StatusPublisher
+	records           map[string]domain.RecordStatus
+	lastUpdate        time.Time
+	force             chan struct{}
+	reconcile         atomic.Bool
+	paused            atomic.Bool
+	stopping          atomic.Bool
+	cycles            sync.WaitGroup
+	statusMu          sync.Mutex
+	status            domain.Status
+	config            *config.SimpleDDNS
+	// settingsMu guards the settings read while a cycle runs, mu the rest.
+	settingsMu sync.RWMutex
+	mu         sync.Mutex
 	domain.IPGetter
 	domain.DDNS
 }
 
 func NewDDNS(awsConfig *config.SimpleDDNS) (*DDNS, error) {
-	ddnsUpdater, err := updater.NewUpdater(awsConfig)
+	s, err := newSettings(awsConfig, settings{})
 	if err != nil {
 		return nil, err
 	}
 
-	elapseTimeToCheck := time.Duration(awsConfig.DDNS.CheckEverySeconds) * time.Second
-	updateTimeout := time.Duration(awsConfig.DDNS.UpdateTimeoutSeconds) * time.Second
+	ddns := &DDNS{
+		force:          make(chan struct{}, 1),
+		interfaceAddrs: interfaceAddrs,
+		config:         awsConfig,
+		IPGetter:       ipgetter.NewIPGetter(),
+	}
+	ddns.apply(s)
+
+	if awsConfig.DDNS.StateDir != "" {
+		store, err := state.NewFileStore(awsConfig.DDNS.StateDir)
+		if err != nil {
+			return nil, err
+		}
 
-	if elapseTimeToCheck <= 0 {
-		return nil, ErrCheckInSecondsMustBeGreaterThanZero
+		ddns.store = store
+		ddns.loadState()
+	} else {
+		ddns.state = &domain.State{ConfigHash: ddns.configHash}
 	}
 
-	if elapseTimeToCheck < updateTimeout {
-		return nil, ErrCheckInSecondsMustBeLessThanUpdateTimeoutSeconds
+	if awsConfig.DDNS.History.Path != "" {
+		h, err := history.NewJSONL(awsConfig.DDNS.History.Path, awsConfig.DDNS.History.MaxSizeMB, awsConfig.DDNS.History.MaxFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		ddns.history = h
+	}
+
+	if awsConfig.DDNS.MQTT.Broker != "" {
+		ddns.publisher = mqtt.New(awsConfig.DDNS.MQTT)
 	}
 
-	return &DDNS{
-		time.Duration(awsConfig.DDNS.CheckEverySeconds) * time.Second,
-		time.Duration(awsConfig.DDNS.UpdateTimeoutSeconds) * time.Second,
-		ipgetter.NewIPGetter(),
-		ddnsUpdater,
-	}, nil
+	return ddns, nil
+}
+
+// UpdateTimeout is how long a cycle may take.
+func (ddns *DDNS) UpdateTimeout() time.Duration {
+	ddns.settingsMu.RLock()
+	defer ddns.settingsMu.RUnlock()
+
+	return ddns.updateTimeout
+}
+
+// interval is the time between checks.
+func (ddns *DDNS) interval() time.Duration {
+	ddns.settingsMu.RLock()
+	defer ddns.settingsMu.RUnlock()
+
+	return ddns.elapseTimeToCheck
 }
 
 func (ddns *DDNS) Run(ctx context.Context) {
+	ddns.health.loop(true, time.Now())
+	defer ddns.health.loop(false, time.Now())
+
+	if ddns.publisher != nil {
+		ddns.publisher.Start(ddns.Force)
+		defer ddns.publisher.Stop()
+	}
+
+	// the cycles outlive ctx so stopping does not abort an update halfway,
+	// they are cancelled when the shutdown grace period is over.
+	cyclesCtx, cancelCycles := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelCycles()
+
+	// the schedules of a cycle are due again once it finishes, from its
+	// outcome
+	type result struct {
+		due []string
+		err error
+	}
+
+	sched := newScheduler(ddns)
+	timer := time.NewTimer(ddns.interval())
+	defer timer.Stop()
+
+	finished := make(chan result)
+	stopped := make(chan struct{})
+	start := func(due []string) {
+		ctxIteration, cancel := context.WithTimeout(cyclesCtx, ddns.interval()-1*time.Second) // -1 second to avoid timeout before the elapseTimeToCheck
+		ddns.cycles.Add(1)
+		go func() {
+			defer ddns.cycles.Done()
+			defer cancel()
+
+			err := ddns.do(ctxIteration, due)
+			select {
+			case finished <- result{due: due, err: err}:
+			case <-stopped:
+			}
+		}()
+	}
+
+	start(sched.all(time.Now()))
+
 loop:
 	for {
-		ctxIteration, cancel := context.WithTimeout(ctx, ddns.elapseTimeToCheck-1*time.Second) // -1 second to avoid timeout before the elapseTimeToCheck
-		go ddns.do(ctxIteration)
+		if wait, ok := sched.wait(time.Now()); ok {
+			timer.Reset(wait)
+		} else {
+			timer.Stop()
+		}
 
 		select {
-		case <-ctxIteration.Done():
-			cancel()
+		case <-timer.C:
+			if due := sched.due(time.Now()); len(due) > 0 {
+				start(due)
+			}
+		case <-ddns.force:
+			start(sched.all(time.Now()))
+		case r := <-finished:
+			sched.finished(r.due, r.err, time.Now())
 		case <-ctx.Done():
-			cancel()
 			break loop
 		}
 	}
 
+	close(stopped)
+	ddns.drain(cancelCycles)
 	log.Info("DDNS loop finished")
 }
 
-func (ddns *DDNS) do(ctx context.Context) {
-	var err error
+// drain refuses new cycles and waits for the running one, its state and its
+// notifications and hooks. Once the shutdown grace period is over the cycle
+// is cancelled, only its state is still saved.
+func (ddns *DDNS) drain(cancelCycles context.CancelFunc) {
+	ddns.stopping.Store(true)
+
+	ddns.settingsMu.RLock()
+	grace := ddns.shutdownGrace
+	ddns.settingsMu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		ddns.cycles.Wait()
+		ddns.background.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	log.Infof("shutting down, waiting up to %s for the running update", grace)
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Warnf("the running update did not finish within %s, cancelling it", grace)
+		cancelCycles()
+		ddns.cycles.Wait()
+	}
+}
+
+// Force starts a cycle right away instead of waiting for the next check.
+// Requests made while one is pending are merged.
+func (ddns *DDNS) Force() {
+	select {
+	case ddns.force <- struct{}{}:
+	default:
+	}
+}
+
+// do runs a cycle of the loop for the records of the due schedules,
+// returning why it failed.
+func (ddns *DDNS) do(ctx context.Context, due []string) error {
+	if ddns.paused.Load() {
+		log.Info("updates paused, skipping the check")
+		ddns.health.finished(time.Now())
+		return nil
+	}
+
+	ctx = cycleContext(ctx)
+	err := ddns.cycle(ctx, due)
+	switch {
+	case errors.Is(err, ErrShuttingDown):
+		logging.FromContext(ctx).Info("shutting down, skipping the check")
+		return nil
+	case err != nil:
+		logging.FromContext(ctx).Errorf("failed to update records: %v", err)
+	}
+
+	return err
+}
+
+// Once runs a single cycle: detects the addresses and updates the records
+// that do not match them. ErrPartialUpdate is returned when only some of the
+// records could be updated. It returns once the notifications and hooks of
+// the cycle are done.
+func (ddns *DDNS) Once(ctx context.Context) error {
+	defer ddns.background.Wait()
+
+	return ddns.cycle(cycleContext(ctx), nil)
+}
 
+// cycle updates the records of the due schedules, all of them when due is
+// nil.
+func (ddns *DDNS) cycle(ctx context.Context, due []string) (err error) {
+	ctx, span := tracing.Start(ctx, "ddns.cycle", attribute.Bool("ddns.dry_run", ddns.dryRun))
 	defer func() {
-		if err != nil {
-			log.Errorf("failed to update records: %v", err)
-		}
+		metrics.Cycles.WithLabelValues(cycleOutcome(err)).Inc()
+		ddns.health.finished(time.Now())
+		tracing.End(span, err)
+	}()
+
+	ddns.mu.Lock()
+	defer ddns.mu.Unlock()
+	if ddns.stopping.Load() {
+		return ErrShuttingDown
+	}
+
+	defer ddns.saveState(ctx)
+	defer ddns.sendNotifications(ctx)
+	defer func() { ddns.runHooks(ctx, err) }()
+	defer func() {
+		ddns.failed(err, time.Now())
+		ddns.publishStatus(err, time.Now())
 	}()
 
 	ip4, ip6 := ddns.getIPs(ctx)
 	if ip4 == nil && ip6 == nil {
-		return
+		return nil
 	}
 
-	records, err := ddns.GetRecords(ctx)
+	public := addresses{ipv4: value(ip4), ipv6: value(ip6)}
+	if public.ipv4 != "" || public.ipv6 != "" {
+		ddns.health.detected(time.Now())
+	}
+	ddns.detected(ctx, public, time.Now())
+
+	records, err := ddns.currentRecords(ctx)
 	if err != nil {
-		return
+		return err
+	}
+	ddns.recordsRead(records)
+	records = ddns.dueRecords(records, due)
+
+	if records = ddns.checkIPs(records, ddns.sourceAddresses(ctx, records, public)); len(records) == 0 {
+		logging.FromContext(ctx).Info("no records to update")
+		return nil
 	}
 
-	if records = ddns.checkIPs(records, ip4, ip6); len(records) == 0 {
-		log.Info("no records to update")
-		return
+	if ddns.dryRun {
+		changes, err := ddns.PlanRecords(ctx, records)
+		if err != nil {
+			return err
+		}
+		logPlan(ctx, changes)
+		return nil
 	}
 
-	err = ddns.UpdateRecords(ctx, records)
+	start := time.Now()
+	results, err := ddns.UpdateRecords(ctx, records)
+	updateMetrics(results, time.Since(start), time.Now())
+	for _, result := range results {
+		logging.FromContext(ctx).WithFields(log.Fields{
+			logging.FieldProvider: result.Provider,
+			logging.FieldZone:     result.Zone,
+		}).Debugf("zone %s: %d records updated, %d failed, atomic: %t, changes: %v",
+			result.Zone, len(result.Updated), len(result.Failed), result.Atomic, result.ChangeIDs)
+	}
+	ddns.published(results, err, time.Now())
+	ddns.recordsUpdated(results, time.Now())
+	ddns.appendHistory(ctx, updateEvents(results, start, time.Since(start))...)
+	ddns.notify(updateNotifications(results, time.Now())...)
+
+	err = updateError(results, err)
+	if !errors.Is(err, ErrPartialUpdate) {
+		ddns.health.providers(err)
+	}
+
+	return err
+}
+
+// updateError tells a failed update from one where only some records failed.
+func updateError(results []domain.UpdateResult, err error) error {
+	updated, failed := 0, 0
+	for _, result := range results {
+		updated += len(result.Updated)
+		failed += len(result.Failed)
+	}
+
+	switch {
+	case err != nil && updated > 0:
+		return fmt.Errorf("%w: %w", ErrPartialUpdate, err)
+	case err != nil:
+		return err
+	case failed > 0:
+		return fmt.Errorf("%w: %d records failed", ErrPartialUpdate, failed)
+	}
+
+	return nil
+}
+
+// currentRecords returns the records as published by the providers. They
+// are read from the state while it is fresh, so unchanged addresses cost no
+// provider calls.
+func (ddns *DDNS) currentRecords(ctx context.Context) ([]domain.Record, error) {
+	now := time.Now()
+	if !ddns.reconcile.Swap(false) && ddns.stateFresh(now) {
+		ddns.health.fromState(ddns.state.ReconciledAt, ddns.reconcileEvery)
+		return ddns.stateRecords(), nil
+	}
+
+	records, err := ddns.GetRecords(ctx)
+	ddns.health.providers(err)
 	if err != nil {
-		return
+		return nil, err
 	}
+
+	publishedMetrics(records)
+	ddns.reconciled(records, now)
+	return records, nil
 }
 
 func (ddns *DDNS) getIPs(ctx context.Context) (*string, *string) {
 	ipv4, err := ddns.GetIPV4(ctx)
 	if err != nil {
-		log.Errorf("failed to get IPv4: %v", err)
+		logging.FromContext(ctx).WithField(logging.FieldFamily, logging.FamilyIPv4).Errorf("failed to get IPv4: %v", err)
 	}
 
 	ipv6, err := ddns.GetIPV6(ctx)
 	if err != nil {
-		log.Errorf("failed to get IPv6: %v", err)
+		logging.FromContext(ctx).WithField(logging.FieldFamily, logging.FamilyIPv6).Errorf("failed to get IPv6: %v", err)
 	}
 
 	return &ipv4, &ipv6
 }
 
-func (ddns *DDNS) checkIPs(records []domain.Record, ipv4 *string, ipv6 *string) []domain.Record {
+// checkIPs returns the records whose value no longer matches the addresses
+// of their source, with the value they should have.
+func (ddns *DDNS) checkIPs(records []domain.Record, sources map[domain.IPSource]addresses) []domain.Record {
+	now := time.Now()
 	newRecords := make([]domain.Record, 0, len(records))
 
 	for _, record := range records {
-		if record.IPType == "A" && ipv4 != nil && *ipv4 != record.IP {
-			newRecords = append(newRecords, domain.Record{
-				IP:     *ipv4,
-				IPType: record.IPType,
-				FQDN:   record.FQDN,
-			})
+		source := sources[recordSource(record)]
+		desired, ok := desiredValue(record, source.ipv4, source.ipv6, now)
+		if !ok || upToDate(record, desired) {
+			continue
 		}
 
-		if record.IPType == "AAAA" && ipv6 != nil && *ipv6 != record.IP && *ipv6 != "" {
-			newRecords = append(newRecords, domain.Record{
-				IP:     *ipv6,
-				IPType: record.IPType,
-				FQDN:   record.FQDN,
-			})
-		}
+		newRecords = append(newRecords, domain.Record{
+			IP:       desired,
+			IPType:   record.IPType,
+			FQDN:     record.FQDN,
+			Spec:     record.Spec,
+			Previous: record.IP,
+			Zone:     record.Zone,
+			Source:   record.Source,
+		})
 	}
 
 	return newRecords
 }
+
+// cycleContext returns a context whose logs have an ID identifying the
+// cycle.
+func cycleContext(ctx context.Context) context.Context {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return ctx
+	}
+
+	return logging.WithFields(ctx, log.Fields{logging.FieldCycle: hex.EncodeToString(id)})
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateError(t *testing.T) {
+	errZone := errors.New("zone Z456 failed")
+	updated := domain.Record{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10"}
+	failed := domain.Record{FQDN: "nas.example.net.", IPType: "A", IP: "192.0.2.10"}
+
+	testCases := []struct {
+		name            string
+		results         []domain.UpdateResult
+		err             error
+		expectedErr     error
+		expectedPartial bool
+	}{
+		{
+			name:    "all records updated",
+			results: []domain.UpdateResult{{Zone: "Z123", Updated: []domain.Record{updated}}},
+		},
+		{
+			name:        "nothing updated",
+			results:     []domain.UpdateResult{{Zone: "Z456", Failed: []domain.Record{failed}, Err: errZone}},
+			err:         errZone,
+			expectedErr: errZone,
+		},
+		{
+			name: "some zones failed",
+			results: []domain.UpdateResult{
+				{Zone: "Z123", Updated: []domain.Record{updated}},
+				{Zone: "Z456", Failed: []domain.Record{failed}, Err: errZone},
+			},
+			err:             errZone,
+			expectedErr:     errZone,
+			expectedPartial: true,
+		},
+		{
+			name:            "some records failed",
+			results:         []domain.UpdateResult{{Zone: "Z123", Updated: []domain.Record{updated}, Failed: []domain.Record{failed}}},
+			expectedErr:     ErrPartialUpdate,
+			expectedPartial: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		results := testCase.results
+		err := testCase.err
+		expectedErr := testCase.expectedErr
+		expectedPartial := testCase.expectedPartial
+
+		t.Run(testCase.name, func(t *testing.T) {
+			got := updateError(results, err)
+
+			if expectedErr == nil {
+				assert.NoError(t, got)
+				return
+			}
+			assert.ErrorIs(t, got, expectedErr)
+			assert.Equal(t, expectedPartial, errors.Is(got, ErrPartialUpdate))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultHealthIntervals = 3
+
+var (
+	ErrLoopNotRunning   = errors.New("update loop is not running")
+	ErrNoAddressYet     = errors.New("no address detected yet")
+	ErrProvidersNotRead = errors.New("providers not reached yet")
+)
+
+// health tracks the loop for the health and readiness checks. It has its own
+// lock so the checks do not wait for a running cycle.
+type health struct {
+	mu           sync.Mutex
+	running      bool
+	started      time.Time
+	lastFinished time.Time
+	lastDetected time.Time
+	providersOK  bool
+	providersErr error
+	// reconciledAt is when the records served from the state were read
+	// from the providers, fresh for reconcileEvery.
+	reconciledAt   time.Time
+	reconcileEvery time.Duration
+}
+
+func (h *health) loop(running bool, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.running = running
+	if running {
+		h.started = now
+	}
+}
+
+func (h *health) finished(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastFinished = now
+}
+
+func (h *health) detected(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastDetected = now
+}
+
+// fromState records that the records were served from the state, read from
+// the providers at reconciledAt.
+func (h *health) fromState(reconciledAt time.Time, reconcileEvery time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.reconciledAt = reconciledAt
+	h.reconcileEvery = reconcileEvery
+}
+
+// providers records the outcome of reading or updating the records.
+func (h *health) providers(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.providersOK = err == nil
+	h.providersErr = err
+}
+
+// Healthy reports whether the loop is running and a cycle finished within
+// the last health intervals, counted from the start of the loop before the
+// first cycle finishes.
+func (ddns *DDNS) Healthy(now time.Time) error {
+	ddns.settingsMu.RLock()
+	limit := time.Duration(ddns.healthIntervals) * ddns.elapseTimeToCheck
+	ddns.settingsMu.RUnlock()
+
+	h := &ddns.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.running {
+		return ErrLoopNotRunning
+	}
+
+	last := h.lastFinished
+	if last.Before(h.started) {
+		last = h.started
+	}
+
+	if now.Sub(last) > limit {
+		return fmt.Errorf("no cycle finished since %s", last.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Ready reports whether an address was detected at least once and the last
+// provider call succeeded. Before any provider call, records served from the
+// state count only while their reconcile is within the reconcile interval.
+func (ddns *DDNS) Ready() error {
+	h := &ddns.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.lastDetected.IsZero() {
+		return ErrNoAddressYet
+	}
+
+	switch {
+	case h.providersErr != nil:
+		return fmt.Errorf("providers not reachable: %w", h.providersErr)
+	case h.providersOK:
+		return nil
+	case !h.reconciledAt.IsZero() && time.Since(h.reconciledAt) < h.reconcileEvery:
+		return nil
+	default:
+		return ErrProvidersNotRead
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+func TestDDNS_Healthy(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		running       bool
+		started       time.Time
+		lastFinished  time.Time
+		expectedError bool
+	}{
+		{
+			name:          "loop not running",
+			expectedError: true,
+		},
+		{
+			name:    "loop just started",
+			running: true,
+			started: now.Add(-time.Minute),
+		},
+		{
+			name:         "cycle finished recently",
+			running:      true,
+			started:      now.Add(-time.Hour),
+			lastFinished: now.Add(-2 * time.Minute),
+		},
+		{
+			name:          "no cycle finished in the last intervals",
+			running:       true,
+			started:       now.Add(-time.Hour),
+			lastFinished:  now.Add(-4 * time.Minute),
+			expectedError: true,
+		},
+		{
+			name:          "stuck since start",
+			running:       true,
+			started:       now.Add(-4 * time.Minute),
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		running := testCase.running
+		started := testCase.started
+		lastFinished := testCase.lastFinished
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			ddns := &DDNS{elapseTimeToCheck: time.Minute, healthIntervals: 3}
+			ddns.health.running = running
+			ddns.health.started = started
+			ddns.health.lastFinished = lastFinished
+
+			assert.Equal(t, expectedError, ddns.Healthy(now) != nil)
+		})
+	}
+}
+
+func TestDDNS_Ready(t *testing.T) {
+	errThrottled := errors.New("throttled")
+
+	ddns := &DDNS{}
+	assert.ErrorIs(t, ddns.Ready(), ErrNoAddressYet)
+
+	ddns.health.detected(time.Now())
+	assert.ErrorIs(t, ddns.Ready(), ErrProvidersNotRead)
+
+	ddns.health.providers(errThrottled)
+	assert.ErrorIs(t, ddns.Ready(), errThrottled)
+
+	ddns.health.providers(nil)
+	assert.NoError(t, ddns.Ready())
+}
+
+func TestDDNS_ReadyFromState(t *testing.T) {
+	errThrottled := errors.New("throttled")
+
+	testCases := []struct {
+		name          string
+		reconciledAgo time.Duration
+		providersErr  error
+		expectedError error
+	}{
+		{
+			name:          "reconciled within the interval",
+			reconciledAgo: 10 * time.Minute,
+		},
+		{
+			name:          "reconcile older than the interval",
+			reconciledAgo: 2 * time.Hour,
+			expectedError: ErrProvidersNotRead,
+		},
+		{
+			name:          "provider call failed",
+			reconciledAgo: 10 * time.Minute,
+			providersErr:  errThrottled,
+			expectedError: errThrottled,
+		},
+	}
+
+	for _, testCase := range testCases {
+		reconciledAgo := testCase.reconciledAgo
+		providersErr := testCase.providersErr
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			ddns := &DDNS{}
+			ddns.health.detected(time.Now())
+			if providersErr != nil {
+				ddns.health.providers(providersErr)
+			}
+			ddns.health.fromState(time.Now().Add(-reconciledAgo), time.Hour)
+
+			assert.ErrorIs(t, ddns.Ready(), expectedError)
+		})
+	}
+}
+
+func TestDDNS_cycleHealth(t *testing.T) {
+	ddns := &DDNS{
+		IPGetter: &mockIPGetter{ipv4: "192.0.2.10"},
+		DDNS: &mockProvider{
+			records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10", Zone: "Z123"}},
+		},
+	}
+
+	ddns.do(context.Background(), nil)
+
+	assert.NoError(t, ddns.Ready())
+	assert.False(t, ddns.health.lastFinished.IsZero())
+}
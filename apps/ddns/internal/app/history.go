@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+)
+
+// detected keeps the public addresses of the cycle, logging and recording
+// in the history when they change. Changes are notified and passed to the
+// hooks.
+func (ddns *DDNS) detected(ctx context.Context, public addresses, now time.Time) {
+	events := make([]domain.HistoryEvent, 0, 2)
+	notifications := make([]domain.Notification, 0, 2)
+	changes := make([]domain.AddressChange, 0, 2)
+
+	if public.ipv4 != "" && public.ipv4 != ddns.public.ipv4 {
+		logging.FromContext(ctx).WithField(logging.FieldFamily, logging.FamilyIPv4).Infof("public IPv4 changed from %q to %q", ddns.public.ipv4, public.ipv4)
+		metrics.IPChanges.WithLabelValues(logging.FamilyIPv4).Inc()
+		events = append(events, addressChange("A", ddns.public.ipv4, public.ipv4, now))
+		// the first address detected is not a change to notify or act on
+		if ddns.public.ipv4 != "" {
+			notifications = append(notifications, addressNotification("A", ddns.public.ipv4, public.ipv4, now))
+			changes = append(changes, domain.AddressChange{Time: now, Family: logging.FamilyIPv4, OldIP: ddns.public.ipv4, NewIP: public.ipv4})
+		}
+		ddns.public.ipv4 = public.ipv4
+	}
+
+	if public.ipv6 != "" && public.ipv6 != ddns.public.ipv6 {
+		logging.FromContext(ctx).WithField(logging.FieldFamily, logging.FamilyIPv6).Infof("public IPv6 changed from %q to %q", ddns.public.ipv6, public.ipv6)
+		metrics.IPChanges.WithLabelValues(logging.FamilyIPv6).Inc()
+		events = append(events, addressChange("AAAA", ddns.public.ipv6, public.ipv6, now))
+		if ddns.public.ipv6 != "" {
+			notifications = append(notifications, addressNotification("AAAA", ddns.public.ipv6, public.ipv6, now))
+			changes = append(changes, domain.AddressChange{Time: now, Family: logging.FamilyIPv6, OldIP: ddns.public.ipv6, NewIP: public.ipv6})
+		}
+		ddns.public.ipv6 = public.ipv6
+	}
+
+	if ddns.state != nil {
+		ddns.state.IPv4 = ddns.public.ipv4
+		ddns.state.IPv6 = ddns.public.ipv6
+	}
+
+	ddns.appendHistory(ctx, events...)
+	ddns.notify(notifications...)
+	ddns.changed(changes...)
+}
+
+func (ddns *DDNS) appendHistory(ctx context.Context, events ...domain.HistoryEvent) {
+	if ddns.history == nil || len(events) == 0 {
+		return
+	}
+
+	if err := ddns.history.Append(events...); err != nil {
+		logging.FromContext(ctx).Errorf("failed to append to history: %v", err)
+	}
+}
+
+func addressChange(recordType, old, current string, now time.Time) domain.HistoryEvent {
+	return domain.HistoryEvent{
+		Time:       now,
+		Kind:       domain.EventAddressChange,
+		RecordType: recordType,
+		OldValue:   old,
+		NewValue:   current,
+	}
+}
+
+// updateEvents returns a history event for every record of the results.
+func updateEvents(results []domain.UpdateResult, start time.Time, duration time.Duration) []domain.HistoryEvent {
+	events := make([]domain.HistoryEvent, 0)
+
+	for _, result := range results {
+		for _, record := range result.Updated {
+			events = append(events, updateEvent(result, record, domain.OutcomeSuccess, "", start, duration))
+		}
+
+		errMsg := ""
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+
+		for _, record := range result.Failed {
+			events = append(events, updateEvent(result, record, domain.OutcomeFailed, errMsg, start, duration))
+		}
+	}
+
+	return events
+}
+
+func updateEvent(result domain.UpdateResult, record domain.Record, outcome, errMsg string, start time.Time, duration time.Duration) domain.HistoryEvent {
+	event := domain.HistoryEvent{
+		Time:       start,
+		Kind:       domain.EventUpdate,
+		FQDN:       record.FQDN,
+		RecordType: record.IPType,
+		OldValue:   record.Previous,
+		NewValue:   record.IP,
+		Provider:   result.Provider,
+		Zone:       result.Zone,
+		Outcome:    outcome,
+		Error:      errMsg,
+		Duration:   duration,
+	}
+
+	if outcome == domain.OutcomeSuccess {
+		event.ChangeIDs = result.ChangeIDs
+	}
+
+	return event
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockHistory struct {
+	events []domain.HistoryEvent
+}
+
+func (m *mockHistory) Append(events ...domain.HistoryEvent) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *mockHistory) Query(domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	return m.events, nil
+}
+
+func TestUpdateEvents(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	results := []domain.UpdateResult{
+		{
+			Provider:  "route53",
+			Zone:      "Z123",
+			ChangeIDs: []string{"C1"},
+			Updated:   []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10", Previous: "192.0.2.1"}},
+			Failed:    []domain.Record{{FQDN: "nas.example.net.", IPType: "A", IP: "192.0.2.10"}},
+			Err:       errors.New("throttled"),
+		},
+	}
+
+	got := updateEvents(results, start, time.Second)
+
+	assert.Equal(t, []domain.HistoryEvent{
+		{
+			Time:       start,
+			Kind:       domain.EventUpdate,
+			FQDN:       "vpn.example.net.",
+			RecordType: "A",
+			OldValue:   "192.0.2.1",
+			NewValue:   "192.0.2.10",
+			Provider:   "route53",
+			Zone:       "Z123",
+			ChangeIDs:  []string{"C1"},
+			Outcome:    domain.OutcomeSuccess,
+			Duration:   time.Second,
+		},
+		{
+			Time:       start,
+			Kind:       domain.EventUpdate,
+			FQDN:       "nas.example.net.",
+			RecordType: "A",
+			NewValue:   "192.0.2.10",
+			Provider:   "route53",
+			Zone:       "Z123",
+			Outcome:    domain.OutcomeFailed,
+			Error:      "throttled",
+			Duration:   time.Second,
+		},
+	}, got)
+}
+
+func TestDDNS_detected(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	h := &mockHistory{}
+	ddns := &DDNS{
+		history: h,
+		public:  addresses{ipv4: "192.0.2.1", ipv6: "2001:db8::1"},
+	}
+
+	ddns.detected(context.Background(), addresses{ipv4: "192.0.2.10", ipv6: "2001:db8::1"}, now)
+	ddns.detected(context.Background(), addresses{ipv4: "192.0.2.10"}, now)
+
+	assert.Equal(t, addresses{ipv4: "192.0.2.10", ipv6: "2001:db8::1"}, ddns.public)
+	assert.Equal(t, []domain.HistoryEvent{
+		{Time: now, Kind: domain.EventAddressChange, RecordType: "A", OldValue: "192.0.2.1", NewValue: "192.0.2.10"},
+	}, h.events)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+)
+
+// changed queues address changes, the hooks run when the cycle finishes so
+// the records are already updated.
+func (ddns *DDNS) changed(changes ...domain.AddressChange) {
+	if ddns.hooks == nil || len(changes) == 0 {
+		return
+	}
+
+	ddns.hooksMu.Lock()
+	defer ddns.hooksMu.Unlock()
+
+	for _, change := range changes {
+		change.IPv4, change.IPv6 = ddns.public.ipv4, ddns.public.ipv6
+		ddns.changes = append(ddns.changes, change)
+	}
+}
+
+// runHooks runs the hooks of the queued changes in the background, one
+// change after the other. The changes stay queued while the records could not
+// be updated or the hooks of a previous cycle are still running, and a change
+// whose hooks failed is retried in the next cycle together with the ones
+// queued after it. In dry-run they are only logged.
+func (ddns *DDNS) runHooks(ctx context.Context, err error) {
+	if err != nil {
+		return
+	}
+
+	ddns.hooksMu.Lock()
+	defer ddns.hooksMu.Unlock()
+
+	if len(ddns.changes) == 0 || ddns.hooksRunning {
+		return
+	}
+
+	changes := ddns.changes
+	ddns.changes = nil
+
+	if ddns.dryRun {
+		for _, change := range changes {
+			logging.FromContext(ctx).WithField(logging.FieldFamily, change.Family).Infof("dry run, not running the hooks of %s -> %s", change.OldIP, change.NewIP)
+		}
+		return
+	}
+
+	runner := ddns.hooks
+	if runner == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	ddns.hooksRunning = true
+	ddns.background.Add(1)
+
+	go func() {
+		defer ddns.background.Done()
+
+		for i, change := range changes {
+			if err := runner.Run(ctx, change); err != nil {
+				logging.FromContext(ctx).WithField(logging.FieldFamily, change.Family).Errorf("hooks failed, retrying in the next cycle: %v", err)
+				ddns.hooksFinished(changes[i:])
+				return
+			}
+		}
+
+		ddns.hooksFinished(nil)
+	}()
+}
+
+// hooksFinished queues the changes whose hooks did not run ahead of the ones
+// detected meanwhile.
+func (ddns *DDNS) hooksFinished(retry []domain.AddressChange) {
+	ddns.hooksMu.Lock()
+	defer ddns.hooksMu.Unlock()
+
+	ddns.changes = append(retry, ddns.changes...)
+	ddns.hooksRunning = false
+}
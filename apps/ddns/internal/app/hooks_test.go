@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+type mockHooks struct {
+	mu       sync.Mutex
+	changes  []domain.AddressChange
+	failures int
+}
+
+// Run records the changes, failing the first failures calls.
+func (m *mockHooks) Run(_ context.Context, change domain.AddressChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("hook failed")
+	}
+
+	m.changes = append(m.changes, change)
+	return nil
+}
+
+func TestDDNS_runHooks(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		dryRun          bool
+		expectedChanges []domain.AddressChange
+	}{
+		{
+			name: "changes passed to the hooks",
+			expectedChanges: []domain.AddressChange{
+				{Time: now, Family: "ipv4", OldIP: "192.0.2.1", NewIP: "192.0.2.10", IPv4: "192.0.2.10", IPv6: "2001:db8::2"},
+				{Time: now, Family: "ipv6", OldIP: "2001:db8::1", NewIP: "2001:db8::2", IPv4: "192.0.2.10", IPv6: "2001:db8::2"},
+			},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		dryRun := testCase.dryRun
+		expectedChanges := testCase.expectedChanges
+
+		t.Run(testCase.name, func(t *testing.T) {
+			hooks := &mockHooks{}
+			ddns := &DDNS{hooks: hooks, dryRun: dryRun, public: addresses{ipv4: "192.0.2.1", ipv6: "2001:db8::1"}}
+
+			ddns.detected(context.Background(), addresses{ipv4: "192.0.2.10", ipv6: "2001:db8::2"}, now)
+			ddns.runHooks(context.Background(), nil)
+			ddns.background.Wait()
+
+			assert.Equal(t, expectedChanges, hooks.changes)
+			assert.Empty(t, ddns.changes)
+		})
+	}
+}
+
+func TestDDNS_runHooksRetry(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	first := domain.AddressChange{Time: now, Family: "ipv4", OldIP: "192.0.2.1", NewIP: "192.0.2.10", IPv4: "192.0.2.10"}
+	second := domain.AddressChange{Time: now, Family: "ipv4", OldIP: "192.0.2.10", NewIP: "192.0.2.20", IPv4: "192.0.2.20"}
+
+	testCases := []struct {
+		name            string
+		cycleErr        error
+		failures        int
+		expectedChanges []domain.AddressChange
+		expectedQueued  []domain.AddressChange
+	}{
+		{
+			name:            "all hooks run",
+			expectedChanges: []domain.AddressChange{first, second},
+		},
+		{
+			name:           "kept while the records are not updated",
+			cycleErr:       errors.New("update failed"),
+			expectedQueued: []domain.AddressChange{first, second},
+		},
+		{
+			name:           "failed change retried with the next ones",
+			failures:       1,
+			expectedQueued: []domain.AddressChange{first, second},
+		},
+	}
+
+	for _, testCase := range testCases {
+		cycleErr := testCase.cycleErr
+		failures := testCase.failures
+		expectedChanges := testCase.expectedChanges
+		expectedQueued := testCase.expectedQueued
+
+		t.Run(testCase.name, func(t *testing.T) {
+			hooks := &mockHooks{failures: failures}
+			ddns := &DDNS{hooks: hooks, changes: []domain.AddressChange{first, second}}
+
+			ddns.runHooks(context.Background(), cycleErr)
+			ddns.background.Wait()
+
+			assert.Equal(t, expectedChanges, hooks.changes)
+			assert.Equal(t, expectedQueued, ddns.changes)
+			assert.False(t, ddns.hooksRunning)
+
+			ddns.runHooks(context.Background(), nil)
+			ddns.background.Wait()
+
+			assert.Equal(t, []domain.AddressChange{first, second}, hooks.changes)
+			assert.Empty(t, ddns.changes)
+		})
+	}
+}
+
+func TestDDNS_runHooksOneAtATime(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	first := domain.AddressChange{Time: now, Family: "ipv4", OldIP: "192.0.2.1", NewIP: "192.0.2.10"}
+	second := domain.AddressChange{Time: now, Family: "ipv4", OldIP: "192.0.2.10", NewIP: "192.0.2.20"}
+
+	hooks := &mockHooks{}
+	ddns := &DDNS{hooks: hooks, changes: []domain.AddressChange{first}, hooksRunning: true}
+
+	ddns.runHooks(context.Background(), nil)
+	ddns.background.Wait()
+	assert.Empty(t, hooks.changes, "hooks of a previous cycle still running")
+
+	ddns.hooksFinished(nil)
+	ddns.changes = append(ddns.changes, second)
+	ddns.runHooks(context.Background(), nil)
+	ddns.background.Wait()
+
+	assert.Equal(t, []domain.AddressChange{first, second}, hooks.changes)
+}
+
+func TestDDNS_hooksFirstAddress(t *testing.T) {
+	hooks := &mockHooks{}
+	ddns := &DDNS{hooks: hooks}
+
+	ddns.detected(context.Background(), addresses{ipv4: "192.0.2.10"}, time.Now())
+
+	assert.Empty(t, ddns.changes)
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+)
+
+func cycleOutcome(err error) string {
+	switch {
+	case errors.Is(err, ErrPartialUpdate):
+		return metrics.OutcomePartial
+	case errors.Is(err, ErrShuttingDown):
+		return metrics.OutcomeSkipped
+	case err != nil:
+		return metrics.OutcomeFailed
+	}
+
+	return metrics.OutcomeSuccess
+}
+
+// lookupMetrics counts the lookup of the addresses of a source, a family
+// without address counts as failed.
+func lookupMetrics(source string, found addresses, err error) {
+	result := func(address string) string {
+		if err != nil || address == "" {
+			return metrics.OutcomeFailed
+		}
+		return metrics.OutcomeSuccess
+	}
+
+	metrics.IPLookups.WithLabelValues(source, logging.FamilyIPv4, result(found.ipv4)).Inc()
+	metrics.IPLookups.WithLabelValues(source, logging.FamilyIPv6, result(found.ipv6)).Inc()
+}
+
+// updateMetrics records the duration of an update and the records it
+// published.
+func updateMetrics(results []domain.UpdateResult, duration time.Duration, now time.Time) {
+	metrics.UpdateDuration.Observe(duration.Seconds())
+
+	for _, result := range results {
+		for _, record := range result.Updated {
+			metrics.RecordLastSuccess.WithLabelValues(record.FQDN, record.IPType).Set(float64(now.Unix()))
+		}
+		publishedMetrics(result.Updated)
+	}
+}
+
+// publishedMetrics sets the values published for records.
+func publishedMetrics(records []domain.Record) {
+	for _, record := range records {
+		metrics.Published(record.FQDN, record.IPType, record.IP)
+	}
+}
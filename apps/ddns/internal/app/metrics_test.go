@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+)
+
+func TestCycleOutcome(t *testing.T) {
+	assert.Equal(t, metrics.OutcomeSuccess, cycleOutcome(nil))
+	assert.Equal(t, metrics.OutcomeFailed, cycleOutcome(errors.New("throttled")))
+	assert.Equal(t, metrics.OutcomePartial, cycleOutcome(fmt.Errorf("%w: 1 records failed", ErrPartialUpdate)))
+	assert.Equal(t, metrics.OutcomeSkipped, cycleOutcome(ErrShuttingDown))
+}
+
+func TestUpdateMetrics(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	results := []domain.UpdateResult{
+		{
+			Zone:    "Z123",
+			Updated: []domain.Record{{FQDN: "metrics.example.net.", IPType: "A", IP: "192.0.2.10"}},
+			Failed:  []domain.Record{{FQDN: "failed.example.net.", IPType: "A", IP: "192.0.2.10"}},
+		},
+	}
+
+	updateMetrics(results, time.Second, now)
+
+	assert.InDelta(t, float64(now.Unix()), testutil.ToFloat64(metrics.RecordLastSuccess.WithLabelValues("metrics.example.net.", "A")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.PublishedIP.WithLabelValues("metrics.example.net.", "A", "192.0.2.10")), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(metrics.RecordLastSuccess.WithLabelValues("failed.example.net.", "A")), 0)
+}
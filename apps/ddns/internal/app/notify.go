@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+)
+
+const (
+	defaultFailureThreshold = 3
+	notifyTimeout           = 30 * time.Second
+)
+
+// notify queues notifications, they are sent when the cycle finishes.
+func (ddns *DDNS) notify(notifications ...domain.Notification) {
+	if ddns.notifier == nil {
+		return
+	}
+
+	ddns.pending = append(ddns.pending, notifications...)
+}
+
+// sendNotifications sends the queued notifications in the background, in
+// order, so a slow notifier does not delay the next cycle. Once waits for
+// them before returning.
+func (ddns *DDNS) sendNotifications(ctx context.Context) {
+	if len(ddns.pending) == 0 {
+		return
+	}
+
+	notifications := ddns.pending
+	ddns.pending = nil
+
+	notifier := ddns.notifier
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	ddns.background.Add(1)
+
+	go func() {
+		defer ddns.background.Done()
+		defer cancel()
+
+		for _, notification := range notifications {
+			if err := notifier.Notify(ctx, notification); err != nil {
+				logging.FromContext(ctx).Errorf("failed to send %s notification: %v", notification.Event, err)
+			}
+		}
+	}()
+}
+
+// failed counts the cycles failed in a row. update-failed is sent when they
+// reach the threshold, recovered on the first cycle that succeeds after it.
+func (ddns *DDNS) failed(err error, now time.Time) {
+	if err == nil {
+		if ddns.failures >= ddns.failureThreshold {
+			ddns.notify(domain.Notification{Event: domain.NotifyRecovered, Time: now, Failures: ddns.failures})
+		}
+		ddns.failures = 0
+		return
+	}
+
+	ddns.failures++
+	if ddns.failures == ddns.failureThreshold {
+		ddns.notify(domain.Notification{Event: domain.NotifyUpdateFailed, Time: now, Failures: ddns.failures, Error: err.Error()})
+	}
+}
+
+func addressNotification(recordType, old, current string, now time.Time) domain.Notification {
+	return domain.Notification{
+		Event:      domain.NotifyIPChanged,
+		Time:       now,
+		Family:     logging.Family(recordType),
+		RecordType: recordType,
+		OldValue:   old,
+		NewValue:   current,
+	}
+}
+
+func updateNotifications(results []domain.UpdateResult, now time.Time) []domain.Notification {
+	notifications := make([]domain.Notification, 0)
+	for _, result := range results {
+		for _, record := range result.Updated {
+			notifications = append(notifications, domain.Notification{
+				Event:      domain.NotifyRecordUpdated,
+				Time:       now,
+				Family:     logging.Family(record.IPType),
+				FQDN:       record.FQDN,
+				RecordType: record.IPType,
+				OldValue:   record.Previous,
+				NewValue:   record.IP,
+				Provider:   result.Provider,
+				Zone:       result.Zone,
+			})
+		}
+	}
+
+	return notifications
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNotifier struct {
+	mu            sync.Mutex
+	notifications []domain.Notification
+}
+
+func (m *mockNotifier) Notify(_ context.Context, notification domain.Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notification.Time = time.Time{}
+	m.notifications = append(m.notifications, notification)
+	return nil
+}
+
+func TestDDNS_notifyAddressChange(t *testing.T) {
+	notifier := &mockNotifier{}
+	ddns := &DDNS{notifier: notifier, public: addresses{ipv6: "2001:db8::1"}}
+
+	ddns.detected(context.Background(), addresses{ipv4: "192.0.2.1", ipv6: "2001:db8::2"}, time.Now())
+	ddns.sendNotifications(context.Background())
+	ddns.background.Wait()
+
+	assert.Equal(t, []domain.Notification{
+		{Event: domain.NotifyIPChanged, Family: "ipv6", RecordType: "AAAA", OldValue: "2001:db8::1", NewValue: "2001:db8::2"},
+	}, notifier.notifications)
+}
+
+func TestDDNS_notifyCycles(t *testing.T) {
+	notifier := &mockNotifier{}
+	provider := &mockProvider{
+		records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"}},
+	}
+	ddns := &DDNS{
+		IPGetter:         &mockIPGetter{ipv4: "192.0.2.10"},
+		DDNS:             provider,
+		notifier:         notifier,
+		failureThreshold: 2,
+	}
+
+	provider.updateErr = errors.New("throttled")
+	for range 3 {
+		require.Error(t, ddns.Once(context.Background()))
+	}
+
+	provider.updateErr = nil
+	require.NoError(t, ddns.Once(context.Background()))
+
+	assert.Equal(t, []domain.Notification{
+		{Event: domain.NotifyUpdateFailed, Failures: 2, Error: "throttled"},
+		{
+			Event:      domain.NotifyRecordUpdated,
+			Family:     "ipv4",
+			FQDN:       "vpn.example.net.",
+			RecordType: "A",
+			OldValue:   "192.0.2.1",
+			NewValue:   "192.0.2.10",
+			Zone:       "Z123",
+		},
+		{Event: domain.NotifyRecovered, Failures: 3},
+	}, notifier.notifications)
+}
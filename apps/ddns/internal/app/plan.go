@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+// Plan detects the addresses, reads the records from the providers and
+// returns the changes an update would send, without changing anything.
+func (ddns *DDNS) Plan(ctx context.Context) ([]domain.PlannedChange, error) {
+	ip4, ip6 := ddns.getIPs(ctx)
+
+	records, err := ddns.GetRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	public := addresses{ipv4: value(ip4), ipv6: value(ip6)}
+	records = ddns.checkIPs(records, ddns.sourceAddresses(ctx, records, public))
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	return ddns.PlanRecords(ctx, records)
+}
+
+func logPlan(ctx context.Context, changes []domain.PlannedChange) {
+	for _, change := range changes {
+		logging.FromContext(ctx).WithFields(log.Fields{
+			logging.FieldProvider: change.Provider,
+			logging.FieldZone:     change.Zone,
+			logging.FieldFQDN:     change.FQDN,
+		}).Infof("dry-run: would %s %s %s in %s zone %s: %q -> %s",
+			change.Action, change.Type, change.FQDN, change.Provider, change.Zone, change.Previous, strings.Join(change.Values, " "))
+
+		if change.HealthCheck != "" {
+			logging.FromContext(ctx).WithFields(log.Fields{
+				logging.FieldProvider: change.Provider,
+				logging.FieldFQDN:     change.FQDN,
+			}).Infof("dry-run: would %s health check %q of %s %s", change.HealthCheck, change.HealthCheckID, change.Type, change.FQDN)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+const txtUpdatedField = "updated="
+
+// desiredValue returns the value the record should hold for the detected
+// addresses, false when it cannot be built from them.
+func desiredValue(record domain.Record, ipv4, ipv6 string, now time.Time) (string, bool) {
+	switch record.IPType {
+	case "A":
+		return ipv4, ipv4 != ""
+	case "AAAA":
+		return ipv6, ipv6 != ""
+	}
+
+	if record.Spec == nil {
+		return "", false
+	}
+
+	switch record.IPType {
+	case "TXT":
+		content := txtAddresses(ipv4, ipv6)
+		if content == "" {
+			return "", false
+		}
+		return strconv.Quote(content + " " + txtUpdatedField + now.UTC().Format(time.RFC3339)), true
+	case "CNAME":
+		return record.Spec.Target, record.Spec.Target != ""
+	case "SRV":
+		return fmt.Sprintf("%d %d %d %s", record.Spec.Priority, record.Spec.Weight, record.Spec.Port, record.Spec.Target), true
+	case "HTTPS", "SVCB":
+		return serviceBinding(*record.Spec, ipv4, ipv6)
+	}
+
+	return "", false
+}
+
+// upToDate reports whether the current value of the record already reflects
+// desired. The update time held by TXT records is not compared, they only
+// change when the addresses do.
+func upToDate(record domain.Record, desired string) bool {
+	switch record.IPType {
+	case "TXT":
+		current, desired := strings.Trim(record.IP, `"`), strings.Trim(desired, `"`)
+		addresses, _, _ := strings.Cut(desired, " "+txtUpdatedField)
+		return current == addresses || strings.HasPrefix(current, addresses+" "+txtUpdatedField)
+	case "CNAME":
+		return strings.EqualFold(strings.TrimSuffix(record.IP, "."), strings.TrimSuffix(desired, "."))
+	}
+
+	return record.IP == desired
+}
+
+func txtAddresses(ipv4, ipv6 string) string {
+	fields := make([]string, 0, 2)
+	if ipv4 != "" {
+		fields = append(fields, "ipv4="+ipv4)
+	}
+
+	if ipv6 != "" {
+		fields = append(fields, "ipv6="+ipv6)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// serviceBinding builds an HTTPS/SVCB record in service mode carrying the
+// addresses as ipv4hint and ipv6hint.
+func serviceBinding(spec domain.RecordSpec, ipv4, ipv6 string) (string, bool) {
+	if ipv4 == "" && ipv6 == "" {
+		return "", false
+	}
+
+	priority, target := spec.Priority, spec.Target
+	if priority == 0 {
+		priority = 1
+	}
+
+	if target == "" {
+		target = "."
+	}
+
+	fields := []string{strconv.Itoa(priority), target}
+	if spec.Params != "" {
+		fields = append(fields, spec.Params)
+	}
+
+	if ipv4 != "" {
+		fields = append(fields, "ipv4hint="+ipv4)
+	}
+
+	if ipv6 != "" {
+		fields = append(fields, "ipv6hint="+ipv6)
+	}
+
+	return strings.Join(fields, " "), true
+}
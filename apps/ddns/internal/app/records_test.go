@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIPs(t *testing.T) {
+	ipv4 := "192.0.2.10"
+	ipv6 := "2001:db8::10"
+	empty := ""
+
+	testCases := []struct {
+		name            string
+		records         []domain.Record
+		ipv4            *string
+		ipv6            *string
+		expectedRecords []domain.Record
+	}{
+		{
+			name: "address records",
+			records: []domain.Record{
+				{FQDN: "a.example.net.", IPType: "A", IP: "192.0.2.1"},
+				{FQDN: "b.example.net.", IPType: "A", IP: ipv4},
+				{FQDN: "a.example.net.", IPType: "AAAA", IP: "2001:db8::1"},
+			},
+			ipv4: &ipv4,
+			ipv6: &ipv6,
+			expectedRecords: []domain.Record{
+				{FQDN: "a.example.net.", IPType: "A", IP: ipv4, Previous: "192.0.2.1"},
+				{FQDN: "a.example.net.", IPType: "AAAA", IP: ipv6, Previous: "2001:db8::1"},
+			},
+		},
+		{
+			name: "missing addresses are not published",
+			records: []domain.Record{
+				{FQDN: "a.example.net.", IPType: "A", IP: "192.0.2.1"},
+				{FQDN: "a.example.net.", IPType: "AAAA", IP: "2001:db8::1"},
+				{FQDN: "a.example.net.", IPType: "HTTPS", IP: "1 . ipv4hint=192.0.2.1", Spec: &domain.RecordSpec{}},
+			},
+			ipv4:            &empty,
+			ipv6:            nil,
+			expectedRecords: []domain.Record{},
+		},
+		{
+			name: "txt record ignores its update time",
+			records: []domain.Record{
+				{
+					FQDN:   "a.example.net.",
+					IPType: "TXT",
+					IP:     `"ipv4=192.0.2.10 ipv6=2001:db8::10 updated=2026-01-01T00:00:00Z"`,
+					Spec:   &domain.RecordSpec{},
+				},
+			},
+			ipv4:            &ipv4,
+			ipv6:            &ipv6,
+			expectedRecords: []domain.Record{},
+		},
+		{
+			name: "cname follows its target",
+			records: []domain.Record{
+				{FQDN: "a.example.net.", IPType: "CNAME", IP: "VPN.example.net", Spec: &domain.RecordSpec{Target: "vpn.example.net."}},
+				{FQDN: "b.example.net.", IPType: "CNAME", IP: "old.example.net", Spec: &domain.RecordSpec{Target: "vpn.example.net."}},
+			},
+			ipv4: &ipv4,
+			expectedRecords: []domain.Record{
+				{
+					FQDN:     "b.example.net.",
+					IPType:   "CNAME",
+					IP:       "vpn.example.net.",
+					Spec:     &domain.RecordSpec{Target: "vpn.example.net."},
+					Previous: "old.example.net",
+				},
+			},
+		},
+		{
+			name: "srv record",
+			records: []domain.Record{
+				{
+					FQDN:   "_openvpn._udp.example.net.",
+					IPType: "SRV",
+					IP:     "10 5 1194 vpn.example.net.",
+					Spec:   &domain.RecordSpec{Target: "vpn.example.net.", Port: 1195, Priority: 10, Weight: 5},
+				},
+			},
+			ipv4: &ipv4,
+			expectedRecords: []domain.Record{
+				{
+					FQDN:     "_openvpn._udp.example.net.",
+					IPType:   "SRV",
+					IP:       "10 5 1195 vpn.example.net.",
+					Spec:     &domain.RecordSpec{Target: "vpn.example.net.", Port: 1195, Priority: 10, Weight: 5},
+					Previous: "10 5 1194 vpn.example.net.",
+				},
+			},
+		},
+		{
+			name: "https and svcb records carry address hints",
+			records: []domain.Record{
+				{FQDN: "a.example.net.", IPType: "HTTPS", IP: `1 . alpn="h2" ipv4hint=192.0.2.1`, Spec: &domain.RecordSpec{Params: `alpn="h2"`}},
+				{FQDN: "_svc.example.net.", IPType: "SVCB", IP: "", Spec: &domain.RecordSpec{Priority: 2, Target: "svc.example.net."}},
+			},
+			ipv4: &ipv4,
+			ipv6: &ipv6,
+			expectedRecords: []domain.Record{
+				{
+					FQDN:     "a.example.net.",
+					IPType:   "HTTPS",
+					IP:       `1 . alpn="h2" ipv4hint=192.0.2.10 ipv6hint=2001:db8::10`,
+					Spec:     &domain.RecordSpec{Params: `alpn="h2"`},
+					Previous: `1 . alpn="h2" ipv4hint=192.0.2.1`,
+				},
+				{
+					FQDN:   "_svc.example.net.",
+					IPType: "SVCB",
+					IP:     "2 svc.example.net. ipv4hint=192.0.2.10 ipv6hint=2001:db8::10",
+					Spec:   &domain.RecordSpec{Priority: 2, Target: "svc.example.net."},
+				},
+			},
+		},
+		{
+			name: "split horizon views use their own source",
+			records: []domain.Record{
+				{FQDN: "nas.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "ZPUBLIC"},
+				{
+					FQDN:   "nas.example.net.",
+					IPType: "A",
+					IP:     "192.168.1.20",
+					Zone:   "ZPRIVATE",
+					Source: &domain.IPSource{Type: "static", IPv4: "192.168.1.10"},
+				},
+			},
+			ipv4: &ipv4,
+			expectedRecords: []domain.Record{
+				{FQDN: "nas.example.net.", IPType: "A", IP: ipv4, Previous: "192.0.2.1", Zone: "ZPUBLIC"},
+				{
+					FQDN:     "nas.example.net.",
+					IPType:   "A",
+					IP:       "192.168.1.10",
+					Previous: "192.168.1.20",
+					Zone:     "ZPRIVATE",
+					Source:   &domain.IPSource{Type: "static", IPv4: "192.168.1.10"},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		records := testCase.records
+		ipv4 := testCase.ipv4
+		ipv6 := testCase.ipv6
+		expectedRecords := testCase.expectedRecords
+
+		t.Run(testCase.name, func(t *testing.T) {
+			ddns := &DDNS{}
+
+			public := addresses{ipv4: value(ipv4), ipv6: value(ipv6)}
+			got := ddns.checkIPs(records, ddns.sourceAddresses(context.Background(), records, public))
+
+			assert.Equal(t, expectedRecords, got)
+		})
+	}
+}
+
+func TestDesiredValue_TXT(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	record := domain.Record{FQDN: "a.example.net.", IPType: "TXT", Spec: &domain.RecordSpec{}}
+
+	got, ok := desiredValue(record, "192.0.2.10", "", now)
+
+	assert.True(t, ok)
+	assert.Equal(t, `"ipv4=192.0.2.10 updated=2026-10-19T12:00:00Z"`, got)
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/hooks"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/notify"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/schedule"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/updater"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+// restartSections are the sections of the config only read when ddns
+// starts.
+var restartSections = map[string]bool{
+	"state-dir": true,
+	"history":   true,
+	"http":      true,
+	"tracing":   true,
+	"mqtt":      true,
+	"control":   true,
+}
+
+// settings is the part of the config that can change while ddns runs.
+type settings struct {
+	elapseTimeToCheck time.Duration
+	updateTimeout     time.Duration
+	reconcileEvery    time.Duration
+	shutdownGrace     time.Duration
+	retryInitial      time.Duration
+	jitterPercent     int
+	recordSchedules   map[string][]string
+	schedules         map[string]schedule.Schedule
+	healthIntervals   int
+	failureThreshold  int
+	dryRun            bool
+	sources           []domain.IPSource
+	configHash        string
+	updater           domain.DDNS
+	notifier          domain.Notifier
+	hooks             domain.Hooks
+}
+
+// newSettings builds the settings of awsConfig. The notifier and hooks of
+// running are kept instead of built again, so a reload that did not change
+// them keeps their rate limits and retries.
+func newSettings(awsConfig *config.SimpleDDNS, running settings) (settings, error) {
+	elapseTimeToCheck := time.Duration(awsConfig.DDNS.CheckEverySeconds) * time.Second
+	updateTimeout := time.Duration(awsConfig.DDNS.UpdateTimeoutSeconds) * time.Second
+
+	if elapseTimeToCheck <= 0 {
+		return settings{}, ErrCheckInSecondsMustBeGreaterThanZero
+	}
+
+	if elapseTimeToCheck < updateTimeout {
+		return settings{}, ErrCheckInSecondsMustBeLessThanUpdateTimeoutSeconds
+	}
+
+	s := settings{
+		elapseTimeToCheck: elapseTimeToCheck,
+		updateTimeout:     updateTimeout,
+		reconcileEvery:    time.Duration(awsConfig.DDNS.ReconcileEverySeconds) * time.Second,
+		shutdownGrace:     time.Duration(awsConfig.DDNS.ShutdownGraceSeconds) * time.Second,
+		retryInitial:      time.Duration(awsConfig.DDNS.RetryInitialSeconds) * time.Second,
+		jitterPercent:     awsConfig.DDNS.JitterPercent,
+		healthIntervals:   awsConfig.DDNS.HTTP.HealthIntervals,
+		failureThreshold:  awsConfig.DDNS.Notifications.FailureThreshold,
+		dryRun:            awsConfig.DDNS.DryRun,
+		sources:           configSources(awsConfig),
+		configHash:        configHash(awsConfig),
+	}
+
+	// without state-dir nor reconcile-every-seconds the records are read
+	// every cycle, so records changed by hand are fixed by the next one
+	if s.reconcileEvery <= 0 && awsConfig.DDNS.StateDir != "" {
+		s.reconcileEvery = defaultReconcileInterval
+	}
+
+	if s.shutdownGrace <= 0 {
+		s.shutdownGrace = updateTimeout
+	}
+
+	if s.retryInitial <= 0 {
+		s.retryInitial = defaultRetryInitial
+	}
+
+	if s.healthIntervals <= 0 {
+		s.healthIntervals = defaultHealthIntervals
+	}
+
+	if s.failureThreshold <= 0 {
+		s.failureThreshold = defaultFailureThreshold
+	}
+
+	recordSchedules, schedules, err := configSchedules(awsConfig)
+	if err != nil {
+		return settings{}, err
+	}
+	s.recordSchedules, s.schedules = recordSchedules, schedules
+
+	ddnsUpdater, err := updater.NewUpdater(awsConfig)
+	if err != nil {
+		return settings{}, err
+	}
+	s.updater = ddnsUpdater
+
+	s.notifier, s.hooks = running.notifier, running.hooks
+
+	if s.notifier == nil && len(awsConfig.DDNS.Notifications.Notifiers) > 0 {
+		dispatcher, err := notify.NewDispatcher(awsConfig.DDNS.Notifications)
+		if err != nil {
+			return settings{}, err
+		}
+
+		s.notifier = dispatcher
+	}
+
+	if s.hooks == nil && len(awsConfig.DDNS.Hooks) > 0 {
+		runner, err := hooks.New(context.Background(), awsConfig.DDNS.Hooks, awsConfig.DDNS.AWS)
+		if err != nil {
+			return settings{}, err
+		}
+
+		s.hooks = runner
+	}
+
+	return s, nil
+}
+
+// Validate reports why NewDDNS or Reload would reject awsConfig. It builds
+// the same intervals, schedules, providers, notifiers and hooks, without
+// calling any of them.
+func Validate(awsConfig *config.SimpleDDNS) error {
+	_, err := newSettings(awsConfig, settings{})
+	return err
+}
+
+// apply replaces the settings, the caller keeps cycles from running.
+func (ddns *DDNS) apply(s settings) {
+	ddns.settingsMu.Lock()
+	ddns.elapseTimeToCheck = s.elapseTimeToCheck
+	ddns.updateTimeout = s.updateTimeout
+	ddns.healthIntervals = s.healthIntervals
+	ddns.shutdownGrace = s.shutdownGrace
+	ddns.retryInitial = s.retryInitial
+	ddns.jitterPercent = s.jitterPercent
+	ddns.recordSchedules = s.recordSchedules
+	ddns.schedules = s.schedules
+	ddns.settingsMu.Unlock()
+
+	ddns.reconcileEvery = s.reconcileEvery
+	ddns.failureThreshold = s.failureThreshold
+	ddns.dryRun = s.dryRun
+	ddns.sources = s.sources
+	ddns.DDNS = s.updater
+	ddns.notifier = s.notifier
+	ddns.hooks = s.hooks
+
+	if ddns.configHash != s.configHash {
+		ddns.configHash = s.configHash
+		ddns.records = nil
+		if ddns.state != nil {
+			ddns.state.Records = nil
+			ddns.state.ReconciledAt = time.Time{}
+			ddns.state.ConfigHash = s.configHash
+		}
+	}
+}
+
+// Reload replaces the records, providers, intervals, notifiers and hooks
+// with the ones of cfg once the running cycle finishes, then starts a cycle
+// with them. The running config is kept when cfg cannot be applied. It
+// returns the sections of the config that changed.
+func (ddns *DDNS) Reload(cfg *config.SimpleDDNS) ([]string, error) {
+	ddns.mu.Lock()
+	running := ddns.config
+	kept := settings{notifier: ddns.notifier, hooks: ddns.hooks}
+	ddns.mu.Unlock()
+
+	changed := changedSections(running, cfg)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	if slices.Contains(changed, "notifications") {
+		kept.notifier = nil
+	}
+
+	if slices.Contains(changed, "hooks") {
+		kept.hooks = nil
+	}
+
+	s, err := newSettings(cfg, kept)
+	if err != nil {
+		return nil, err
+	}
+
+	ddns.mu.Lock()
+	ddns.apply(s)
+	ddns.config = cfg
+	ddns.mu.Unlock()
+
+	for _, section := range changed {
+		if restartSections[section] {
+			log.Warnf("%s changed, it is applied on the next restart", section)
+		}
+	}
+
+	ddns.Force()
+	return changed, nil
+}
+
+// changedSections returns the top level keys of the ddns config that differ.
+func changedSections(running, cfg *config.SimpleDDNS) []string {
+	var before config.DDNSConfig
+	if running != nil {
+		before = running.DDNS
+	}
+
+	beforeValue, afterValue := reflect.ValueOf(before), reflect.ValueOf(cfg.DDNS)
+	changed := make([]string, 0)
+
+	for i := range beforeValue.NumField() {
+		if !reflect.DeepEqual(beforeValue.Field(i).Interface(), afterValue.Field(i).Interface()) {
+			changed = append(changed, beforeValue.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+func reloadConfig(checkEverySeconds int, fqdn string) *config.SimpleDDNS {
+	return &config.SimpleDDNS{DDNS: config.DDNSConfig{
+		CheckEverySeconds:    checkEverySeconds,
+		UpdateTimeoutSeconds: 10,
+		AWS: []config.AWSConfig{{
+			AccountName: "home",
+			Region:      "us-east-1",
+			AccessKey:   "AKIAEXAMPLE",
+			SecretKey:   "secret",
+			Zones: []config.ZoneConfig{{
+				ID:      "Z123",
+				Records: []config.RecordConfig{{FQDN: fqdn, RecordType: "A", RecordTTL: 300}},
+			}},
+		}},
+	}}
+}
+
+func TestDDNS_Reload(t *testing.T) {
+	testCases := []struct {
+		name              string
+		cfg               *config.SimpleDDNS
+		expectedChanged   []string
+		expectedErr       error
+		expectedInterval  time.Duration
+		expectedRecords   bool
+		expectedForced    bool
+		expectedSameState bool
+	}{
+		{
+			name:              "nothing changed",
+			cfg:               reloadConfig(60, "vpn.example.net."),
+			expectedInterval:  time.Minute,
+			expectedRecords:   true,
+			expectedSameState: true,
+		},
+		{
+			name:              "interval changed",
+			cfg:               reloadConfig(120, "vpn.example.net."),
+			expectedChanged:   []string{"check-every-seconds"},
+			expectedInterval:  2 * time.Minute,
+			expectedRecords:   true,
+			expectedForced:    true,
+			expectedSameState: true,
+		},
+		{
+			name:             "records changed",
+			cfg:              reloadConfig(60, "nas.example.net."),
+			expectedChanged:  []string{"aws"},
+			expectedInterval: time.Minute,
+			expectedForced:   true,
+		},
+		{
+			name:              "invalid config",
+			cfg:               reloadConfig(5, "nas.example.net."),
+			expectedErr:       ErrCheckInSecondsMustBeLessThanUpdateTimeoutSeconds,
+			expectedInterval:  time.Minute,
+			expectedRecords:   true,
+			expectedSameState: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		cfg := testCase.cfg
+		expectedChanged := testCase.expectedChanged
+		expectedErr := testCase.expectedErr
+		expectedInterval := testCase.expectedInterval
+		expectedRecords := testCase.expectedRecords
+		expectedForced := testCase.expectedForced
+		expectedSameState := testCase.expectedSameState
+
+		t.Run(testCase.name, func(t *testing.T) {
+			running := reloadConfig(60, "vpn.example.net.")
+			ddns, err := NewDDNS(running)
+			require.NoError(t, err)
+
+			published := []domain.PublishedRecord{{Record: domain.Record{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10"}}}
+			ddns.records = map[string]domain.RecordStatus{"vpn.example.net./A": {FQDN: "vpn.example.net.", RecordType: "A", Status: domain.RecordOK}}
+			ddns.state = &domain.State{ConfigHash: ddns.configHash, Records: published, ReconciledAt: time.Now()}
+			updater := ddns.DDNS
+
+			changed, err := ddns.Reload(cfg)
+
+			assert.ErrorIs(t, err, expectedErr)
+			assert.Equal(t, expectedChanged, changed)
+			assert.Equal(t, expectedInterval, ddns.interval())
+			assert.Equal(t, expectedRecords, ddns.records != nil)
+			assert.Equal(t, expectedForced, len(ddns.force) == 1)
+			assert.Equal(t, expectedSameState, ddns.state.Records != nil)
+			assert.Equal(t, configHash(ddns.config), ddns.state.ConfigHash)
+
+			if expectedErr != nil {
+				assert.Same(t, running, ddns.config)
+				assert.Equal(t, updater, ddns.DDNS)
+			}
+		})
+	}
+}
+
+func TestDDNS_ReloadKeepsNotifierAndHooks(t *testing.T) {
+	withNotifierAndHooks := func(checkEverySeconds int, topic string) *config.SimpleDDNS {
+		cfg := reloadConfig(checkEverySeconds, "vpn.example.net.")
+		cfg.DDNS.Notifications.Notifiers = []config.NotifierConfig{{Type: "ntfy", URL: "https://ntfy.sh", Topic: topic}}
+		cfg.DDNS.Hooks = []config.HookConfig{{Name: "wireguard", Type: "command", Command: []string{"true"}}}
+		return cfg
+	}
+
+	ddns, err := NewDDNS(withNotifierAndHooks(60, "ddns"))
+	require.NoError(t, err)
+	notifier, hooks := ddns.notifier, ddns.hooks
+
+	_, err = ddns.Reload(withNotifierAndHooks(120, "ddns"))
+	require.NoError(t, err)
+	assert.Same(t, notifier, ddns.notifier)
+	assert.Same(t, hooks, ddns.hooks)
+
+	_, err = ddns.Reload(withNotifierAndHooks(120, "home"))
+	require.NoError(t, err)
+	assert.NotSame(t, notifier, ddns.notifier)
+	assert.Same(t, hooks, ddns.hooks)
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		configure     func(cfg *config.SimpleDDNS)
+		expectedError string
+	}{
+		{
+			name:      "valid",
+			configure: func(*config.SimpleDDNS) {},
+		},
+		{
+			name:          "timeout longer than the interval",
+			configure:     func(cfg *config.SimpleDDNS) { cfg.DDNS.CheckEverySeconds = 5 },
+			expectedError: ErrCheckInSecondsMustBeLessThanUpdateTimeoutSeconds.Error(),
+		},
+		{
+			name: "invalid schedule",
+			configure: func(cfg *config.SimpleDDNS) {
+				cfg.DDNS.AWS[0].Zones[0].Records[0].Schedule = "61 * * * *"
+			},
+			expectedError: "invalid minute",
+		},
+		{
+			name: "invalid notifier template",
+			configure: func(cfg *config.SimpleDDNS) {
+				cfg.DDNS.Notifications.Notifiers = []config.NotifierConfig{
+					{Type: "webhook", URL: "https://example.net/hooks", Templates: map[string]string{"recovered": "{{.FQDN"}},
+				}
+			},
+			expectedError: "invalid recovered template",
+		},
+	}
+
+	for _, testCase := range testCases {
+		cfg := reloadConfig(60, "vpn.example.net.")
+		testCase.configure(cfg)
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Validate(cfg)
+			if expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, expectedError)
+		})
+	}
+}
+
+func TestChangedSections(t *testing.T) {
+	running := &config.SimpleDDNS{DDNS: config.DDNSConfig{LogLevel: "info", CheckEverySeconds: 60}}
+	cfg := &config.SimpleDDNS{DDNS: config.DDNSConfig{
+		LogLevel:          "debug",
+		CheckEverySeconds: 60,
+		MQTT:              config.MQTTConfig{Broker: "tcp://broker:1883"},
+	}}
+
+	assert.Equal(t, []string{"log-level", "mqtt"}, changedSections(running, cfg))
+	assert.Empty(t, changedSections(running, running))
+	assert.Equal(t, []string{"check-every-seconds"}, changedSections(nil, &config.SimpleDDNS{DDNS: config.DDNSConfig{CheckEverySeconds: 60}}))
+}
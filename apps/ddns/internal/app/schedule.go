@@ -0,0 +1,284 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/schedule"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const defaultRetryInitial = 30 * time.Second
+
+// nextCheck is how long to wait for the next check after failures cycles
+// failed in a row: the check interval, or after a failure a delay doubling
+// from the initial retry up to it. The wait is moved by up to the jitter
+// percent so instances started together do not stay in lockstep.
+func (ddns *DDNS) nextCheck(failures int) time.Duration {
+	ddns.settingsMu.RLock()
+	interval, retry, jitter := ddns.elapseTimeToCheck, ddns.retryInitial, ddns.jitterPercent
+	ddns.settingsMu.RUnlock()
+
+	wait := interval
+	if failures > 0 && retry > 0 {
+		wait = retry
+		for i := 1; i < failures && wait < interval; i++ {
+			wait *= 2
+		}
+		wait = min(wait, interval)
+	}
+
+	if jitter > 0 {
+		spread := float64(wait) * float64(jitter) / 100
+		wait += time.Duration((rand.Float64()*2 - 1) * spread) //nolint:gosec // jitter needs no secure randomness
+	}
+
+	return wait
+}
+
+// defaultSchedule is the schedule of the records without one, checked every
+// check interval.
+const defaultSchedule = ""
+
+// configSchedules returns the schedules of the configured records by FQDN
+// and record type, a record without schedule taking the one of its zone or
+// account, and the parsed schedules.
+func configSchedules(awsConfig *config.SimpleDDNS) (map[string][]string, map[string]schedule.Schedule, error) {
+	records := map[string][]string{}
+	schedules := map[string]schedule.Schedule{}
+
+	add := func(record config.RecordConfig, inherited ...string) error {
+		spec := firstSchedule(append([]string{record.Schedule}, inherited...)...)
+		if spec != defaultSchedule {
+			if _, ok := schedules[spec]; !ok {
+				parsed, err := schedule.Parse(spec)
+				if err != nil {
+					return fmt.Errorf("record %s: %w", record.FQDN, err)
+				}
+				schedules[spec] = parsed
+			}
+		}
+
+		key := scheduleKey(record.FQDN, record.RecordType)
+		if !slices.Contains(records[key], spec) {
+			records[key] = append(records[key], spec)
+		}
+		return nil
+	}
+
+	for _, account := range awsConfig.DDNS.AWS {
+		for _, zone := range account.Zones {
+			for _, record := range zone.Records {
+				if err := add(record, zone.Schedule, account.Schedule); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+
+		for _, record := range account.Records {
+			if err := add(record, account.Schedule); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return records, schedules, nil
+}
+
+func firstSchedule(specs ...string) string {
+	for _, spec := range specs {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			return spec
+		}
+	}
+
+	return defaultSchedule
+}
+
+func scheduleKey(fqdn, recordType string) string {
+	return fqdn + "|" + recordType
+}
+
+// scheduleSpecs returns the schedules in use. The default one is only in use
+// when a record has no schedule, or when no record is configured, so records
+// with slower schedules are not checked every check interval.
+func (ddns *DDNS) scheduleSpecs() map[string]schedule.Schedule {
+	ddns.settingsMu.RLock()
+	defer ddns.settingsMu.RUnlock()
+
+	specs := make(map[string]schedule.Schedule, len(ddns.schedules)+1)
+	if ddns.defaultInUse() {
+		specs[defaultSchedule] = nil
+	}
+	maps.Copy(specs, ddns.schedules)
+
+	return specs
+}
+
+// defaultInUse reports whether a record follows the default schedule, with
+// settingsMu held.
+func (ddns *DDNS) defaultInUse() bool {
+	if len(ddns.recordSchedules) == 0 {
+		return true
+	}
+
+	for _, specs := range ddns.recordSchedules {
+		if slices.Contains(specs, defaultSchedule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dueRecords keeps the records with one of the due schedules, all of them
+// when due is nil. Records not configured, such as PTR records, follow the
+// default schedule, or any schedule when the default one is not in use.
+func (ddns *DDNS) dueRecords(records []domain.Record, due []string) []domain.Record {
+	if due == nil {
+		return records
+	}
+
+	ddns.settingsMu.RLock()
+	defer ddns.settingsMu.RUnlock()
+
+	unconfigured := []string{defaultSchedule}
+	if !ddns.defaultInUse() {
+		unconfigured = due
+	}
+
+	kept := make([]domain.Record, 0, len(records))
+	for _, record := range records {
+		specs, ok := ddns.recordSchedules[scheduleKey(record.FQDN, record.IPType)]
+		if !ok {
+			specs = unconfigured
+		}
+
+		if slices.ContainsFunc(specs, func(spec string) bool { return slices.Contains(due, spec) }) {
+			kept = append(kept, record)
+		}
+	}
+
+	return kept
+}
+
+// scheduler keeps when every schedule is due next. The schedules of a
+// running cycle are pending until it finishes, then the next check is
+// planned from its outcome.
+type scheduler struct {
+	ddns     *DDNS
+	next     map[string]time.Time
+	pending  map[string]bool
+	failures int
+}
+
+func newScheduler(ddns *DDNS) *scheduler {
+	return &scheduler{ddns: ddns, next: map[string]time.Time{}, pending: map[string]bool{}}
+}
+
+// sync follows the schedules of a reloaded config.
+func (s *scheduler) sync(now time.Time) map[string]schedule.Schedule {
+	specs := s.ddns.scheduleSpecs()
+
+	for spec := range s.next {
+		if _, ok := specs[spec]; !ok {
+			delete(s.next, spec)
+			delete(s.pending, spec)
+		}
+	}
+
+	for spec, parsed := range specs {
+		if _, ok := s.next[spec]; !ok {
+			s.next[spec] = s.nextDue(spec, parsed, now)
+		}
+	}
+
+	return specs
+}
+
+// all marks every schedule pending, for a cycle checking all the records.
+func (s *scheduler) all(now time.Time) []string {
+	s.sync(now)
+
+	due := make([]string, 0, len(s.next))
+	for spec := range s.next {
+		s.pending[spec] = true
+		due = append(due, spec)
+	}
+
+	return due
+}
+
+// due marks pending the schedules due at now.
+func (s *scheduler) due(now time.Time) []string {
+	s.sync(now)
+
+	due := make([]string, 0)
+	for spec, next := range s.next {
+		if !s.pending[spec] && !next.IsZero() && !next.After(now) {
+			s.pending[spec] = true
+			due = append(due, spec)
+		}
+	}
+
+	return due
+}
+
+// finished plans the next check of the schedules of a cycle.
+func (s *scheduler) finished(due []string, err error, now time.Time) {
+	if err == nil {
+		s.failures = 0
+	} else {
+		s.failures++
+	}
+
+	specs := s.sync(now)
+	for _, spec := range due {
+		if parsed, ok := specs[spec]; ok {
+			delete(s.pending, spec)
+			s.next[spec] = s.nextDue(spec, parsed, now)
+		}
+	}
+}
+
+// wait is how long until the next schedule is due, false when none is.
+func (s *scheduler) wait(now time.Time) (time.Duration, bool) {
+	var earliest time.Time
+	for spec, next := range s.next {
+		if s.pending[spec] || next.IsZero() {
+			continue
+		}
+
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+
+	if earliest.IsZero() {
+		return 0, false
+	}
+
+	return max(earliest.Sub(now), 0), true
+}
+
+// nextDue is when a schedule is due after a check at now. After a failure
+// it is retried with the backoff of nextCheck when that comes first.
+func (s *scheduler) nextDue(spec string, parsed schedule.Schedule, now time.Time) time.Time {
+	if spec == defaultSchedule || parsed == nil {
+		return now.Add(s.ddns.nextCheck(s.failures))
+	}
+
+	next := parsed.Next(now)
+	if s.failures > 0 {
+		if retry := now.Add(s.ddns.nextCheck(s.failures)); next.IsZero() || retry.Before(next) {
+			next = retry
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,319 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/schedule"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+func TestDDNS_nextCheck(t *testing.T) {
+	testCases := []struct {
+		name         string
+		retryInitial time.Duration
+		failures     int
+		expectedWait time.Duration
+	}{
+		{
+			name:         "success",
+			retryInitial: 30 * time.Second,
+			expectedWait: 5 * time.Minute,
+		},
+		{
+			name:         "first failure",
+			retryInitial: 30 * time.Second,
+			failures:     1,
+			expectedWait: 30 * time.Second,
+		},
+		{
+			name:         "third failure",
+			retryInitial: 30 * time.Second,
+			failures:     3,
+			expectedWait: 2 * time.Minute,
+		},
+		{
+			name:         "capped at the interval",
+			retryInitial: 30 * time.Second,
+			failures:     40,
+			expectedWait: 5 * time.Minute,
+		},
+		{
+			name:         "without backoff",
+			failures:     2,
+			expectedWait: 5 * time.Minute,
+		},
+	}
+
+	for _, testCase := range testCases {
+		retryInitial := testCase.retryInitial
+		failures := testCase.failures
+		expectedWait := testCase.expectedWait
+
+		t.Run(testCase.name, func(t *testing.T) {
+			ddns := &DDNS{elapseTimeToCheck: 5 * time.Minute, retryInitial: retryInitial}
+
+			assert.Equal(t, expectedWait, ddns.nextCheck(failures))
+		})
+	}
+}
+
+func TestDDNS_nextCheckJitter(t *testing.T) {
+	ddns := &DDNS{elapseTimeToCheck: 100 * time.Second, retryInitial: 10 * time.Second, jitterPercent: 10}
+
+	waits := map[time.Duration]bool{}
+	for range 50 {
+		wait := ddns.nextCheck(0)
+		assert.GreaterOrEqual(t, wait, 90*time.Second)
+		assert.LessOrEqual(t, wait, 110*time.Second)
+		waits[wait] = true
+
+		retry := ddns.nextCheck(1)
+		assert.GreaterOrEqual(t, retry, 9*time.Second)
+		assert.LessOrEqual(t, retry, 11*time.Second)
+	}
+
+	assert.Greater(t, len(waits), 1)
+}
+
+func TestDDNS_RunRetry(t *testing.T) {
+	provider := &mockProvider{
+		records:   []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"}},
+		updateErr: errors.New("throttled"),
+	}
+	ipGetter := &countingIPGetter{mockIPGetter: mockIPGetter{ipv4: "192.0.2.10"}}
+	ddns := &DDNS{
+		elapseTimeToCheck: time.Hour,
+		retryInitial:      10 * time.Millisecond,
+		IPGetter:          ipGetter,
+		DDNS:              provider,
+		force:             make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ddns.Run(ctx)
+		close(done)
+	}()
+
+	// failed cycles are retried long before the interval
+	assert.Eventually(t, func() bool { return ipGetter.lookups.Load() >= 3 }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestDDNS_RunWithoutDefaultSchedule(t *testing.T) {
+	records, schedules, err := configSchedules(&config.SimpleDDNS{DDNS: config.DDNSConfig{AWS: []config.AWSConfig{
+		{
+			AccountName: "home",
+			Schedule:    "1h",
+			Records:     []config.RecordConfig{{FQDN: "vpn.example.net.", RecordType: "A"}},
+			Zones: []config.ZoneConfig{
+				{ID: "Z123", Records: []config.RecordConfig{{FQDN: "nas.example.net.", RecordType: "A", Schedule: "@daily"}}},
+			},
+		},
+	}}})
+	require.NoError(t, err)
+
+	ipGetter := &countingIPGetter{mockIPGetter: mockIPGetter{ipv4: "192.0.2.10"}}
+	ddns := &DDNS{
+		elapseTimeToCheck: 10 * time.Millisecond,
+		recordSchedules:   records,
+		schedules:         schedules,
+		IPGetter:          ipGetter,
+		DDNS:              &mockProvider{records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10", Zone: "Z123"}}},
+		force:             make(chan struct{}, 1),
+	}
+
+	assert.ElementsMatch(t, []string{"1h", "@daily"}, slices.Collect(maps.Keys(ddns.scheduleSpecs())))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ddns.Run(ctx)
+		close(done)
+	}()
+
+	// only the first cycle runs, the check interval is not a schedule of any record
+	assert.Eventually(t, func() bool { return ipGetter.lookups.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), ipGetter.lookups.Load())
+
+	cancel()
+	<-done
+}
+
+func TestNewDDNS_reconcileWithoutStateDir(t *testing.T) {
+	testCases := []struct {
+		name               string
+		reconcileEvery     int
+		expectedGetRecords int
+	}{
+		{
+			name:               "records read every cycle by default",
+			expectedGetRecords: 2,
+		},
+		{
+			name:               "records read every reconcile interval",
+			reconcileEvery:     3600,
+			expectedGetRecords: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		reconcileEvery := testCase.reconcileEvery
+		expectedGetRecords := testCase.expectedGetRecords
+
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg := reloadConfig(60, "vpn.example.net.")
+			cfg.DDNS.ReconcileEverySeconds = reconcileEvery
+			ddns, err := NewDDNS(cfg)
+			require.NoError(t, err)
+
+			provider := &mockProvider{
+				records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10", Zone: "Z123"}},
+			}
+			ddns.IPGetter = &mockIPGetter{ipv4: "192.0.2.10"}
+			ddns.DDNS = provider
+
+			require.NoError(t, ddns.do(context.Background(), nil))
+			require.NoError(t, ddns.do(context.Background(), nil))
+			assert.Equal(t, expectedGetRecords, provider.getCalls)
+		})
+	}
+}
+
+func scheduledConfig() *config.SimpleDDNS {
+	return &config.SimpleDDNS{DDNS: config.DDNSConfig{AWS: []config.AWSConfig{
+		{
+			AccountName: "home",
+			Schedule:    "@hourly",
+			Zones: []config.ZoneConfig{
+				{
+					ID:       "Z123",
+					Schedule: "1m",
+					Records: []config.RecordConfig{
+						{FQDN: "vpn.example.net.", RecordType: "A"},
+						{FQDN: "vpn.example.net.", RecordType: "TXT", Schedule: "*/15 * * * *"},
+					},
+				},
+				{
+					ID:      "Z456",
+					Records: []config.RecordConfig{{FQDN: "office.example.net.", RecordType: "A"}},
+				},
+			},
+		},
+		{
+			AccountName: "lab",
+			Records:     []config.RecordConfig{{FQDN: "lab.example.net.", RecordType: "AAAA"}},
+		},
+	}}}
+}
+
+func TestConfigSchedules(t *testing.T) {
+	records, schedules, err := configSchedules(scheduledConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"vpn.example.net.|A":    {"1m"},
+		"vpn.example.net.|TXT":  {"*/15 * * * *"},
+		"office.example.net.|A": {"@hourly"},
+		"lab.example.net.|AAAA": {defaultSchedule},
+	}, records)
+	assert.Len(t, schedules, 3)
+
+	invalid := scheduledConfig()
+	invalid.DDNS.AWS[1].Records[0].Schedule = "61 * * * *"
+	_, _, err = configSchedules(invalid)
+	assert.EqualError(t, err, `record lab.example.net.: schedule "61 * * * *": invalid minute "61", want 0 to 59`)
+}
+
+func TestDDNS_dueRecords(t *testing.T) {
+	records, schedules, err := configSchedules(scheduledConfig())
+	require.NoError(t, err)
+	ddns := &DDNS{recordSchedules: records, schedules: schedules}
+
+	vpn := domain.Record{FQDN: "vpn.example.net.", IPType: "A"}
+	office := domain.Record{FQDN: "office.example.net.", IPType: "A"}
+	lab := domain.Record{FQDN: "lab.example.net.", IPType: "AAAA"}
+	ptr := domain.Record{FQDN: "10.2.0.192.in-addr.arpa.", IPType: "PTR"}
+	all := []domain.Record{vpn, office, lab, ptr}
+
+	testCases := []struct {
+		name            string
+		due             []string
+		expectedRecords []domain.Record
+	}{
+		{name: "all", expectedRecords: all},
+		{name: "every minute", due: []string{"1m"}, expectedRecords: []domain.Record{vpn}},
+		{name: "hourly and default", due: []string{"@hourly", defaultSchedule}, expectedRecords: []domain.Record{office, lab, ptr}},
+		{name: "none", due: []string{}, expectedRecords: []domain.Record{}},
+	}
+
+	for _, testCase := range testCases {
+		due := testCase.due
+		expectedRecords := testCase.expectedRecords
+
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, expectedRecords, ddns.dueRecords(all, due))
+		})
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	records, schedules, err := configSchedules(scheduledConfig())
+	require.NoError(t, err)
+	ddns := &DDNS{
+		elapseTimeToCheck: 5 * time.Minute,
+		retryInitial:      10 * time.Second,
+		recordSchedules:   records,
+		schedules:         schedules,
+	}
+	sched := newScheduler(ddns)
+	start := time.Date(2026, 5, 1, 10, 0, 30, 0, time.UTC)
+
+	// the first cycle checks every record
+	all := sched.all(start)
+	assert.ElementsMatch(t, []string{defaultSchedule, "1m", "*/15 * * * *", "@hourly"}, all)
+	_, ok := sched.wait(start)
+	assert.False(t, ok)
+
+	sched.finished(all, nil, start)
+	wait, ok := sched.wait(start)
+	require.True(t, ok)
+	assert.Equal(t, time.Minute, wait)
+
+	now := start.Add(time.Minute)
+	assert.Empty(t, sched.due(now.Add(-time.Second)))
+	assert.Equal(t, []string{"1m"}, sched.due(now))
+
+	// a failed cycle is retried before its schedule is due again
+	sched.finished([]string{"1m"}, errors.New("throttled"), now)
+	wait, ok = sched.wait(now)
+	require.True(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, []string{"1m"}, sched.due(now))
+	sched.finished([]string{"1m"}, nil, now)
+
+	// at 10:15 the quarter hour schedule is due with the one of every minute
+	// and the check interval
+	now = time.Date(2026, 5, 1, 10, 15, 0, 0, time.UTC)
+	assert.ElementsMatch(t, []string{defaultSchedule, "1m", "*/15 * * * *"}, sched.due(now))
+
+	// a schedule removed by a reload is dropped
+	ddns.schedules = map[string]schedule.Schedule{}
+	assert.Empty(t, sched.due(now.Add(time.Hour)))
+	assert.Equal(t, []string{defaultSchedule}, sched.all(now))
+}
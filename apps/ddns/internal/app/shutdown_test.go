@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+// slowProvider takes delay to update the records unless the context is done
+// first.
+type slowProvider struct {
+	mockProvider
+	delay   time.Duration
+	started chan struct{}
+	err     chan error
+}
+
+func (s *slowProvider) UpdateRecords(ctx context.Context, records []domain.Record) ([]domain.UpdateResult, error) {
+	close(s.started)
+
+	select {
+	case <-time.After(s.delay):
+		s.err <- nil
+		return s.mockProvider.UpdateRecords(ctx, records)
+	case <-ctx.Done():
+		s.err <- ctx.Err()
+		return nil, ctx.Err()
+	}
+}
+
+func TestDDNS_RunShutdown(t *testing.T) {
+	testCases := []struct {
+		name           string
+		grace          time.Duration
+		expectedErr    error
+		expectedSaved  string
+		expectedUpdate bool
+	}{
+		{
+			name:           "update finishes within the grace period",
+			grace:          5 * time.Second,
+			expectedSaved:  "192.0.2.10",
+			expectedUpdate: true,
+		},
+		{
+			name:          "update cancelled after the grace period",
+			grace:         50 * time.Millisecond,
+			expectedErr:   context.Canceled,
+			expectedSaved: "192.0.2.1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		grace := testCase.grace
+		expectedErr := testCase.expectedErr
+		expectedSaved := testCase.expectedSaved
+		expectedUpdate := testCase.expectedUpdate
+
+		t.Run(testCase.name, func(t *testing.T) {
+			provider := &slowProvider{
+				mockProvider: mockProvider{
+					records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"}},
+				},
+				delay:   300 * time.Millisecond,
+				started: make(chan struct{}),
+				err:     make(chan error, 1),
+			}
+			store := &mockStateStore{}
+			ddns := &DDNS{
+				elapseTimeToCheck: time.Hour,
+				reconcileEvery:    time.Hour,
+				shutdownGrace:     grace,
+				store:             store,
+				IPGetter:          &mockIPGetter{ipv4: "192.0.2.10"},
+				DDNS:              provider,
+				force:             make(chan struct{}, 1),
+			}
+			ddns.loadState()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				ddns.Run(ctx)
+				close(done)
+			}()
+
+			<-provider.started
+			cancel()
+			<-done
+
+			assert.ErrorIs(t, <-provider.err, expectedErr)
+			assert.Equal(t, expectedUpdate, len(provider.updateInputs) == 1)
+			require.NotNil(t, store.saved)
+			require.Len(t, store.saved.Records, 1)
+			assert.Equal(t, expectedSaved, store.saved.Records[0].IP)
+
+			// a cycle started after the stop is refused
+			assert.ErrorIs(t, ddns.cycle(context.Background(), nil), ErrShuttingDown)
+		})
+	}
+}
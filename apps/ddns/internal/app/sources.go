@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const (
+	sourcePublic    = "public"
+	sourceInterface = "interface"
+	sourceStatic    = "static"
+)
+
+// addresses are the IPv4 and IPv6 addresses published for a source, empty
+// when the source has none.
+type addresses struct {
+	ipv4 string
+	ipv6 string
+}
+
+// SourceAddresses are the addresses detected from a source, Err is set when
+// the source could not be read.
+type SourceAddresses struct {
+	Source domain.IPSource
+	IPv4   string
+	IPv6   string
+	Err    error
+}
+
+// DetectAddresses returns the addresses of the public address detector and
+// of every other source used by the configured records.
+func (ddns *DDNS) DetectAddresses(ctx context.Context) []SourceAddresses {
+	ipv4, errIPv4 := ddns.GetIPV4(ctx)
+	ipv6, errIPv6 := ddns.GetIPV6(ctx)
+
+	detected := []SourceAddresses{
+		{Source: domain.IPSource{Type: sourcePublic}, IPv4: ipv4, IPv6: ipv6, Err: errors.Join(errIPv4, errIPv6)},
+	}
+
+	for _, source := range ddns.sources {
+		switch source.Type {
+		case sourceStatic:
+			detected = append(detected, SourceAddresses{Source: source, IPv4: source.IPv4, IPv6: source.IPv6})
+		case sourceInterface:
+			found, err := ddns.interfaceAddresses(source.Interface)
+			detected = append(detected, SourceAddresses{Source: source, IPv4: found.ipv4, IPv6: found.ipv6, Err: err})
+		}
+	}
+
+	return detected
+}
+
+// sourceAddresses resolves the addresses of every source used by records.
+// Records without a source publish the public addresses.
+func (ddns *DDNS) sourceAddresses(ctx context.Context, records []domain.Record, public addresses) map[domain.IPSource]addresses {
+	resolved := map[domain.IPSource]addresses{
+		{Type: sourcePublic}: public,
+	}
+
+	for _, record := range records {
+		source := recordSource(record)
+		if _, ok := resolved[source]; ok {
+			continue
+		}
+
+		switch source.Type {
+		case sourceStatic:
+			resolved[source] = addresses{ipv4: source.IPv4, ipv6: source.IPv6}
+		case sourceInterface:
+			interfaceAddresses, err := ddns.interfaceAddresses(source.Interface)
+			lookupMetrics(sourceInterface, interfaceAddresses, err)
+			if err != nil {
+				logging.FromContext(ctx).WithField(logging.FieldSource, source.Interface).Errorf("failed to get addresses of interface %s: %v", source.Interface, err)
+			}
+			resolved[source] = interfaceAddresses
+		default:
+			resolved[source] = public
+		}
+	}
+
+	return resolved
+}
+
+// interfaceAddresses returns the first IPv4 and IPv6 unicast addresses of a
+// network interface, link local addresses are ignored.
+func (ddns *DDNS) interfaceAddresses(name string) (addresses, error) {
+	addrs, err := ddns.interfaceAddrs(name)
+	if err != nil {
+		return addresses{}, err
+	}
+
+	found := addresses{}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			if found.ipv4 == "" {
+				found.ipv4 = ip4.String()
+			}
+			continue
+		}
+
+		if found.ipv6 == "" {
+			found.ipv6 = ipNet.IP.String()
+		}
+	}
+
+	return found, nil
+}
+
+func recordSource(record domain.Record) domain.IPSource {
+	if record.Source == nil {
+		return domain.IPSource{Type: sourcePublic}
+	}
+
+	return *record.Source
+}
+
+func interfaceAddrs(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return iface.Addrs()
+}
+
+// configSources returns the sources other than the public address used by
+// the configured records, without repetitions.
+func configSources(ddnsConfig *config.SimpleDDNS) []domain.IPSource {
+	sources := make([]domain.IPSource, 0)
+	seen := make(map[domain.IPSource]bool)
+
+	add := func(records []config.RecordConfig) {
+		for _, record := range records {
+			if record.IPSource == nil || record.IPSource.Type == sourcePublic {
+				continue
+			}
+
+			source := domain.IPSource{
+				Type:      record.IPSource.Type,
+				Interface: record.IPSource.Interface,
+				IPv4:      record.IPSource.IPv4,
+				IPv6:      record.IPSource.IPv6,
+			}
+			if !seen[source] {
+				seen[source] = true
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	for _, account := range ddnsConfig.DDNS.AWS {
+		add(account.Records)
+		for _, zone := range account.Zones {
+			add(zone.Records)
+		}
+	}
+
+	return sources
+}
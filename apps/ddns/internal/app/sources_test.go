@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceAddresses(t *testing.T) {
+	public := addresses{ipv4: "192.0.2.10", ipv6: "2001:db8::10"}
+	eth0 := &domain.IPSource{Type: "interface", Interface: "eth0"}
+	wlan0 := &domain.IPSource{Type: "interface", Interface: "wlan0"}
+	static := &domain.IPSource{Type: "static", IPv4: "192.168.1.10"}
+
+	interfaces := map[string][]net.Addr{
+		"eth0": {
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+			&net.IPNet{IP: net.ParseIP("192.168.1.20"), Mask: net.CIDRMask(24, 32)},
+			&net.IPNet{IP: net.ParseIP("192.168.1.21"), Mask: net.CIDRMask(24, 32)},
+			&net.IPNet{IP: net.ParseIP("fd00::20"), Mask: net.CIDRMask(64, 128)},
+		},
+	}
+
+	ddns := &DDNS{
+		interfaceAddrs: func(name string) ([]net.Addr, error) {
+			addrs, ok := interfaces[name]
+			if !ok {
+				return nil, errors.New("no such network interface")
+			}
+			return addrs, nil
+		},
+	}
+
+	records := []domain.Record{
+		{FQDN: "vpn.example.net.", IPType: "A"},
+		{FQDN: "nas.example.net.", IPType: "A", Source: eth0},
+		{FQDN: "nas.example.net.", IPType: "AAAA", Source: eth0},
+		{FQDN: "tv.example.net.", IPType: "A", Source: wlan0},
+		{FQDN: "printer.example.net.", IPType: "A", Source: static},
+	}
+
+	expected := map[domain.IPSource]addresses{
+		{Type: "public"}: public,
+		*eth0:            {ipv4: "192.168.1.20", ipv6: "fd00::20"},
+		*wlan0:           {},
+		*static:          {ipv4: "192.168.1.10"},
+	}
+
+	assert.Equal(t, expected, ddns.sourceAddresses(context.Background(), records, public))
+}
+
+func TestDDNS_DetectAddresses(t *testing.T) {
+	eth0 := domain.IPSource{Type: "interface", Interface: "eth0"}
+	wlan0 := domain.IPSource{Type: "interface", Interface: "wlan0"}
+	static := domain.IPSource{Type: "static", IPv4: "192.168.1.10"}
+
+	ddns := &DDNS{
+		IPGetter: &mockIPGetter{ipv4: "192.0.2.10"},
+		sources:  []domain.IPSource{eth0, wlan0, static},
+		interfaceAddrs: func(name string) ([]net.Addr, error) {
+			if name != "eth0" {
+				return nil, errors.New("no such network interface")
+			}
+			return []net.Addr{&net.IPNet{IP: net.ParseIP("192.168.1.20"), Mask: net.CIDRMask(24, 32)}}, nil
+		},
+	}
+
+	expected := []SourceAddresses{
+		{Source: domain.IPSource{Type: "public"}, IPv4: "192.0.2.10"},
+		{Source: eth0, IPv4: "192.168.1.20"},
+		{Source: wlan0, Err: errors.New("no such network interface")},
+		{Source: static, IPv4: "192.168.1.10"},
+	}
+
+	assert.Equal(t, expected, ddns.DetectAddresses(context.Background()))
+}
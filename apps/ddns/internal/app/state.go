@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultReconcileInterval = time.Hour
+
+// loadState restores the state saved by a previous run. A state saved with
+// another config is only used for its addresses, the records are read again
+// from the providers.
+func (ddns *DDNS) loadState() {
+	state, err := ddns.store.Load()
+	if err != nil {
+		log.Warnf("failed to load state, reading records from the providers: %v", err)
+	}
+
+	if state == nil {
+		state = &domain.State{}
+	}
+
+	if state.ConfigHash != ddns.configHash {
+		state.Records = nil
+		state.ReconciledAt = time.Time{}
+	}
+
+	state.ConfigHash = ddns.configHash
+	ddns.state = state
+	ddns.public = addresses{ipv4: state.IPv4, ipv6: state.IPv6}
+}
+
+// stateFresh reports whether the records kept in the state can be used
+// instead of reading them from the providers.
+func (ddns *DDNS) stateFresh(now time.Time) bool {
+	if ddns.state == nil || ddns.state.ReconciledAt.IsZero() || ddns.reconcileEvery <= 0 {
+		return false
+	}
+
+	return now.Sub(ddns.state.ReconciledAt) < ddns.reconcileEvery
+}
+
+func (ddns *DDNS) stateRecords() []domain.Record {
+	records := make([]domain.Record, 0, len(ddns.state.Records))
+	for _, record := range ddns.state.Records {
+		records = append(records, record.Record)
+	}
+
+	return records
+}
+
+// reconciled replaces the records of the state with the ones read from the
+// providers, keeping the changes that published them.
+func (ddns *DDNS) reconciled(records []domain.Record, now time.Time) {
+	if ddns.state == nil {
+		return
+	}
+
+	previous := make(map[string]domain.PublishedRecord, len(ddns.state.Records))
+	for _, record := range ddns.state.Records {
+		previous[stateKey(record.Record)] = record
+	}
+
+	published := make([]domain.PublishedRecord, 0, len(records))
+	for _, record := range records {
+		current := domain.PublishedRecord{Record: record}
+		if known, ok := previous[stateKey(record)]; ok && known.IP == record.IP {
+			current.ChangeIDs = known.ChangeIDs
+			current.PublishedAt = known.PublishedAt
+		}
+
+		published = append(published, current)
+	}
+
+	ddns.state.Records = published
+	ddns.state.ReconciledAt = now
+}
+
+// published records the outcome of an update in the state. After a failed
+// update the providers are read again in the next cycle.
+func (ddns *DDNS) published(results []domain.UpdateResult, err error, now time.Time) {
+	if ddns.state == nil {
+		return
+	}
+
+	if err != nil {
+		ddns.state.ReconciledAt = time.Time{}
+	}
+
+	for _, result := range results {
+		if result.Err != nil || len(result.Failed) > 0 {
+			ddns.state.ReconciledAt = time.Time{}
+		}
+
+		for _, updated := range result.Updated {
+			for i := range ddns.state.Records {
+				record := &ddns.state.Records[i]
+				if record.FQDN != updated.FQDN || record.IPType != updated.IPType || (updated.Zone != "" && record.Zone != updated.Zone) {
+					continue
+				}
+
+				record.IP = updated.IP
+				record.ChangeIDs = result.ChangeIDs
+				record.PublishedAt = now
+			}
+		}
+	}
+}
+
+func (ddns *DDNS) saveState(ctx context.Context) {
+	if ddns.store == nil {
+		return
+	}
+
+	if err := ddns.store.Save(ddns.state); err != nil {
+		logging.FromContext(ctx).Errorf("failed to save state: %v", err)
+	}
+}
+
+func stateKey(record domain.Record) string {
+	return record.FQDN + "|" + record.IPType + "|" + record.Zone
+}
+
+// configHash identifies the records configured, so a state saved with a
+// different config is not trusted.
+func configHash(ddnsConfig *config.SimpleDDNS) string {
+	data, err := json.Marshal(ddnsConfig.DDNS.AWS)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockIPGetter struct {
+	ipv4 string
+	ipv6 string
+}
+
+func (m *mockIPGetter) GetIPV4(context.Context) (string, error) {
+	return m.ipv4, nil
+}
+
+func (m *mockIPGetter) GetIPV6(context.Context) (string, error) {
+	return m.ipv6, nil
+}
+
+type mockProvider struct {
+	records      []domain.Record
+	updateErr    error
+	getCalls     int
+	updateInputs [][]domain.Record
+}
+
+func (m *mockProvider) GetRecords(context.Context) ([]domain.Record, error) {
+	m.getCalls++
+	return m.records, nil
+}
+
+func (m *mockProvider) UpdateRecords(_ context.Context, records []domain.Record) ([]domain.UpdateResult, error) {
+	m.updateInputs = append(m.updateInputs, records)
+	if m.updateErr != nil {
+		return []domain.UpdateResult{{Zone: "Z123", Failed: records, Err: m.updateErr}}, m.updateErr
+	}
+
+	return []domain.UpdateResult{{Zone: "Z123", Atomic: true, ChangeIDs: []string{"C1"}, Updated: records}}, nil
+}
+
+func (m *mockProvider) PlanRecords(context.Context, []domain.Record) ([]domain.PlannedChange, error) {
+	return nil, nil
+}
+
+type mockStateStore struct {
+	saved *domain.State
+}
+
+func (m *mockStateStore) Load() (*domain.State, error) {
+	return m.saved, nil
+}
+
+func (m *mockStateStore) Save(state *domain.State) error {
+	saved := *state
+	saved.Records = append([]domain.PublishedRecord(nil), state.Records...)
+	m.saved = &saved
+	return nil
+}
+
+func TestDDNS_doWithState(t *testing.T) {
+	ipGetter := &mockIPGetter{ipv4: "192.0.2.10"}
+	provider := &mockProvider{
+		records: []domain.Record{{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"}},
+	}
+	store := &mockStateStore{}
+
+	ddns := &DDNS{
+		reconcileEvery: time.Hour,
+		store:          store,
+		configHash:     "abc",
+		IPGetter:       ipGetter,
+		DDNS:           provider,
+	}
+	ddns.loadState()
+
+	// the first cycle reads the provider and publishes the new address
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 1, provider.getCalls)
+	require.Len(t, provider.updateInputs, 1)
+	require.NotNil(t, store.saved)
+	assert.Equal(t, "192.0.2.10", store.saved.IPv4)
+	assert.Equal(t, "192.0.2.10", store.saved.Records[0].IP)
+	assert.Equal(t, []string{"C1"}, store.saved.Records[0].ChangeIDs)
+	assert.False(t, store.saved.ReconciledAt.IsZero())
+
+	// an unchanged address costs no provider calls
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 1, provider.getCalls)
+	assert.Len(t, provider.updateInputs, 1)
+
+	// a new address is published from the state
+	ipGetter.ipv4 = "192.0.2.20"
+	provider.updateErr = errors.New("throttled")
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 1, provider.getCalls)
+	require.Len(t, provider.updateInputs, 2)
+	assert.Equal(t, "192.0.2.10", provider.updateInputs[1][0].Previous)
+	assert.True(t, store.saved.ReconciledAt.IsZero())
+
+	// a failed update reads the provider again
+	provider.updateErr = nil
+	ddns.do(context.Background(), nil)
+	assert.Equal(t, 2, provider.getCalls)
+	assert.Len(t, provider.updateInputs, 3)
+}
+
+func TestDDNS_loadState(t *testing.T) {
+	saved := &domain.State{
+		IPv4:         "192.0.2.10",
+		Records:      []domain.PublishedRecord{{Record: domain.Record{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.10"}}},
+		ReconciledAt: time.Now(),
+		ConfigHash:   "old",
+	}
+
+	ddns := &DDNS{
+		reconcileEvery: time.Hour,
+		store:          &mockStateStore{saved: saved},
+		configHash:     "new",
+	}
+	ddns.loadState()
+
+	assert.Equal(t, "192.0.2.10", ddns.state.IPv4)
+	assert.Empty(t, ddns.state.Records)
+	assert.False(t, ddns.stateFresh(time.Now()), "records saved with another config are read again")
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+// recordsRead keeps the values of the records read from the providers in
+// the status.
+func (ddns *DDNS) recordsRead(records []domain.Record) {
+	if ddns.records == nil {
+		ddns.records = make(map[string]domain.RecordStatus, len(records))
+	}
+
+	for _, record := range records {
+		status, ok := ddns.records[stateKey(record)]
+		if !ok {
+			status = domain.RecordStatus{FQDN: record.FQDN, RecordType: record.IPType, Zone: record.Zone, Status: domain.RecordOK}
+		}
+
+		status.Value = record.IP
+		ddns.records[stateKey(record)] = status
+	}
+}
+
+// recordsUpdated keeps the outcome of an update in the status.
+func (ddns *DDNS) recordsUpdated(results []domain.UpdateResult, now time.Time) {
+	if ddns.records == nil {
+		ddns.records = map[string]domain.RecordStatus{}
+	}
+
+	for _, result := range results {
+		for _, record := range result.Updated {
+			ddns.records[stateKey(record)] = domain.RecordStatus{
+				FQDN:       record.FQDN,
+				RecordType: record.IPType,
+				Zone:       record.Zone,
+				Value:      record.IP,
+				Status:     domain.RecordOK,
+				UpdatedAt:  now,
+			}
+		}
+
+		for _, record := range result.Failed {
+			status := ddns.records[stateKey(record)]
+			status.FQDN, status.RecordType, status.Zone = record.FQDN, record.IPType, record.Zone
+			status.Status = domain.RecordFailed
+			if result.Err != nil {
+				status.Error = result.Err.Error()
+			}
+
+			ddns.records[stateKey(record)] = status
+		}
+	}
+}
+
+// publishStatus keeps the status after a cycle and shares it.
+func (ddns *DDNS) publishStatus(err error, now time.Time) {
+	status := domain.Status{IPv4: ddns.public.ipv4, IPv6: ddns.public.ipv6}
+	if err == nil {
+		ddns.lastUpdate = now
+	} else {
+		status.Error = err.Error()
+	}
+	status.LastUpdate = ddns.lastUpdate
+
+	for _, record := range ddns.records {
+		status.Records = append(status.Records, record)
+	}
+	sort.Slice(status.Records, func(i, j int) bool {
+		if status.Records[i].FQDN != status.Records[j].FQDN {
+			return status.Records[i].FQDN < status.Records[j].FQDN
+		}
+		return status.Records[i].RecordType < status.Records[j].RecordType
+	})
+
+	ddns.statusMu.Lock()
+	ddns.status = status
+	ddns.statusMu.Unlock()
+
+	if ddns.publisher != nil {
+		ddns.publisher.Publish(status)
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+type mockPublisher struct {
+	statuses []domain.Status
+}
+
+func (m *mockPublisher) Start(func()) {}
+
+func (m *mockPublisher) Publish(status domain.Status) {
+	m.statuses = append(m.statuses, status)
+}
+
+func (m *mockPublisher) Stop() {}
+
+func TestDDNS_publishStatus(t *testing.T) {
+	publisher := &mockPublisher{}
+	provider := &mockProvider{
+		records: []domain.Record{
+			{FQDN: "vpn.example.net.", IPType: "A", IP: "192.0.2.1", Zone: "Z123"},
+			{FQDN: "nas.example.net.", IPType: "AAAA", IP: "2001:db8::1", Zone: "Z123"},
+		},
+		updateErr: errors.New("throttled"),
+	}
+	ddns := &DDNS{
+		IPGetter:  &mockIPGetter{ipv4: "192.0.2.10"},
+		DDNS:      provider,
+		publisher: publisher,
+	}
+
+	require.Error(t, ddns.Once(context.Background()))
+	provider.updateErr = nil
+	require.NoError(t, ddns.Once(context.Background()))
+
+	require.Len(t, publisher.statuses, 2)
+
+	failed := publisher.statuses[0]
+	assert.Equal(t, "throttled", failed.Error)
+	assert.True(t, failed.LastUpdate.IsZero())
+	assert.Equal(t, []domain.RecordStatus{
+		{FQDN: "nas.example.net.", RecordType: "AAAA", Zone: "Z123", Value: "2001:db8::1", Status: domain.RecordOK},
+		{FQDN: "vpn.example.net.", RecordType: "A", Zone: "Z123", Value: "192.0.2.1", Status: domain.RecordFailed, Error: "throttled"},
+	}, failed.Records)
+
+	updated := publisher.statuses[1]
+	assert.Empty(t, updated.Error)
+	assert.Equal(t, "192.0.2.10", updated.IPv4)
+	assert.False(t, updated.LastUpdate.IsZero())
+	require.Len(t, updated.Records, 2)
+	assert.Equal(t, domain.RecordOK, updated.Records[1].Status)
+	assert.Equal(t, "192.0.2.10", updated.Records[1].Value)
+	assert.Empty(t, updated.Records[1].Error)
+	assert.False(t, updated.Records[1].UpdatedAt.IsZero())
+}
+
+func TestDDNS_Force(t *testing.T) {
+	ddns := &DDNS{force: make(chan struct{}, 1)}
+
+	ddns.Force()
+	ddns.Force()
+
+	assert.Len(t, ddns.force, 1)
+}
+
+// countingIPGetter counts the cycles, which look up the IPv4 address once.
+type countingIPGetter struct {
+	mockIPGetter
+	lookups atomic.Int32
+}
+
+func (c *countingIPGetter) GetIPV4(ctx context.Context) (string, error) {
+	c.lookups.Add(1)
+	return c.mockIPGetter.GetIPV4(ctx)
+}
+
+func TestDDNS_RunForce(t *testing.T) {
+	ipGetter := &countingIPGetter{mockIPGetter: mockIPGetter{ipv4: "192.0.2.10"}}
+	ddns := &DDNS{
+		elapseTimeToCheck: time.Hour,
+		IPGetter:          ipGetter,
+		DDNS:              &mockProvider{},
+		force:             make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ddns.Run(ctx)
+		close(done)
+	}()
+
+	ddns.Force()
+	assert.Eventually(t, func() bool { return ipGetter.lookups.Load() >= 2 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -7,13 +7,70 @@ type IPGetter interface {
 	GetIPV6(ctx context.Context) (string, error)
 }
 
+// Record is a DNS record managed by ddns. For A and AAAA records IP holds
+// the address, for the record types derived from the addresses it holds the
+// record value and Spec describes how that value is built. Previous is the
+// value published before an update, empty when it is not known. Zone is the
+// zone holding the record and Source where its addresses come from, the
+// public addresses when it is nil.
 type Record struct {
-	IP     string
-	IPType string
-	FQDN   string
+	IP       string
+	IPType   string
+	FQDN     string
+	Spec     *RecordSpec
+	Previous string
+	Zone     string
+	Source   *IPSource
+}
+
+type RecordSpec struct {
+	Target   string
+	Port     int
+	Priority int
+	Weight   int
+	Params   string
+}
+
+// IPSource is where the addresses of a record come from: the public address
+// detector, a local network interface or static addresses.
+type IPSource struct {
+	Type      string
+	Interface string
+	IPv4      string
+	IPv6      string
+}
+
+// UpdateResult is the outcome of updating the records of a zone. Atomic is
+// true when all the changes of the zone were applied in a single request.
+type UpdateResult struct {
+	Provider  string
+	Zone      string
+	Atomic    bool
+	ChangeIDs []string
+	Updated   []Record
+	Failed    []Record
+	Err       error
+}
+
+// PlannedChange is a change that updating the records would send to a
+// provider. Action is the provider action, e.g. UPSERT or DELETE.
+// HealthCheck is create, update or reuse for records with a health check,
+// HealthCheckID the health check updated or reused.
+type PlannedChange struct {
+	Provider      string
+	Zone          string
+	Action        string
+	FQDN          string
+	Type          string
+	TTL           int
+	Values        []string
+	Previous      string
+	HealthCheck   string
+	HealthCheckID string
 }
 
 type DDNS interface {
 	GetRecords(ctx context.Context) ([]Record, error)
-	UpdateRecords(ctx context.Context, records []Record) error
+	UpdateRecords(ctx context.Context, records []Record) ([]UpdateResult, error)
+	PlanRecords(ctx context.Context, records []Record) ([]PlannedChange, error)
 }
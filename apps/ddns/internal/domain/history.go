@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+const (
+	EventAddressChange = "address-change"
+	EventUpdate        = "update"
+
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+)
+
+// HistoryEvent is an entry of the update history: a change of the detected
+// public address or the update of a record.
+type HistoryEvent struct {
+	Time       time.Time
+	Kind       string
+	FQDN       string
+	RecordType string
+	OldValue   string
+	NewValue   string
+	Provider   string
+	Zone       string
+	ChangeIDs  []string
+	Outcome    string
+	Error      string
+	Duration   time.Duration
+}
+
+// HistoryFilter selects history events, empty fields match every event.
+type HistoryFilter struct {
+	FQDN  string
+	Since time.Time
+}
+
+type History interface {
+	Append(events ...HistoryEvent) error
+	Query(filter HistoryFilter) ([]HistoryEvent, error)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AddressChange is a change of the public address of a family, ipv4 or
+// ipv6. IPv4 and IPv6 are the current addresses of both families.
+type AddressChange struct {
+	Time   time.Time
+	Family string
+	OldIP  string
+	NewIP  string
+	IPv4   string
+	IPv6   string
+}
+
+// Hooks act on the changes of the public addresses, besides the records.
+type Hooks interface {
+	Run(ctx context.Context, change AddressChange) error
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// events sent to the notifiers.
+const (
+	NotifyIPChanged     = "ip-changed"
+	NotifyRecordUpdated = "record-updated"
+	NotifyUpdateFailed  = "update-failed"
+	NotifyRecovered     = "recovered"
+)
+
+// Notification is an event of ddns worth telling someone about. Family is
+// ipv4 or ipv6 for address changes, Failures the cycles in a row that failed
+// for update-failed and recovered.
+type Notification struct {
+	Event      string
+	Time       time.Time
+	Family     string
+	FQDN       string
+	RecordType string
+	OldValue   string
+	NewValue   string
+	Provider   string
+	Zone       string
+	Failures   int
+	Error      string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
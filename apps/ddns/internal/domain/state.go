@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// State is what ddns remembers between cycles and restarts: the last detected
+// public addresses and the records as last read from or published to the
+// providers. ReconciledAt is when the records were last read from the
+// providers, ConfigHash identifies the config they were read with.
+type State struct {
+	IPv4         string
+	IPv6         string
+	Records      []PublishedRecord
+	ReconciledAt time.Time
+	ConfigHash   string
+}
+
+// PublishedRecord is a record with the changes that last published it.
+type PublishedRecord struct {
+	Record
+	ChangeIDs   []string
+	PublishedAt time.Time
+}
+
+type StateStore interface {
+	Load() (*State, error)
+	Save(state *State) error
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// status of a record.
+const (
+	RecordOK     = "ok"
+	RecordFailed = "failed"
+)
+
+// Status is the current view of ddns shared with other systems. LastUpdate
+// is when a cycle last succeeded, Error why the last one failed and Paused
+// whether the checks are paused.
+type Status struct {
+	IPv4       string
+	IPv6       string
+	LastUpdate time.Time
+	Error      string
+	Paused     bool
+	Records    []RecordStatus
+}
+
+// RecordStatus is the published value of a record and whether its last
+// update failed. UpdatedAt is when ddns last changed it.
+type RecordStatus struct {
+	FQDN       string
+	RecordType string
+	Zone       string
+	Value      string
+	Status     string
+	Error      string
+	UpdatedAt  time.Time
+}
+
+// StatusPublisher shares the status of ddns while it runs. force is called
+// when an update is requested through the publisher.
+type StatusPublisher interface {
+	Start(force func())
+	Publish(status Status)
+	Stop()
+}
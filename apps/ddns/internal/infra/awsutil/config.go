@@ -0,0 +1,67 @@
+// Package awsutil holds what the AWS backed packages share: the SDK
+// configuration of an account and the IAM policy types.
+package awsutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const defaultSessionName = "ddns"
+
+// LoadConfig builds the SDK configuration of an account. Static keys take
+// precedence over the default credential chain, and the resulting identity
+// is used to assume RoleARN when one is configured.
+func LoadConfig(ctx context.Context, account config.AWSConfig) (aws.Config, error) {
+	opts := make([]func(*awsconfig.LoadOptions) error, 0, 3)
+
+	if account.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(account.Region))
+	}
+
+	if account.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(account.Profile))
+	}
+
+	if account.AccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(account.AccessKey, account.SecretKey, "")))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load aws config for account %s: %w", account.AccountName, err)
+	}
+
+	if account.RoleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(roleProvider(sts.NewFromConfig(cfg), account))
+	}
+
+	return cfg, nil
+}
+
+func roleProvider(client *sts.Client, account config.AWSConfig) aws.CredentialsProvider {
+	sessionName := account.SessionName
+	if sessionName == "" {
+		sessionName = defaultSessionName
+	}
+
+	if account.WebIdentityTokenFile != "" {
+		return stscreds.NewWebIdentityRoleProvider(client, account.RoleARN, stscreds.IdentityTokenFile(account.WebIdentityTokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = sessionName
+		})
+	}
+
+	return stscreds.NewAssumeRoleProvider(client, account.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if account.ExternalID != "" {
+			o.ExternalID = aws.String(account.ExternalID)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package awsutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/jorgesanchez-e/localenvironment/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sharedConfig = `
+[profile ddns]
+aws_access_key_id = AKIAPROFILE
+aws_secret_access_key = profilesecret
+region = eu-west-1
+`
+
+func withSharedConfig(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	if err := os.WriteFile(configFile, []byte(sharedConfig), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+}
+
+func TestLoadConfig(t *testing.T) {
+	testCases := []struct {
+		name              string
+		account           config.AWSConfig
+		expectedRegion    string
+		expectedAccessKey string
+		expectedRole      bool
+		expectedError     bool
+	}{
+		{
+			name: "static keys",
+			account: config.AWSConfig{
+				AccountName: "static",
+				Region:      "us-east-1",
+				AccessKey:   "AKIASTATIC",
+				SecretKey:   "staticsecret",
+			},
+			expectedRegion:    "us-east-1",
+			expectedAccessKey: "AKIASTATIC",
+		},
+		{
+			name: "shared config profile",
+			account: config.AWSConfig{
+				AccountName: "profile",
+				Profile:     "ddns",
+			},
+			expectedRegion:    "eu-west-1",
+			expectedAccessKey: "AKIAPROFILE",
+		},
+		{
+			name: "region overrides profile region",
+			account: config.AWSConfig{
+				AccountName: "profile",
+				Region:      "us-west-2",
+				Profile:     "ddns",
+			},
+			expectedRegion:    "us-west-2",
+			expectedAccessKey: "AKIAPROFILE",
+		},
+		{
+			name: "assume role from profile",
+			account: config.AWSConfig{
+				AccountName: "role",
+				Profile:     "ddns",
+				RoleARN:     "arn:aws:iam::111122223333:role/ddns",
+				ExternalID:  "external",
+			},
+			expectedRegion: "eu-west-1",
+			expectedRole:   true,
+		},
+		{
+			name: "unknown profile",
+			account: config.AWSConfig{
+				AccountName: "missing",
+				Profile:     "missing",
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		account := testCase.account
+		expectedRegion := testCase.expectedRegion
+		expectedAccessKey := testCase.expectedAccessKey
+		expectedRole := testCase.expectedRole
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			withSharedConfig(t)
+
+			cfg, err := LoadConfig(context.Background(), account)
+			if expectedError {
+				assert.ErrorContains(t, err, account.AccountName)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, expectedRegion, cfg.Region)
+
+			if expectedRole {
+				cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+				require.True(t, ok)
+				assert.True(t, cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}))
+				return
+			}
+
+			creds, err := cfg.Credentials.Retrieve(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, expectedAccessKey, creds.AccessKeyID)
+		})
+	}
+}
+
+func TestRoleProvider(t *testing.T) {
+	webIdentity := config.AWSConfig{
+		RoleARN:              "arn:aws:iam::111122223333:role/ddns",
+		WebIdentityTokenFile: "/var/run/secrets/token",
+	}
+	assumeRole := config.AWSConfig{
+		RoleARN: "arn:aws:iam::111122223333:role/ddns",
+	}
+
+	assert.IsType(t, &stscreds.WebIdentityRoleProvider{}, roleProvider(nil, webIdentity))
+	assert.IsType(t, &stscreds.AssumeRoleProvider{}, roleProvider(nil, assumeRole))
+}
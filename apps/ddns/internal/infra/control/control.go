@@ -0,0 +1,242 @@
+// Package control serves the commands of a running ddns on a Unix socket:
+// each connection sends one command line and reads one JSON response.
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+// commands of the control socket.
+const (
+	CommandForce  = "force"
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandStatus = "status"
+)
+
+const (
+	connTimeout = 5 * time.Second
+	// socketMode lets the group of the daemon control it.
+	socketMode = 0o660
+)
+
+// Controller is the running ddns.
+type Controller interface {
+	Reconcile() error
+	Pause()
+	Resume()
+	Status() domain.Status
+}
+
+// Response is the answer to a command, Status is only set for status.
+type Response struct {
+	OK      bool    `json:"ok"`
+	Message string  `json:"message,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	Status  *Status `json:"status,omitempty"`
+}
+
+type Status struct {
+	IPv4       string         `json:"ipv4,omitempty"`
+	IPv6       string         `json:"ipv6,omitempty"`
+	LastUpdate time.Time      `json:"last_update"`
+	Error      string         `json:"error,omitempty"`
+	Paused     bool           `json:"paused"`
+	Records    []RecordStatus `json:"records"`
+}
+
+type RecordStatus struct {
+	FQDN       string    `json:"fqdn"`
+	RecordType string    `json:"type"`
+	Zone       string    `json:"zone,omitempty"`
+	Value      string    `json:"value"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Serve answers the commands sent to the socket at path until ctx is done.
+// A socket left behind by a previous run is replaced, it fails when another
+// daemon is answering on path or path is not a socket.
+func Serve(ctx context.Context, path string, controller Controller) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+
+	if err := removeStale(ctx, path); err != nil {
+		return err
+	}
+
+	listener, err := listen(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close() //nolint:errcheck
+	}()
+
+	go func() {
+		log.Infof("control socket on %s", path)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorf("control socket failed: %v", err)
+				}
+				return
+			}
+
+			go serveConn(conn, controller)
+		}
+	}()
+
+	return nil
+}
+
+// listen creates the socket with socketMode through the umask, so it never
+// exists with the wider mode of the process umask. The umask is process wide, Serve runs
+// before the daemon writes any file.
+func listen(ctx context.Context, path string) (net.Listener, error) {
+	umask := syscall.Umask(0o777 &^ socketMode)
+	defer syscall.Umask(umask)
+
+	return (&net.ListenConfig{}).Listen(ctx, "unix", path)
+}
+
+// removeStale removes the socket at path when nothing answers on it.
+func removeStale(ctx context.Context, path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check control socket: %w", err)
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("control socket %s exists and is not a socket", path)
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if conn, err := (&net.Dialer{}).DialContext(dialCtx, "unix", path); err == nil {
+		conn.Close() //nolint:errcheck
+		return fmt.Errorf("control socket %s is in use, is another ddns running?", path)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+
+	return nil
+}
+
+func serveConn(conn net.Conn, controller Controller) {
+	defer conn.Close() //nolint:errcheck
+
+	if err := conn.SetDeadline(time.Now().Add(connTimeout)); err != nil {
+		return
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+
+	response := handle(controller, strings.ToLower(strings.TrimSpace(line)))
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		log.Debugf("failed to answer control command: %v", err)
+	}
+}
+
+func handle(controller Controller, command string) Response {
+	switch command {
+	case CommandForce:
+		if err := controller.Reconcile(); err != nil {
+			return Response{Error: err.Error()}
+		}
+		log.Info("reconcile requested through the control socket")
+		return Response{OK: true, Message: "reconcile started"}
+	case CommandPause:
+		controller.Pause()
+		return Response{OK: true, Message: "updates paused"}
+	case CommandResume:
+		controller.Resume()
+		return Response{OK: true, Message: "updates resumed"}
+	case CommandStatus:
+		status := newStatus(controller.Status())
+		return Response{OK: true, Status: &status}
+	}
+
+	return Response{Error: fmt.Sprintf("unknown command %q, use %s, %s, %s or %s",
+		command, CommandForce, CommandPause, CommandResume, CommandStatus)}
+}
+
+func newStatus(status domain.Status) Status {
+	s := Status{
+		IPv4:       status.IPv4,
+		IPv6:       status.IPv6,
+		LastUpdate: status.LastUpdate,
+		Error:      status.Error,
+		Paused:     status.Paused,
+		Records:    make([]RecordStatus, 0, len(status.Records)),
+	}
+
+	for _, record := range status.Records {
+		s.Records = append(s.Records, RecordStatus(record))
+	}
+
+	return s
+}
+
+// Send sends command to the daemon listening on the socket at path. An
+// error is returned when the daemon cannot be reached or refuses the
+// command.
+func Send(ctx context.Context, path, command string) (Response, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", path)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to reach the daemon: %w", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	deadline := time.Now().Add(connTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return Response{}, err
+	}
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return Response{}, err
+	}
+
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return Response{}, fmt.Errorf("invalid answer from the daemon: %w", err)
+	}
+
+	if !response.OK {
+		return response, errors.New(response.Error)
+	}
+
+	return response, nil
+}
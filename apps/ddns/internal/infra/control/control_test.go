@@ -0,0 +1,203 @@
+package control
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+type fakeController struct {
+	reconcileErr error
+	reconciles   int
+	paused       bool
+	status       domain.Status
+}
+
+func (f *fakeController) Reconcile() error {
+	f.reconciles++
+	return f.reconcileErr
+}
+
+func (f *fakeController) Pause() {
+	f.paused = true
+}
+
+func (f *fakeController) Resume() {
+	f.paused = false
+}
+
+func (f *fakeController) Status() domain.Status {
+	status := f.status
+	status.Paused = f.paused
+	return status
+}
+
+// socketPath is a short path, Unix socket paths are limited to about 100
+// bytes.
+func socketPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "ddns")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) }) //nolint:errcheck
+
+	return filepath.Join(dir, "control.sock")
+}
+
+func TestServe(t *testing.T) {
+	updatedAt := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	controller := &fakeController{status: domain.Status{
+		IPv4:       "192.0.2.10",
+		LastUpdate: updatedAt,
+		Records: []domain.RecordStatus{
+			{FQDN: "vpn.example.net.", RecordType: "A", Zone: "Z123", Value: "192.0.2.10", Status: domain.RecordOK, UpdatedAt: updatedAt},
+		},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := socketPath(t)
+	require.NoError(t, Serve(ctx, path, controller))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketMode), info.Mode().Perm())
+
+	testCases := []struct {
+		name             string
+		command          string
+		expectedResponse Response
+		expectedErr      string
+		expectedPaused   bool
+	}{
+		{
+			name:             "force",
+			command:          "force",
+			expectedResponse: Response{OK: true, Message: "reconcile started"},
+		},
+		{
+			name:             "pause",
+			command:          "PAUSE",
+			expectedResponse: Response{OK: true, Message: "updates paused"},
+			expectedPaused:   true,
+		},
+		{
+			name:    "status",
+			command: "status",
+			expectedResponse: Response{OK: true, Status: &Status{
+				IPv4:       "192.0.2.10",
+				LastUpdate: updatedAt,
+				Paused:     true,
+				Records: []RecordStatus{
+					{FQDN: "vpn.example.net.", RecordType: "A", Zone: "Z123", Value: "192.0.2.10", Status: domain.RecordOK, UpdatedAt: updatedAt},
+				},
+			}},
+			expectedPaused: true,
+		},
+		{
+			name:             "resume",
+			command:          "resume",
+			expectedResponse: Response{OK: true, Message: "updates resumed"},
+		},
+		{
+			name:             "unknown command",
+			command:          "restart",
+			expectedResponse: Response{Error: `unknown command "restart", use force, pause, resume or status`},
+			expectedErr:      `unknown command "restart", use force, pause, resume or status`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		command := testCase.command
+		expectedResponse := testCase.expectedResponse
+		expectedErr := testCase.expectedErr
+		expectedPaused := testCase.expectedPaused
+
+		t.Run(testCase.name, func(t *testing.T) {
+			response, err := Send(context.Background(), path, command)
+
+			if expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, expectedErr)
+			}
+			assert.Equal(t, expectedResponse, response)
+			assert.Equal(t, expectedPaused, controller.paused)
+		})
+	}
+
+	assert.Equal(t, 1, controller.reconciles)
+}
+
+func TestServe_ExistingPath(t *testing.T) {
+	testCases := []struct {
+		name        string
+		create      func(t *testing.T, path string)
+		expectedErr string
+	}{
+		{
+			name: "stale socket replaced",
+			create: func(t *testing.T, path string) {
+				listener, err := net.Listen("unix", path)
+				require.NoError(t, err)
+				listener.(*net.UnixListener).SetUnlinkOnClose(false)
+				require.NoError(t, listener.Close())
+			},
+		},
+		{
+			name: "socket of a running daemon",
+			create: func(t *testing.T, path string) {
+				listener, err := net.Listen("unix", path)
+				require.NoError(t, err)
+				t.Cleanup(func() { listener.Close() }) //nolint:errcheck
+			},
+			expectedErr: "is in use, is another ddns running?",
+		},
+		{
+			name: "regular file",
+			create: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, nil, 0o600))
+			},
+			expectedErr: "exists and is not a socket",
+		},
+	}
+
+	for _, testCase := range testCases {
+		create := testCase.create
+		expectedErr := testCase.expectedErr
+
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			path := socketPath(t)
+			create(t, path)
+
+			err := Serve(ctx, path, &fakeController{})
+
+			if expectedErr != "" {
+				assert.ErrorContains(t, err, expectedErr)
+				assert.FileExists(t, path)
+				return
+			}
+			require.NoError(t, err)
+			_, err = Send(context.Background(), path, "status")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSend_NotRunning(t *testing.T) {
+	_, err := Send(context.Background(), socketPath(t), CommandStatus)
+
+	assert.ErrorContains(t, err, "failed to reach the daemon")
+}
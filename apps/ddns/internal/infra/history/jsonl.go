@@ -0,0 +1,240 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+const (
+	defaultMaxSize  = 10 * 1024 * 1024
+	defaultMaxFiles = 5
+	// lines longer than this are skipped when reading the history
+	maxLineSize = 1024 * 1024
+)
+
+// JSONL is an append-only history stored as one JSON event per line. The
+// file is rotated to path.1, path.2... when it grows over maxSize, the
+// oldest rotated file is removed once there are maxFiles of them.
+type JSONL struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+}
+
+type event struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	FQDN       string    `json:"fqdn,omitempty"`
+	RecordType string    `json:"type,omitempty"`
+	OldValue   string    `json:"old,omitempty"`
+	NewValue   string    `json:"new,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	Zone       string    `json:"zone,omitempty"`
+	ChangeIDs  []string  `json:"change_ids,omitempty"`
+	Outcome    string    `json:"outcome,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+}
+
+func NewJSONL(path string, maxSizeMB, maxFiles int) (*JSONL, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create history dir: %w", err)
+	}
+
+	h := &JSONL{
+		path:     path,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		maxFiles: maxFiles,
+	}
+
+	if h.maxSize <= 0 {
+		h.maxSize = defaultMaxSize
+	}
+
+	if h.maxFiles <= 0 {
+		h.maxFiles = defaultMaxFiles
+	}
+
+	return h, nil
+}
+
+func (h *JSONL) Append(events ...domain.HistoryEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, e := range events {
+		line, err := json.Marshal(fromDomain(e))
+		if err != nil {
+			return fmt.Errorf("failed to encode history event: %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.rotate(int64(len(data))); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close() //nolint:errcheck,gosec
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	return file.Close()
+}
+
+// rotate moves the history aside when appending size bytes would make it
+// grow over the maximum size.
+func (h *JSONL) rotate(size int64) error {
+	info, err := os.Stat(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check history size: %w", err)
+	}
+
+	if info.Size() == 0 || info.Size()+size <= h.maxSize {
+		return nil
+	}
+
+	if err := os.Remove(h.rotated(h.maxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove old history: %w", err)
+	}
+
+	for i := h.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(h.rotated(i), h.rotated(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate history: %w", err)
+		}
+	}
+
+	if err := os.Rename(h.path, h.rotated(1)); err != nil {
+		return fmt.Errorf("failed to rotate history: %w", err)
+	}
+
+	return nil
+}
+
+// Query returns the events matching filter, oldest first.
+func (h *JSONL) Query(filter domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make([]domain.HistoryEvent, 0)
+	for i := h.maxFiles; i >= 0; i-- {
+		path := h.path
+		if i > 0 {
+			path = h.rotated(i)
+		}
+
+		fileEvents, err := readEvents(path, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, fileEvents...)
+	}
+
+	return events, nil
+}
+
+func (h *JSONL) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", h.path, i)
+}
+
+func readEvents(path string, filter domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	file, err := os.Open(path) //nolint:gosec // path comes from the config
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	events := make([]domain.HistoryEvent, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for scanner.Scan() {
+		var e event
+		// a partially written line must not hide the rest of the history
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		if !filter.Since.IsZero() && e.Time.Before(filter.Since) {
+			continue
+		}
+
+		if filter.FQDN != "" && !sameName(e.FQDN, filter.FQDN) {
+			continue
+		}
+
+		events = append(events, e.toDomain())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+
+	return events, nil
+}
+
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+func fromDomain(e domain.HistoryEvent) event {
+	return event{
+		Time:       e.Time,
+		Kind:       e.Kind,
+		FQDN:       e.FQDN,
+		RecordType: e.RecordType,
+		OldValue:   e.OldValue,
+		NewValue:   e.NewValue,
+		Provider:   e.Provider,
+		Zone:       e.Zone,
+		ChangeIDs:  e.ChangeIDs,
+		Outcome:    e.Outcome,
+		Error:      e.Error,
+		DurationMS: e.Duration.Milliseconds(),
+	}
+}
+
+func (e event) toDomain() domain.HistoryEvent {
+	return domain.HistoryEvent{
+		Time:       e.Time,
+		Kind:       e.Kind,
+		FQDN:       e.FQDN,
+		RecordType: e.RecordType,
+		OldValue:   e.OldValue,
+		NewValue:   e.NewValue,
+		Provider:   e.Provider,
+		Zone:       e.Zone,
+		ChangeIDs:  e.ChangeIDs,
+		Outcome:    e.Outcome,
+		Error:      e.Error,
+		Duration:   time.Duration(e.DurationMS) * time.Millisecond,
+	}
+}
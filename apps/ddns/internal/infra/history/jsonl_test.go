@@ -0,0 +1,134 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONL_Query(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	events := []domain.HistoryEvent{
+		{
+			Time:       start,
+			Kind:       domain.EventAddressChange,
+			RecordType: "A",
+			OldValue:   "192.0.2.1",
+			NewValue:   "192.0.2.10",
+		},
+		{
+			Time:       start.Add(time.Second),
+			Kind:       domain.EventUpdate,
+			FQDN:       "vpn.example.net.",
+			RecordType: "A",
+			OldValue:   "192.0.2.1",
+			NewValue:   "192.0.2.10",
+			Provider:   "route53",
+			Zone:       "Z123",
+			ChangeIDs:  []string{"C1"},
+			Outcome:    domain.OutcomeSuccess,
+			Duration:   250 * time.Millisecond,
+		},
+		{
+			Time:       start.Add(time.Hour),
+			Kind:       domain.EventUpdate,
+			FQDN:       "nas.example.net.",
+			RecordType: "A",
+			NewValue:   "192.0.2.10",
+			Provider:   "route53",
+			Zone:       "Z123",
+			Outcome:    domain.OutcomeFailed,
+			Error:      "throttled",
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		filter         domain.HistoryFilter
+		expectedEvents []domain.HistoryEvent
+	}{
+		{
+			name:           "all events",
+			expectedEvents: events,
+		},
+		{
+			name:           "events of a record",
+			filter:         domain.HistoryFilter{FQDN: "VPN.example.net"},
+			expectedEvents: events[1:2],
+		},
+		{
+			name:           "events since",
+			filter:         domain.HistoryFilter{Since: start.Add(time.Minute)},
+			expectedEvents: events[2:],
+		},
+	}
+
+	h, err := NewJSONL(filepath.Join(t.TempDir(), "ddns", "history.jsonl"), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, h.Append(events[:2]...))
+	require.NoError(t, h.Append(events[2]))
+
+	for _, testCase := range testCases {
+		filter := testCase.filter
+		expectedEvents := testCase.expectedEvents
+
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := h.Query(filter)
+
+			require.NoError(t, err)
+			assert.Equal(t, expectedEvents, got)
+		})
+	}
+}
+
+func TestJSONL_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h, err := NewJSONL(path, 0, 2)
+	require.NoError(t, err)
+	h.maxSize = 200
+
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		require.NoError(t, h.Append(domain.HistoryEvent{
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			Kind:     domain.EventUpdate,
+			FQDN:     "vpn.example.net.",
+			NewValue: "192.0.2.10",
+		}))
+	}
+
+	_, err = os.Stat(path + ".2")
+	require.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	got, err := h.Query(domain.HistoryFilter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, got)
+	assert.Less(t, len(got), 10, "the oldest events are removed")
+	assert.Equal(t, start.Add(9*time.Minute), got[len(got)-1].Time)
+	for i := 1; i < len(got); i++ {
+		assert.True(t, got[i-1].Time.Before(got[i].Time), "events are returned oldest first")
+	}
+}
+
+func TestJSONL_QuerySkipsPartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `{"time":"2026-10-19T12:00:00Z","kind":"update","fqdn":"vpn.example.net."}` + "\n" + `{"time":"2026-10-19T12:01`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	h, err := NewJSONL(path, 0, 0)
+	require.NoError(t, err)
+
+	got, err := h.Query(domain.HistoryFilter{})
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.HistoryEvent{
+		{Time: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), Kind: domain.EventUpdate, FQDN: "vpn.example.net."},
+	}, got)
+}
@@ -0,0 +1,69 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+)
+
+// maxOutput is how much of the output of a failed command is kept in the
+// error.
+const maxOutput = 512
+
+// command runs a program, without a shell, with the change in its
+// environment.
+type command struct {
+	args []string
+}
+
+func (c *command) run(ctx context.Context, change domain.AddressChange) error {
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...) //nolint:gosec // the command comes from the config
+	cmd.Env = append(os.Environ(), environment(change)...)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if output.Len() > 0 {
+		logging.FromContext(ctx).Debugf("%s: %s", c.args[0], strings.TrimSpace(output.String()))
+	}
+
+	if err != nil {
+		detail := strings.TrimSpace(output.String())
+		if len(detail) > maxOutput {
+			detail = "..." + detail[len(detail)-maxOutput:]
+		}
+		if detail != "" {
+			return fmt.Errorf("%s: %w: %s", c.args[0], err, detail)
+		}
+		return fmt.Errorf("%s: %w", c.args[0], err)
+	}
+
+	return nil
+}
+
+// environment describes the change to the commands.
+func environment(change domain.AddressChange) []string {
+	recordType := "A"
+	if change.Family == logging.FamilyIPv6 {
+		recordType = "AAAA"
+	}
+
+	return []string{
+		"DDNS_FAMILY=" + change.Family,
+		"DDNS_RECORD_TYPE=" + recordType,
+		"DDNS_OLD_IP=" + change.OldIP,
+		"DDNS_NEW_IP=" + change.NewIP,
+		"DDNS_IPV4=" + change.IPv4,
+		"DDNS_IPV6=" + change.IPv6,
+		"DDNS_CHANGED_AT=" + change.Time.UTC().Format(time.RFC3339),
+	}
+}
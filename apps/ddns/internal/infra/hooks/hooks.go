@@ -0,0 +1,113 @@
+// Package hooks runs the actions configured for the changes of the public
+// addresses: commands and rewrites of AWS security group rules.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/tracing"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const defaultTimeout = time.Minute
+
+// action is what a hook does with a change.
+type action interface {
+	run(ctx context.Context, change domain.AddressChange) error
+}
+
+type hook struct {
+	name     string
+	families map[string]bool
+	timeout  time.Duration
+	action   action
+}
+
+// Runner runs the hooks of the family of every change, one after the other.
+type Runner struct {
+	hooks []*hook
+}
+
+func New(ctx context.Context, hooksConfig []config.HookConfig, accounts []config.AWSConfig) (*Runner, error) {
+	runner := &Runner{}
+
+	for i, hookConfig := range hooksConfig {
+		name := hookConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", hookConfig.Type, i)
+		}
+
+		a, err := newAction(ctx, hookConfig, accounts)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %w", name, err)
+		}
+
+		h := &hook{name: name, timeout: defaultTimeout, action: a}
+		if hookConfig.TimeoutSeconds > 0 {
+			h.timeout = time.Duration(hookConfig.TimeoutSeconds) * time.Second
+		}
+
+		if len(hookConfig.Families) > 0 {
+			h.families = make(map[string]bool, len(hookConfig.Families))
+			for _, family := range hookConfig.Families {
+				h.families[family] = true
+			}
+		}
+
+		runner.hooks = append(runner.hooks, h)
+	}
+
+	return runner, nil
+}
+
+func newAction(ctx context.Context, hookConfig config.HookConfig, accounts []config.AWSConfig) (action, error) {
+	switch hookConfig.Type {
+	case "command":
+		return &command{args: hookConfig.Command}, nil
+	case "security-group":
+		return newSecurityGroup(ctx, hookConfig, accounts)
+	}
+
+	return nil, fmt.Errorf("unknown hook type %q", hookConfig.Type)
+}
+
+// Run runs the hooks wanting the family of change. A failed hook does not
+// stop the next ones.
+func (r *Runner) Run(ctx context.Context, change domain.AddressChange) error {
+	var errs error
+
+	for _, h := range r.hooks {
+		if h.families != nil && !h.families[change.Family] {
+			continue
+		}
+
+		if err := h.run(ctx, change); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("hook %s: %w", h.name, err))
+		}
+	}
+
+	return errs
+}
+
+func (h *hook) run(ctx context.Context, change domain.AddressChange) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "hook."+h.name, attribute.String("ddns.family", change.Family))
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	err = h.action.run(ctx, change)
+	if err == nil {
+		logging.FromContext(ctx).WithField(logging.FieldFamily, change.Family).Infof("hook %s done in %s", h.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	return err
+}
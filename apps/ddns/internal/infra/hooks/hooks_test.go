@@ -0,0 +1,101 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+type fakeAction struct {
+	err     error
+	changes []domain.AddressChange
+}
+
+func (f *fakeAction) run(_ context.Context, change domain.AddressChange) error {
+	f.changes = append(f.changes, change)
+	return f.err
+}
+
+var ipv4Change = domain.AddressChange{
+	Time:   time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+	Family: "ipv4",
+	OldIP:  "192.0.2.1",
+	NewIP:  "192.0.2.10",
+	IPv4:   "192.0.2.10",
+	IPv6:   "2001:db8::1",
+}
+
+func TestRunner_Run(t *testing.T) {
+	both := &fakeAction{}
+	ipv6Only := &fakeAction{}
+	failing := &fakeAction{err: errors.New("unreachable")}
+	runner := &Runner{hooks: []*hook{
+		{name: "failing", timeout: time.Second, action: failing},
+		{name: "both", timeout: time.Second, action: both},
+		{name: "ipv6", timeout: time.Second, families: map[string]bool{"ipv6": true}, action: ipv6Only},
+	}}
+
+	err := runner.Run(context.Background(), ipv4Change)
+
+	assert.EqualError(t, err, "hook failing: unreachable")
+	assert.Equal(t, []domain.AddressChange{ipv4Change}, both.changes, "a failed hook does not stop the next ones")
+	assert.Empty(t, ipv6Only.changes)
+}
+
+func TestNew(t *testing.T) {
+	runner, err := New(context.Background(), []config.HookConfig{
+		{Type: "command", Command: []string{"true"}, Families: []string{"ipv4"}},
+		{Name: "slow", Type: "command", Command: []string{"true"}, TimeoutSeconds: 5},
+	}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, runner.hooks, 2)
+	assert.Equal(t, "command-0", runner.hooks[0].name)
+	assert.Equal(t, map[string]bool{"ipv4": true}, runner.hooks[0].families)
+	assert.Equal(t, defaultTimeout, runner.hooks[0].timeout)
+	assert.Equal(t, "slow", runner.hooks[1].name)
+	assert.Equal(t, 5*time.Second, runner.hooks[1].timeout)
+
+	_, err = New(context.Background(), []config.HookConfig{
+		{Name: "office", Type: "security-group", Account: "missing", GroupID: "sg-1"},
+	}, []config.AWSConfig{{AccountName: "example", Region: "us-east-1"}})
+	assert.EqualError(t, err, `hook office: unknown aws account "missing"`)
+}
+
+func TestCommand_run(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	c := &command{args: []string{"sh", "-c", `echo "$DDNS_FAMILY $DDNS_RECORD_TYPE $DDNS_OLD_IP $DDNS_NEW_IP $DDNS_IPV6 $DDNS_CHANGED_AT" > "$0"`, out}}
+
+	require.NoError(t, c.run(context.Background(), ipv4Change))
+
+	env, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "ipv4 A 192.0.2.1 192.0.2.10 2001:db8::1 2026-05-01T10:00:00Z\n", string(env))
+}
+
+func TestCommand_runFailed(t *testing.T) {
+	c := &command{args: []string{"sh", "-c", "echo no route to peer >&2; exit 3"}}
+
+	err := c.run(context.Background(), ipv4Change)
+
+	assert.EqualError(t, err, "sh: exit status 3: no route to peer")
+}
+
+func TestHook_runTimeout(t *testing.T) {
+	h := &hook{name: "slow", timeout: 50 * time.Millisecond, action: &command{args: []string{"sleep", "5"}}}
+
+	start := time.Now()
+	err := h.run(context.Background(), ipv4Change)
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,35 @@
+package hooks
+
+import (
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/awsutil"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const securityGroupARN = "arn:aws:ec2:*:*:security-group/"
+
+// IAMStatement is the permission needed by the security-group hooks using
+// account, nil when none does.
+func IAMStatement(account string, hooksConfig []config.HookConfig) *awsutil.Statement {
+	groups := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, hookConfig := range hooksConfig {
+		if hookConfig.Type != "security-group" || hookConfig.Account != account || seen[hookConfig.GroupID] {
+			continue
+		}
+
+		seen[hookConfig.GroupID] = true
+		groups = append(groups, securityGroupARN+hookConfig.GroupID)
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	return &awsutil.Statement{
+		Sid:      "UpdateSecurityGroupRules",
+		Effect:   "Allow",
+		Action:   []string{"ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress"},
+		Resource: groups,
+	}
+}
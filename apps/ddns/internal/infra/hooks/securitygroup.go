@@ -0,0 +1,159 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/awsutil"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const (
+	defaultProtocol = "tcp"
+	// error codes of rules already in the group and of rules not in it.
+	errDuplicateRule = "InvalidPermission.Duplicate"
+	errRuleNotFound  = "InvalidPermission.NotFound"
+)
+
+type ingressClient interface {
+	AuthorizeSecurityGroupIngress(ctx context.Context, params *ec2.AuthorizeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error)
+	RevokeSecurityGroupIngress(ctx context.Context, params *ec2.RevokeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error)
+}
+
+// securityGroup moves an ingress rule of a security group from the old
+// address to the new one. The new rule is added before the old one is
+// removed, so the address never loses access.
+type securityGroup struct {
+	client      ingressClient
+	groupID     string
+	protocol    string
+	fromPort    int32
+	toPort      int32
+	description string
+}
+
+func newSecurityGroup(ctx context.Context, hookConfig config.HookConfig, accounts []config.AWSConfig) (*securityGroup, error) {
+	var account *config.AWSConfig
+	for i := range accounts {
+		if accounts[i].AccountName == hookConfig.Account {
+			account = &accounts[i]
+		}
+	}
+
+	if account == nil {
+		return nil, fmt.Errorf("unknown aws account %q", hookConfig.Account)
+	}
+
+	awsConfig, err := awsutil.LoadConfig(ctx, *account)
+	if err != nil {
+		return nil, err
+	}
+
+	client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) {
+		if hookConfig.Region != "" {
+			o.Region = hookConfig.Region
+		}
+	})
+
+	return newSecurityGroupRule(client, hookConfig), nil
+}
+
+func newSecurityGroupRule(client ingressClient, hookConfig config.HookConfig) *securityGroup {
+	sg := &securityGroup{
+		client:      client,
+		groupID:     hookConfig.GroupID,
+		protocol:    hookConfig.Protocol,
+		fromPort:    int32(hookConfig.FromPort), //nolint:gosec // validated to be a port
+		toPort:      int32(hookConfig.ToPort),   //nolint:gosec // validated to be a port
+		description: hookConfig.Description,
+	}
+
+	if sg.protocol == "" {
+		sg.protocol = defaultProtocol
+	}
+
+	if sg.toPort == 0 {
+		sg.toPort = sg.fromPort
+	}
+
+	return sg
+}
+
+func (sg *securityGroup) run(ctx context.Context, change domain.AddressChange) error {
+	permission, err := sg.permission(change.NewIP)
+	if err != nil {
+		return err
+	}
+
+	_, err = sg.client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       aws.String(sg.groupID),
+		IpPermissions: []types.IpPermission{permission},
+	})
+	if err != nil && !isAPIError(err, errDuplicateRule) {
+		return fmt.Errorf("failed to allow %s in %s: %w", change.NewIP, sg.groupID, err)
+	}
+
+	if change.OldIP == "" {
+		return nil
+	}
+
+	if permission, err = sg.permission(change.OldIP); err != nil {
+		return err
+	}
+
+	_, err = sg.client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       aws.String(sg.groupID),
+		IpPermissions: []types.IpPermission{permission},
+	})
+	if isAPIError(err, errRuleNotFound) {
+		logging.FromContext(ctx).Debugf("no rule of %s in %s to remove", change.OldIP, sg.groupID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from %s: %w", change.OldIP, sg.groupID, err)
+	}
+
+	return nil
+}
+
+// permission is the rule of a single address.
+func (sg *securityGroup) permission(ip string) (types.IpPermission, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return types.IpPermission{}, fmt.Errorf("invalid address %q: %w", ip, err)
+	}
+
+	permission := types.IpPermission{IpProtocol: aws.String(sg.protocol)}
+	if sg.protocol != "-1" {
+		permission.FromPort = aws.Int32(sg.fromPort)
+		permission.ToPort = aws.Int32(sg.toPort)
+	}
+
+	var description *string
+	if sg.description != "" {
+		description = aws.String(sg.description)
+	}
+
+	prefix := netip.PrefixFrom(addr, addr.BitLen()).String()
+	if addr.Is4() {
+		permission.IpRanges = []types.IpRange{{CidrIp: aws.String(prefix), Description: description}}
+	} else {
+		permission.Ipv6Ranges = []types.Ipv6Range{{CidrIpv6: aws.String(prefix), Description: description}}
+	}
+
+	return permission, nil
+}
+
+func isAPIError(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
@@ -0,0 +1,150 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+type fakeIngress struct {
+	authorizeErr error
+	revokeErr    error
+	calls        []string
+	authorized   []types.IpPermission
+	revoked      []types.IpPermission
+}
+
+func (f *fakeIngress) AuthorizeSecurityGroupIngress(_ context.Context, params *ec2.AuthorizeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.calls = append(f.calls, "authorize "+aws.ToString(params.GroupId))
+	f.authorized = append(f.authorized, params.IpPermissions...)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, f.authorizeErr
+}
+
+func (f *fakeIngress) RevokeSecurityGroupIngress(_ context.Context, params *ec2.RevokeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	f.calls = append(f.calls, "revoke "+aws.ToString(params.GroupId))
+	f.revoked = append(f.revoked, params.IpPermissions...)
+	return &ec2.RevokeSecurityGroupIngressOutput{}, f.revokeErr
+}
+
+func TestSecurityGroup_run(t *testing.T) {
+	testCases := []struct {
+		name              string
+		hookConfig        config.HookConfig
+		change            domain.AddressChange
+		authorizeErr      error
+		revokeErr         error
+		expectedCalls     []string
+		expectedAuthorize []types.IpPermission
+		expectedRevoke    []types.IpPermission
+		expectedError     string
+	}{
+		{
+			name:          "ipv4 rule moved",
+			hookConfig:    config.HookConfig{GroupID: "sg-1", FromPort: 22, Description: "home"},
+			change:        ipv4Change,
+			expectedCalls: []string{"authorize sg-1", "revoke sg-1"},
+			expectedAuthorize: []types.IpPermission{{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int32(22),
+				ToPort:     aws.Int32(22),
+				IpRanges:   []types.IpRange{{CidrIp: aws.String("192.0.2.10/32"), Description: aws.String("home")}},
+			}},
+			expectedRevoke: []types.IpPermission{{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int32(22),
+				ToPort:     aws.Int32(22),
+				IpRanges:   []types.IpRange{{CidrIp: aws.String("192.0.2.1/32"), Description: aws.String("home")}},
+			}},
+		},
+		{
+			name:          "ipv6 all traffic",
+			hookConfig:    config.HookConfig{GroupID: "sg-1", Protocol: "-1"},
+			change:        domain.AddressChange{Family: "ipv6", OldIP: "2001:db8::1", NewIP: "2001:db8::10"},
+			expectedCalls: []string{"authorize sg-1", "revoke sg-1"},
+			expectedAuthorize: []types.IpPermission{{
+				IpProtocol: aws.String("-1"),
+				Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: aws.String("2001:db8::10/128")}},
+			}},
+			expectedRevoke: []types.IpPermission{{
+				IpProtocol: aws.String("-1"),
+				Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: aws.String("2001:db8::1/128")}},
+			}},
+		},
+		{
+			name:          "existing rules are fine",
+			hookConfig:    config.HookConfig{GroupID: "sg-1", Protocol: "udp", FromPort: 51820},
+			change:        ipv4Change,
+			authorizeErr:  &smithy.GenericAPIError{Code: errDuplicateRule},
+			revokeErr:     &smithy.GenericAPIError{Code: errRuleNotFound},
+			expectedCalls: []string{"authorize sg-1", "revoke sg-1"},
+		},
+		{
+			name:          "old rule kept when the new one fails",
+			hookConfig:    config.HookConfig{GroupID: "sg-1", FromPort: 443},
+			change:        ipv4Change,
+			authorizeErr:  &smithy.GenericAPIError{Code: "RulesPerSecurityGroupLimitExceeded", Message: "too many rules"},
+			expectedCalls: []string{"authorize sg-1"},
+			expectedError: "failed to allow 192.0.2.10 in sg-1: api error RulesPerSecurityGroupLimitExceeded: too many rules",
+		},
+		{
+			name:          "failed revoke",
+			hookConfig:    config.HookConfig{GroupID: "sg-1", FromPort: 443},
+			change:        ipv4Change,
+			revokeErr:     errors.New("throttled"),
+			expectedCalls: []string{"authorize sg-1", "revoke sg-1"},
+			expectedError: "failed to remove 192.0.2.1 from sg-1: throttled",
+		},
+	}
+
+	for _, testCase := range testCases {
+		hookConfig := testCase.hookConfig
+		change := testCase.change
+		fake := &fakeIngress{authorizeErr: testCase.authorizeErr, revokeErr: testCase.revokeErr}
+		expectedCalls := testCase.expectedCalls
+		expectedAuthorize := testCase.expectedAuthorize
+		expectedRevoke := testCase.expectedRevoke
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			err := newSecurityGroupRule(fake, hookConfig).run(context.Background(), change)
+
+			if expectedError != "" {
+				require.EqualError(t, err, expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, expectedCalls, fake.calls)
+			if expectedAuthorize != nil {
+				assert.Equal(t, expectedAuthorize, fake.authorized)
+				assert.Equal(t, expectedRevoke, fake.revoked)
+			}
+		})
+	}
+}
+
+func TestIAMStatement(t *testing.T) {
+	hooksConfig := []config.HookConfig{
+		{Type: "command", Command: []string{"true"}},
+		{Type: "security-group", Account: "example", GroupID: "sg-1"},
+		{Type: "security-group", Account: "other", GroupID: "sg-2"},
+		{Type: "security-group", Account: "example", GroupID: "sg-1", Protocol: "udp"},
+		{Type: "security-group", Account: "example", GroupID: "sg-3"},
+	}
+
+	statement := IAMStatement("example", hooksConfig)
+
+	require.NotNil(t, statement)
+	assert.Equal(t, []string{"ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress"}, statement.Action)
+	assert.Equal(t, []string{"arn:aws:ec2:*:*:security-group/sg-1", "arn:aws:ec2:*:*:security-group/sg-3"}, statement.Resource)
+	assert.Nil(t, IAMStatement("unused", hooksConfig))
+}
@@ -4,6 +4,11 @@ import (
 	"context"
 
 	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -24,9 +29,32 @@ func NewIPGetter() domain.IPGetter {
 }
 
 func (g *getter) GetIPV4(ctx context.Context) (string, error) {
-	return g.ipify.publicIP(ctx, ipTypeIPv4)
+	return g.publicIP(ctx, ipTypeIPv4)
 }
 
 func (g *getter) GetIPV6(ctx context.Context) (string, error) {
-	return g.ipify.publicIP(ctx, ipTypeIPv6)
+	return g.publicIP(ctx, ipTypeIPv6)
+}
+
+func (g *getter) publicIP(ctx context.Context, iType ipType) (string, error) {
+	family := logging.FamilyIPv4
+	if iType == ipTypeIPv6 {
+		family = logging.FamilyIPv6
+	}
+
+	ctx, span := tracing.Start(ctx, "ipgetter.PublicIP",
+		attribute.String("ddns.source", "ipify"),
+		attribute.String("ddns.family", family),
+	)
+	ip, err := g.ipify.publicIP(ctx, iType)
+	tracing.End(span, err)
+	metrics.IPLookups.WithLabelValues("ipify", family, metrics.Result(err)).Inc()
+	if err == nil {
+		logging.FromContext(ctx).WithFields(log.Fields{
+			logging.FieldSource: "ipify",
+			logging.FieldFamily: family,
+		}).Debugf("public address is %s", ip)
+	}
+
+	return ip, err
 }
@@ -0,0 +1,66 @@
+// Package logging carries log fields in contexts, so every log of a cycle,
+// whatever package writes it, has the fields of the cycle, provider, account
+// and zone it belongs to.
+package logging
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the fields attached to the logs.
+const (
+	FieldCycle    = "cycle"
+	FieldProvider = "provider"
+	FieldAccount  = "account"
+	FieldZone     = "zone"
+	FieldFQDN     = "fqdn"
+	FieldFamily   = "family"
+	FieldSource   = "source"
+)
+
+// the values of FieldFamily.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a context whose logs have fields besides the ones
+// already in ctx.
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	current, _ := ctx.Value(fieldsKey{}).(log.Fields)
+
+	merged := make(log.Fields, len(current)+len(fields))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns an entry of the standard logger with the fields of
+// ctx.
+func FromContext(ctx context.Context) *log.Entry {
+	fields, _ := ctx.Value(fieldsKey{}).(log.Fields)
+
+	return log.WithContext(ctx).WithFields(fields)
+}
+
+// Family returns the address family of an address record type, empty for
+// other types.
+func Family(recordType string) string {
+	switch recordType {
+	case "A":
+		return FamilyIPv4
+	case "AAAA":
+		return FamilyIPv6
+	}
+
+	return ""
+}
@@ -0,0 +1,18 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFields(t *testing.T) {
+	ctx := WithFields(context.Background(), log.Fields{FieldCycle: "c1", FieldZone: "Z123"})
+	zoneCtx := WithFields(ctx, log.Fields{FieldZone: "Z456", FieldFQDN: "vpn.example.net."})
+
+	assert.Equal(t, log.Fields{FieldCycle: "c1", FieldZone: "Z123"}, FromContext(ctx).Data)
+	assert.Equal(t, log.Fields{FieldCycle: "c1", FieldZone: "Z456", FieldFQDN: "vpn.example.net."}, FromContext(zoneCtx).Data)
+	assert.Empty(t, FromContext(context.Background()).Data)
+}
@@ -0,0 +1,99 @@
+// Package metrics holds the Prometheus metrics of ddns and the handler
+// exposing them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ddns"
+
+// the values of the outcome and result labels.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+	OutcomePartial = "partial"
+	OutcomeSkipped = "skipped"
+)
+
+// Registry holds the metrics of ddns besides the Go runtime and process
+// ones.
+var Registry = prometheus.NewRegistry()
+
+var (
+	Cycles = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cycles_total",
+		Help:      "Update cycles run, by outcome.",
+	}, []string{"outcome"})
+
+	IPLookups = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ip_lookups_total",
+		Help:      "Address lookups, by source, address family and result.",
+	}, []string{"source", "family", "result"})
+
+	IPChanges = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ip_changes_total",
+		Help:      "Changes of the detected public address, by address family.",
+	}, []string{"family"})
+
+	ProviderRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Provider API calls, by provider, operation, zone and outcome.",
+	}, []string{"provider", "operation", "zone", "outcome"})
+
+	UpdateDuration = promauto.With(Registry).NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "update_duration_seconds",
+		Help:      "Time taken to send the changed records to the providers.",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 8),
+	})
+
+	RecordLastSuccess = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "record_last_success_timestamp_seconds",
+		Help:      "When a record was last updated successfully.",
+	}, []string{"fqdn", "type"})
+
+	PublishedIP = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "published_ip_info",
+		Help:      "Value currently published for a record, always 1.",
+	}, []string{"fqdn", "type", "value"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Published sets the value published for a record, replacing the previous
+// one.
+func Published(fqdn, recordType, value string) {
+	PublishedIP.DeletePartialMatch(prometheus.Labels{"fqdn": fqdn, "type": recordType})
+	PublishedIP.WithLabelValues(fqdn, recordType, value).Set(1)
+}
+
+// Result returns the result label of an error.
+func Result(err error) string {
+	if err != nil {
+		return OutcomeFailed
+	}
+
+	return OutcomeSuccess
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
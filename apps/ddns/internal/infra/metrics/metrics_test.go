@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublished(t *testing.T) {
+	Published("vpn.example.net.", "A", "192.0.2.1")
+	Published("vpn.example.net.", "A", "192.0.2.10")
+	Published("vpn.example.net.", "AAAA", "2001:db8::1")
+
+	assert.Equal(t, 2, testutil.CollectAndCount(PublishedIP))
+	assert.InDelta(t, 1, testutil.ToFloat64(PublishedIP.WithLabelValues("vpn.example.net.", "A", "192.0.2.10")), 0)
+}
+
+func TestHandler(t *testing.T) {
+	Cycles.WithLabelValues(OutcomeSuccess).Inc()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `ddns_cycles_total{outcome="success"}`)
+	assert.Contains(t, recorder.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,76 @@
+package mqtt
+
+import (
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const (
+	// qos 1 so the retained status is not lost on a flaky connection.
+	qos                  = 1
+	connectRetryInterval = 10 * time.Second
+	publishTimeout       = 10 * time.Second
+	// disconnectQuiesce lets the offline status reach the broker.
+	disconnectQuiesce = 250
+)
+
+// pahoClient publishes retained messages with the Eclipse Paho client,
+// reconnecting on its own. The broker marks ddns offline when the
+// connection is lost.
+type pahoClient struct {
+	client paho.Client
+}
+
+func newPahoClient(mqttConfig config.MQTTConfig, clientID, availabilityTopic string, onConnect func()) *pahoClient {
+	options := paho.NewClientOptions().
+		AddBroker(mqttConfig.Broker).
+		SetClientID(clientID).
+		SetUsername(mqttConfig.Username).
+		SetPassword(mqttConfig.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(connectRetryInterval).
+		SetWill(availabilityTopic, offline, qos, true).
+		SetOnConnectHandler(func(paho.Client) { onConnect() }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Warnf("lost the connection to the MQTT broker, reconnecting: %v", err)
+		})
+
+	return &pahoClient{client: paho.NewClient(options)}
+}
+
+// connect returns right away, the client keeps trying in the background.
+func (c *pahoClient) connect() {
+	c.client.Connect()
+}
+
+func (c *pahoClient) publish(topic string, payload []byte) {
+	token := c.client.Publish(topic, qos, true, payload)
+	go wait(token, "failed to publish to "+topic)
+}
+
+func (c *pahoClient) subscribe(topic string, handler func(payload []byte)) {
+	token := c.client.Subscribe(topic, qos, func(_ paho.Client, msg paho.Message) {
+		handler(msg.Payload())
+	})
+	go wait(token, "failed to subscribe to "+topic)
+}
+
+func (c *pahoClient) disconnect() {
+	c.client.Disconnect(disconnectQuiesce)
+}
+
+func wait(token paho.Token, message string) {
+	if !token.WaitTimeout(publishTimeout) {
+		log.Warnf("%s: timed out", message)
+		return
+	}
+
+	if err := token.Error(); err != nil {
+		log.Warnf("%s: %v", message, err)
+	}
+}
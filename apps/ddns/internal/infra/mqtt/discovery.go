@@ -0,0 +1,88 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// device groups the entities of a ddns instance in Home Assistant.
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Model        string   `json:"model"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+// entity is the Home Assistant MQTT discovery payload of a sensor, binary
+// sensor or button.
+type entity struct {
+	Name                string `json:"name"`
+	UniqueID            string `json:"unique_id"`
+	StateTopic          string `json:"state_topic,omitempty"`
+	CommandTopic        string `json:"command_topic,omitempty"`
+	PayloadPress        string `json:"payload_press,omitempty"`
+	AvailabilityTopic   string `json:"availability_topic"`
+	ValueTemplate       string `json:"value_template,omitempty"`
+	JSONAttributesTopic string `json:"json_attributes_topic,omitempty"`
+	DeviceClass         string `json:"device_class,omitempty"`
+	EntityCategory      string `json:"entity_category,omitempty"`
+	Icon                string `json:"icon,omitempty"`
+	Device              device `json:"device"`
+}
+
+// announce publishes the discovery payloads not published since the client
+// connected, the records appear as they are read from the providers.
+func (p *Publisher) announce() {
+	components := map[string]string{"problem": "binary_sensor", "update": "button"}
+	entities := map[string]entity{
+		"ipv4":        {Name: "Public IPv4", StateTopic: p.topic("ipv4"), Icon: "mdi:ip-network"},
+		"ipv6":        {Name: "Public IPv6", StateTopic: p.topic("ipv6"), Icon: "mdi:ip-network"},
+		"last_update": {Name: "Last update", StateTopic: p.topic("last_update"), DeviceClass: "timestamp"},
+		"problem":     {Name: "Problem", StateTopic: p.topic("problem"), DeviceClass: "problem"},
+		"error":       {Name: "Last error", StateTopic: p.topic("error"), EntityCategory: "diagnostic", Icon: "mdi:alert-circle"},
+		"update":      {Name: "Update now", CommandTopic: p.topic("command"), PayloadPress: commandUpdate, Icon: "mdi:refresh"},
+	}
+
+	for _, record := range p.status.Records {
+		fqdn := strings.TrimSuffix(record.FQDN, ".")
+		key := "record_" + fqdn + "_" + record.RecordType
+		if record.Zone != "" {
+			key = "record_" + record.Zone + "_" + fqdn + "_" + record.RecordType
+		}
+
+		entities[key] = entity{
+			Name:                fqdn + " " + record.RecordType,
+			StateTopic:          p.recordTopic(record),
+			ValueTemplate:       "{{ value_json.value }}",
+			JSONAttributesTopic: p.recordTopic(record),
+			Icon:                "mdi:dns",
+		}
+	}
+
+	for name, e := range entities {
+		objectID := unsafeID.ReplaceAllString(name, "_")
+		if p.announced[objectID] {
+			continue
+		}
+
+		component, ok := components[name]
+		if !ok {
+			component = "sensor"
+		}
+
+		e.UniqueID = p.nodeID + "_" + objectID
+		e.AvailabilityTopic = p.topic("status")
+		e.Device = device{Identifiers: []string{p.nodeID}, Name: "ddns", Model: "ddns", Manufacturer: "localenvironment"}
+
+		payload, err := json.Marshal(e)
+		if err != nil {
+			log.Errorf("failed to encode the discovery of %s: %v", name, err)
+			continue
+		}
+
+		p.client.publish(p.discoveryPrefix+"/"+component+"/"+p.nodeID+"/"+objectID+"/config", payload)
+		p.announced[objectID] = true
+	}
+}
@@ -0,0 +1,241 @@
+// Package mqtt publishes the status of ddns to an MQTT broker as retained
+// messages, announcing it to Home Assistant with MQTT discovery.
+package mqtt
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const (
+	defaultTopicPrefix     = "ddns"
+	defaultDiscoveryPrefix = "homeassistant"
+
+	online  = "online"
+	offline = "offline"
+
+	// commandUpdate on the command topic forces an update.
+	commandUpdate = "update"
+	// noError is the error of a cycle that succeeded, an empty retained
+	// message would delete the topic instead.
+	noError = "none"
+	// unknown is the address or update time not known yet, for the same
+	// reason.
+	unknown = "unknown"
+)
+
+var unsafeID = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// client is the part of the MQTT client used by the publisher, every message
+// is published retained.
+type client interface {
+	connect()
+	publish(topic string, payload []byte)
+	subscribe(topic string, handler func(payload []byte))
+	disconnect()
+}
+
+// Publisher keeps the retained topics of the broker up to date with the
+// status of ddns. The status and the discovery payloads are published again
+// every time the client connects.
+type Publisher struct {
+	prefix          string
+	discovery       bool
+	discoveryPrefix string
+	nodeID          string
+	client          client
+
+	mu        sync.Mutex
+	connected bool
+	force     func()
+	status    domain.Status
+	announced map[string]bool
+}
+
+func New(mqttConfig config.MQTTConfig) *Publisher {
+	clientID := mqttConfig.ClientID
+	if clientID == "" {
+		hostname, _ := os.Hostname()
+		clientID = "ddns-" + hostname
+	}
+
+	p := newPublisher(mqttConfig, clientID)
+	p.client = newPahoClient(mqttConfig, clientID, p.topic("status"), p.onConnect)
+
+	return p
+}
+
+func newPublisher(mqttConfig config.MQTTConfig, clientID string) *Publisher {
+	prefix := strings.TrimSuffix(mqttConfig.TopicPrefix, "/")
+	if prefix == "" {
+		prefix = defaultTopicPrefix
+	}
+
+	discoveryPrefix := strings.TrimSuffix(mqttConfig.DiscoveryPrefix, "/")
+	if discoveryPrefix == "" {
+		discoveryPrefix = defaultDiscoveryPrefix
+	}
+
+	return &Publisher{
+		prefix:          prefix,
+		discovery:       mqttConfig.Discovery,
+		discoveryPrefix: discoveryPrefix,
+		nodeID:          unsafeID.ReplaceAllString(clientID, "_"),
+		announced:       map[string]bool{},
+	}
+}
+
+// Start connects to the broker in the background, retrying until it is
+// reachable. force is called for every update requested on the command
+// topic.
+func (p *Publisher) Start(force func()) {
+	p.mu.Lock()
+	p.force = force
+	p.mu.Unlock()
+
+	p.client.connect()
+}
+
+// Publish replaces the status, it is sent right away when connected.
+func (p *Publisher) Publish(status domain.Status) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+	if p.connected {
+		p.publishStatus()
+	}
+}
+
+// Stop marks ddns offline and disconnects.
+func (p *Publisher) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.connected {
+		p.client.publish(p.topic("status"), []byte(offline))
+	}
+	p.connected = false
+	p.client.disconnect()
+}
+
+func (p *Publisher) onConnect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log.Infof("connected to the MQTT broker, publishing to %s", p.prefix)
+	p.connected = true
+	p.announced = map[string]bool{}
+
+	p.client.subscribe(p.topic("command"), p.onCommand)
+	p.client.publish(p.topic("status"), []byte(online))
+	p.publishStatus()
+}
+
+func (p *Publisher) onCommand(payload []byte) {
+	command := strings.ToLower(strings.TrimSpace(string(payload)))
+	if command != commandUpdate {
+		log.Warnf("unknown MQTT command %q, only %q is supported", command, commandUpdate)
+		return
+	}
+
+	p.mu.Lock()
+	force := p.force
+	p.mu.Unlock()
+
+	if force != nil {
+		log.Info("update requested through MQTT")
+		force()
+	}
+}
+
+func (p *Publisher) publishStatus() {
+	if p.discovery {
+		p.announce()
+	}
+
+	lastUpdate := unknown
+	if !p.status.LastUpdate.IsZero() {
+		lastUpdate = p.status.LastUpdate.Format(time.RFC3339)
+	}
+
+	problem, lastError := "OFF", noError
+	if p.status.Error != "" {
+		problem, lastError = "ON", p.status.Error
+	}
+
+	p.client.publish(p.topic("ipv4"), []byte(orUnknown(p.status.IPv4)))
+	p.client.publish(p.topic("ipv6"), []byte(orUnknown(p.status.IPv6)))
+	p.client.publish(p.topic("last_update"), []byte(lastUpdate))
+	p.client.publish(p.topic("problem"), []byte(problem))
+	p.client.publish(p.topic("error"), []byte(lastError))
+
+	for _, record := range p.status.Records {
+		payload, err := json.Marshal(newRecordPayload(record))
+		if err != nil {
+			log.Errorf("failed to encode the status of %s: %v", record.FQDN, err)
+			continue
+		}
+
+		p.client.publish(p.recordTopic(record), payload)
+	}
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return unknown
+	}
+
+	return value
+}
+
+func (p *Publisher) topic(name string) string {
+	return p.prefix + "/" + name
+}
+
+// recordTopic is records/<zone>/<fqdn>/<type>, the zone tells apart the
+// records of the same name in a public and a private zone.
+func (p *Publisher) recordTopic(record domain.RecordStatus) string {
+	name := strings.TrimSuffix(record.FQDN, ".") + "/" + record.RecordType
+	if record.Zone != "" {
+		name = record.Zone + "/" + name
+	}
+
+	return p.topic("records/" + name)
+}
+
+type recordPayload struct {
+	FQDN       string `json:"fqdn"`
+	RecordType string `json:"type"`
+	Zone       string `json:"zone,omitempty"`
+	Value      string `json:"value"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+}
+
+func newRecordPayload(record domain.RecordStatus) recordPayload {
+	payload := recordPayload{
+		FQDN:       record.FQDN,
+		RecordType: record.RecordType,
+		Zone:       record.Zone,
+		Value:      record.Value,
+		Status:     record.Status,
+		Error:      record.Error,
+	}
+
+	if !record.UpdatedAt.IsZero() {
+		payload.UpdatedAt = record.UpdatedAt.Format(time.RFC3339)
+	}
+
+	return payload
+}
@@ -0,0 +1,165 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+type fakeClient struct {
+	onConnect     func()
+	published     map[string]string
+	subscriptions map[string]func(payload []byte)
+	disconnected  bool
+}
+
+func (f *fakeClient) connect() {
+	f.onConnect()
+}
+
+func (f *fakeClient) publish(topic string, payload []byte) {
+	f.published[topic] = string(payload)
+}
+
+func (f *fakeClient) subscribe(topic string, handler func(payload []byte)) {
+	f.subscriptions[topic] = handler
+}
+
+func (f *fakeClient) disconnect() {
+	f.disconnected = true
+}
+
+func newTestPublisher(mqttConfig config.MQTTConfig) (*Publisher, *fakeClient) {
+	p := newPublisher(mqttConfig, "ddns-home.lan")
+	fake := &fakeClient{onConnect: p.onConnect, published: map[string]string{}, subscriptions: map[string]func([]byte){}}
+	p.client = fake
+
+	return p, fake
+}
+
+var status = domain.Status{
+	IPv4:       "192.0.2.10",
+	IPv6:       "2001:db8::10",
+	LastUpdate: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+	Records: []domain.RecordStatus{
+		{
+			FQDN:       "vpn.example.net.",
+			RecordType: "A",
+			Zone:       "Z123",
+			Value:      "192.0.2.10",
+			Status:     domain.RecordOK,
+			UpdatedAt:  time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			FQDN:       "vpn.example.net.",
+			RecordType: "A",
+			Zone:       "ZPRIVATE",
+			Value:      "10.0.0.10",
+			Status:     domain.RecordOK,
+			UpdatedAt:  time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	p, fake := newTestPublisher(config.MQTTConfig{TopicPrefix: "home/ddns/"})
+
+	p.Publish(status)
+	assert.Empty(t, fake.published, "nothing is published before connecting")
+
+	p.Start(func() {})
+
+	assert.Equal(t, map[string]string{
+		"home/ddns/status":                             "online",
+		"home/ddns/ipv4":                               "192.0.2.10",
+		"home/ddns/ipv6":                               "2001:db8::10",
+		"home/ddns/last_update":                        "2026-05-01T10:00:00Z",
+		"home/ddns/problem":                            "OFF",
+		"home/ddns/error":                              "none",
+		"home/ddns/records/Z123/vpn.example.net/A":     `{"fqdn":"vpn.example.net.","type":"A","zone":"Z123","value":"192.0.2.10","status":"ok","updated_at":"2026-05-01T10:00:00Z"}`,
+		"home/ddns/records/ZPRIVATE/vpn.example.net/A": `{"fqdn":"vpn.example.net.","type":"A","zone":"ZPRIVATE","value":"10.0.0.10","status":"ok","updated_at":"2026-05-01T10:00:00Z"}`,
+	}, fake.published)
+
+	failed := status
+	failed.Error = "throttled"
+	p.Publish(failed)
+
+	assert.Equal(t, "ON", fake.published["home/ddns/problem"])
+	assert.Equal(t, "throttled", fake.published["home/ddns/error"])
+
+	p.Publish(domain.Status{})
+
+	assert.Equal(t, "unknown", fake.published["home/ddns/ipv4"])
+	assert.Equal(t, "unknown", fake.published["home/ddns/ipv6"])
+	assert.Equal(t, "unknown", fake.published["home/ddns/last_update"])
+
+	p.Stop()
+
+	assert.Equal(t, "offline", fake.published["home/ddns/status"])
+	assert.True(t, fake.disconnected)
+}
+
+func TestPublisher_Discovery(t *testing.T) {
+	p, fake := newTestPublisher(config.MQTTConfig{Discovery: true})
+	p.Start(func() {})
+
+	for _, topic := range []string{
+		"homeassistant/sensor/ddns-home_lan/ipv4/config",
+		"homeassistant/sensor/ddns-home_lan/ipv6/config",
+		"homeassistant/sensor/ddns-home_lan/last_update/config",
+		"homeassistant/sensor/ddns-home_lan/error/config",
+		"homeassistant/binary_sensor/ddns-home_lan/problem/config",
+		"homeassistant/button/ddns-home_lan/update/config",
+	} {
+		assert.Contains(t, fake.published, topic)
+	}
+
+	recordTopic := "homeassistant/sensor/ddns-home_lan/record_Z123_vpn_example_net_A/config"
+	assert.NotContains(t, fake.published, recordTopic)
+
+	p.Publish(status)
+
+	require.Contains(t, fake.published, recordTopic)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(fake.published[recordTopic]), &record))
+	assert.Equal(t, map[string]any{
+		"name":                  "vpn.example.net A",
+		"unique_id":             "ddns-home_lan_record_Z123_vpn_example_net_A",
+		"state_topic":           "ddns/records/Z123/vpn.example.net/A",
+		"availability_topic":    "ddns/status",
+		"value_template":        "{{ value_json.value }}",
+		"json_attributes_topic": "ddns/records/Z123/vpn.example.net/A",
+		"icon":                  "mdi:dns",
+		"device": map[string]any{
+			"identifiers":  []any{"ddns-home_lan"},
+			"name":         "ddns",
+			"model":        "ddns",
+			"manufacturer": "localenvironment",
+		},
+	}, record)
+
+	var button map[string]any
+	require.NoError(t, json.Unmarshal([]byte(fake.published["homeassistant/button/ddns-home_lan/update/config"]), &button))
+	assert.Equal(t, "ddns/command", button["command_topic"])
+	assert.Equal(t, "update", button["payload_press"])
+}
+
+func TestPublisher_Command(t *testing.T) {
+	p, fake := newTestPublisher(config.MQTTConfig{})
+	forced := 0
+	p.Start(func() { forced++ })
+
+	command := fake.subscriptions["ddns/command"]
+	require.NotNil(t, command)
+
+	command([]byte(" Update\n"))
+	command([]byte("reboot"))
+
+	assert.Equal(t, 1, forced)
+}
@@ -0,0 +1,224 @@
+// Package notify sends the ddns events to webhooks, ntfy, Gotify, Slack
+// compatible webhooks, Matrix rooms and email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const requestTimeout = 10 * time.Second
+
+var titles = map[string]string{
+	domain.NotifyIPChanged:     "ddns: public address changed",
+	domain.NotifyRecordUpdated: "ddns: record updated",
+	domain.NotifyUpdateFailed:  "ddns: updates failing",
+	domain.NotifyRecovered:     "ddns: updates recovered",
+}
+
+var defaultTemplates = map[string]string{
+	domain.NotifyIPChanged:     "Public {{.Family}} address changed from {{.OldValue}} to {{.NewValue}}",
+	domain.NotifyRecordUpdated: "{{.FQDN}} {{.RecordType}} updated from {{.OldValue}} to {{.NewValue}} in {{.Provider}} zone {{.Zone}}",
+	domain.NotifyUpdateFailed:  "Updating the records failed {{.Failures}} times in a row: {{.Error}}",
+	domain.NotifyRecovered:     "Records updated again after {{.Failures}} failed cycles",
+}
+
+// message is a notification rendered for a notifier.
+type message struct {
+	Title        string
+	Text         string
+	Notification domain.Notification
+}
+
+// sink delivers messages to a service.
+type sink interface {
+	send(ctx context.Context, msg message) error
+}
+
+type notifier struct {
+	name      string
+	sink      sink
+	events    map[string]bool
+	templates map[string]*template.Template
+	limiter   *rateLimiter
+}
+
+// Dispatcher sends every notification to the notifiers configured for its
+// event.
+type Dispatcher struct {
+	notifiers []*notifier
+}
+
+func NewDispatcher(notificationsConfig config.NotificationsConfig) (*Dispatcher, error) {
+	return newDispatcher(notificationsConfig, &http.Client{Timeout: requestTimeout})
+}
+
+func newDispatcher(notificationsConfig config.NotificationsConfig, client *http.Client) (*Dispatcher, error) {
+	dispatcher := &Dispatcher{}
+
+	for i, notifierConfig := range notificationsConfig.Notifiers {
+		name := notifierConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", notifierConfig.Type, i)
+		}
+
+		s, err := newSink(notifierConfig, client)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+
+		templates, err := parseTemplates(name, notifierConfig.Templates)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+
+		n := &notifier{
+			name:      name,
+			sink:      s,
+			templates: templates,
+			limiter:   newRateLimiter(notifierConfig.RateLimitPerHour),
+		}
+
+		if len(notifierConfig.Events) > 0 {
+			n.events = make(map[string]bool, len(notifierConfig.Events))
+			for _, event := range notifierConfig.Events {
+				n.events[event] = true
+			}
+		}
+
+		dispatcher.notifiers = append(dispatcher.notifiers, n)
+	}
+
+	return dispatcher, nil
+}
+
+func newSink(notifierConfig config.NotifierConfig, client *http.Client) (sink, error) {
+	baseURL := strings.TrimSuffix(notifierConfig.URL, "/")
+
+	switch notifierConfig.Type {
+	case "webhook":
+		return &webhook{client: client, url: notifierConfig.URL}, nil
+	case "ntfy":
+		return &ntfy{client: client, url: baseURL + "/" + notifierConfig.Topic, token: notifierConfig.Token}, nil
+	case "gotify":
+		return &gotify{client: client, url: baseURL + "/message", token: notifierConfig.Token}, nil
+	case "slack":
+		return &slack{client: client, url: notifierConfig.URL}, nil
+	case "matrix":
+		return &matrix{client: client, url: baseURL, room: notifierConfig.Room, token: notifierConfig.Token}, nil
+	case "smtp":
+		return newSMTP(notifierConfig)
+	}
+
+	return nil, fmt.Errorf("unknown notifier type %q", notifierConfig.Type)
+}
+
+// parseTemplates returns the message templates of every event, the
+// configured ones replacing the defaults.
+func parseTemplates(name string, configured map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(defaultTemplates))
+
+	for event, text := range defaultTemplates {
+		if custom, ok := configured[event]; ok {
+			text = custom
+		}
+
+		tmpl, err := template.New(name + "-" + event).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", event, err)
+		}
+
+		templates[event] = tmpl
+	}
+
+	return templates, nil
+}
+
+// Notify sends notification to the notifiers wanting its event. Notifiers
+// over their rate limit skip it.
+func (d *Dispatcher) Notify(ctx context.Context, notification domain.Notification) error {
+	var errs error
+
+	for _, n := range d.notifiers {
+		if n.events != nil && !n.events[notification.Event] {
+			continue
+		}
+
+		if !n.limiter.allow(notification.Time) {
+			logging.FromContext(ctx).Warnf("notifier %s over its rate limit, %s notification dropped", n.name, notification.Event)
+			continue
+		}
+
+		msg, err := n.render(notification)
+		if err == nil {
+			err = n.sink.send(ctx, msg)
+		}
+
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("notifier %s: %w", n.name, err))
+		}
+	}
+
+	return errs
+}
+
+func (n *notifier) render(notification domain.Notification) (message, error) {
+	tmpl, ok := n.templates[notification.Event]
+	if !ok {
+		return message{}, fmt.Errorf("unknown event %q", notification.Event)
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, notification); err != nil {
+		return message{}, fmt.Errorf("failed to render %s message: %w", notification.Event, err)
+	}
+
+	return message{Title: titles[notification.Event], Text: text.String(), Notification: notification}, nil
+}
+
+// rateLimiter allows up to perHour notifications in any hour, all of them
+// when perHour is zero.
+type rateLimiter struct {
+	mu      sync.Mutex
+	perHour int
+	sent    []time.Time
+}
+
+func newRateLimiter(perHour int) *rateLimiter {
+	return &rateLimiter{perHour: perHour}
+}
+
+func (r *rateLimiter) allow(now time.Time) bool {
+	if r.perHour <= 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recent := r.sent[:0]
+	for _, sent := range r.sent {
+		if now.Sub(sent) < time.Hour {
+			recent = append(recent, sent)
+		}
+	}
+	r.sent = recent
+
+	if len(r.sent) >= r.perHour {
+		return false
+	}
+
+	r.sent = append(r.sent, now)
+	return true
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+type request struct {
+	method  string
+	path    string
+	headers http.Header
+	body    string
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	requests []request
+	status   int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request{method: req.Method, path: req.URL.EscapedPath(), headers: req.Header, body: string(body)})
+
+	if r.status != 0 {
+		http.Error(w, "unavailable", r.status)
+	}
+}
+
+var ipChanged = domain.Notification{
+	Event:    domain.NotifyIPChanged,
+	Time:     time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+	Family:   "ipv4",
+	OldValue: "192.0.2.1",
+	NewValue: "192.0.2.10",
+}
+
+func TestDispatcher_Sinks(t *testing.T) {
+	testCases := []struct {
+		name            string
+		notifier        config.NotifierConfig
+		expectedMethod  string
+		expectedPath    string
+		expectedHeaders map[string]string
+		expectedBody    string
+	}{
+		{
+			name:           "webhook",
+			notifier:       config.NotifierConfig{Type: "webhook"},
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/",
+			expectedBody: `{"event":"ip-changed","time":"2026-05-01T10:00:00Z","title":"ddns: public address changed",` +
+				`"message":"Public ipv4 address changed from 192.0.2.1 to 192.0.2.10","family":"ipv4","old":"192.0.2.1","new":"192.0.2.10"}`,
+		},
+		{
+			name:            "ntfy",
+			notifier:        config.NotifierConfig{Type: "ntfy", Topic: "home", Token: "tk_123"},
+			expectedMethod:  http.MethodPost,
+			expectedPath:    "/home",
+			expectedHeaders: map[string]string{"Title": "ddns: public address changed", "Authorization": "Bearer tk_123"},
+			expectedBody:    "Public ipv4 address changed from 192.0.2.1 to 192.0.2.10",
+		},
+		{
+			name:            "gotify",
+			notifier:        config.NotifierConfig{Type: "gotify", Token: "AppToken"},
+			expectedMethod:  http.MethodPost,
+			expectedPath:    "/message",
+			expectedHeaders: map[string]string{"X-Gotify-Key": "AppToken"},
+			expectedBody:    `{"message":"Public ipv4 address changed from 192.0.2.1 to 192.0.2.10","priority":5,"title":"ddns: public address changed"}`,
+		},
+		{
+			name:           "slack",
+			notifier:       config.NotifierConfig{Type: "slack"},
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/",
+			expectedBody:   `{"text":"*ddns: public address changed*\nPublic ipv4 address changed from 192.0.2.1 to 192.0.2.10"}`,
+		},
+		{
+			name:            "matrix",
+			notifier:        config.NotifierConfig{Type: "matrix", Room: "!room:example.net", Token: "syt_123"},
+			expectedMethod:  http.MethodPut,
+			expectedPath:    "/_matrix/client/v3/rooms/%21room:example.net/send/m.room.message/ddns-1777629600000000000-ip-changed-ipv4",
+			expectedHeaders: map[string]string{"Authorization": "Bearer syt_123"},
+			expectedBody:    `{"body":"ddns: public address changed: Public ipv4 address changed from 192.0.2.1 to 192.0.2.10","msgtype":"m.text"}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		notifier := testCase.notifier
+		expectedMethod := testCase.expectedMethod
+		expectedPath := testCase.expectedPath
+		expectedHeaders := testCase.expectedHeaders
+		expectedBody := testCase.expectedBody
+
+		t.Run(testCase.name, func(t *testing.T) {
+			rec := &recorder{}
+			server := httptest.NewServer(rec)
+			defer server.Close()
+
+			notifier.URL = server.URL + "/"
+			dispatcher, err := newDispatcher(config.NotificationsConfig{Notifiers: []config.NotifierConfig{notifier}}, server.Client())
+			require.NoError(t, err)
+
+			require.NoError(t, dispatcher.Notify(context.Background(), ipChanged))
+
+			require.Len(t, rec.requests, 1)
+			got := rec.requests[0]
+			assert.Equal(t, expectedMethod, got.method)
+			assert.Equal(t, expectedPath, got.path)
+			for key, value := range expectedHeaders {
+				assert.Equal(t, value, got.headers.Get(key), key)
+			}
+			if got.headers.Get("Content-Type") == "application/json" {
+				assert.JSONEq(t, expectedBody, got.body)
+			} else {
+				assert.Equal(t, expectedBody, got.body)
+			}
+		})
+	}
+}
+
+func TestMatrix_TransactionIDs(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	sink := &matrix{client: server.Client(), url: server.URL, room: "!room:example.net", token: "syt_123"}
+	updated := domain.Notification{Event: domain.NotifyRecordUpdated, Time: ipChanged.Time, Zone: "Z123", FQDN: "home.example.com.", RecordType: "A"}
+	for _, recordType := range []string{"A", "AAAA", "A"} {
+		updated.RecordType = recordType
+		require.NoError(t, sink.send(context.Background(), message{Notification: updated}))
+	}
+
+	require.Len(t, rec.requests, 3)
+	assert.Equal(t, "/_matrix/client/v3/rooms/%21room:example.net/send/m.room.message/ddns-1777629600000000000-record-updated-Z123-home.example.com-A",
+		rec.requests[0].path)
+	assert.NotEqual(t, rec.requests[0].path, rec.requests[1].path)
+	assert.Equal(t, rec.requests[0].path, rec.requests[2].path)
+}
+
+func TestDispatcher_Notify(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	failing := &recorder{status: http.StatusBadGateway}
+	failingServer := httptest.NewServer(failing)
+	defer failingServer.Close()
+
+	dispatcher, err := newDispatcher(config.NotificationsConfig{
+		Notifiers: []config.NotifierConfig{
+			{
+				Name:             "phone",
+				Type:             "ntfy",
+				URL:              server.URL,
+				Topic:            "home",
+				Events:           []string{domain.NotifyIPChanged},
+				Templates:        map[string]string{domain.NotifyIPChanged: "new {{.Family}}: {{.NewValue}}"},
+				RateLimitPerHour: 2,
+			},
+			{Name: "chat", Type: "slack", URL: failingServer.URL, Events: []string{domain.NotifyUpdateFailed}},
+		},
+	}, server.Client())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, dispatcher.Notify(ctx, ipChanged))
+	require.NoError(t, dispatcher.Notify(ctx, domain.Notification{Event: domain.NotifyRecordUpdated, Time: ipChanged.Time}))
+
+	later := ipChanged
+	later.Time = ipChanged.Time.Add(30 * time.Minute)
+	require.NoError(t, dispatcher.Notify(ctx, later))
+	require.NoError(t, dispatcher.Notify(ctx, later))
+
+	err = dispatcher.Notify(ctx, domain.Notification{Event: domain.NotifyUpdateFailed, Time: ipChanged.Time, Failures: 3})
+	assert.ErrorContains(t, err, "notifier chat: 502 Bad Gateway")
+
+	require.Len(t, rec.requests, 2, "record-updated filtered out and the last ip-changed over the rate limit")
+	assert.Equal(t, "new ipv4: 192.0.2.10", rec.requests[0].body)
+	assert.Len(t, failing.requests, 1)
+}
+
+func TestNewDispatcher_InvalidTemplate(t *testing.T) {
+	_, err := NewDispatcher(config.NotificationsConfig{
+		Notifiers: []config.NotifierConfig{
+			{Type: "webhook", URL: "http://localhost", Templates: map[string]string{domain.NotifyRecovered: "{{.Failures"}},
+		},
+	})
+
+	assert.ErrorContains(t, err, "notifier webhook-0: invalid recovered template")
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2)
+
+	assert.True(t, limiter.allow(now))
+	assert.True(t, limiter.allow(now.Add(10*time.Minute)))
+	assert.False(t, limiter.allow(now.Add(59*time.Minute)))
+	assert.True(t, limiter.allow(now.Add(61*time.Minute)))
+	assert.True(t, newRateLimiter(0).allow(now))
+}
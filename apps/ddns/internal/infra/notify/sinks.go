@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gotifyPriority shows the message as a notification on the Gotify apps.
+const gotifyPriority = 5
+
+type webhookPayload struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Title      string    `json:"title"`
+	Message    string    `json:"message"`
+	Family     string    `json:"family,omitempty"`
+	FQDN       string    `json:"fqdn,omitempty"`
+	RecordType string    `json:"type,omitempty"`
+	OldValue   string    `json:"old,omitempty"`
+	NewValue   string    `json:"new,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	Zone       string    `json:"zone,omitempty"`
+	Failures   int       `json:"failures,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// webhook posts the notification as JSON.
+type webhook struct {
+	client *http.Client
+	url    string
+}
+
+func (w *webhook) send(ctx context.Context, msg message) error {
+	n := msg.Notification
+
+	return sendJSON(ctx, w.client, http.MethodPost, w.url, nil, webhookPayload{
+		Event:      n.Event,
+		Time:       n.Time,
+		Title:      msg.Title,
+		Message:    msg.Text,
+		Family:     n.Family,
+		FQDN:       n.FQDN,
+		RecordType: n.RecordType,
+		OldValue:   n.OldValue,
+		NewValue:   n.NewValue,
+		Provider:   n.Provider,
+		Zone:       n.Zone,
+		Failures:   n.Failures,
+		Error:      n.Error,
+	})
+}
+
+// ntfy publishes the message to a ntfy topic.
+type ntfy struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func (n *ntfy) send(ctx context.Context, msg message) error {
+	headers := map[string]string{"Title": msg.Title, "Tags": msg.Notification.Event}
+	if n.token != "" {
+		headers["Authorization"] = "Bearer " + n.token
+	}
+
+	return send(ctx, n.client, http.MethodPost, n.url, headers, "text/plain", strings.NewReader(msg.Text))
+}
+
+// gotify sends the message to a Gotify server with an application token.
+type gotify struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func (g *gotify) send(ctx context.Context, msg message) error {
+	return sendJSON(ctx, g.client, http.MethodPost, g.url, map[string]string{"X-Gotify-Key": g.token}, map[string]any{
+		"title":    msg.Title,
+		"message":  msg.Text,
+		"priority": gotifyPriority,
+	})
+}
+
+// slack posts the message to a Slack compatible incoming webhook, such as
+// the ones of Mattermost, Rocket.Chat or Discord's /slack endpoint.
+type slack struct {
+	client *http.Client
+	url    string
+}
+
+func (s *slack) send(ctx context.Context, msg message) error {
+	return sendJSON(ctx, s.client, http.MethodPost, s.url, nil, map[string]string{
+		"text": "*" + msg.Title + "*\n" + msg.Text,
+	})
+}
+
+// matrix sends the message to a Matrix room as the user of the access token.
+type matrix struct {
+	client *http.Client
+	url    string
+	room   string
+	token  string
+}
+
+func (m *matrix) send(ctx context.Context, msg message) error {
+	// the transaction ID makes retries of the same message idempotent, the
+	// record fields tell apart the notifications of the same cycle
+	n := msg.Notification
+	parts := []string{"ddns", strconv.FormatInt(n.Time.UnixNano(), 10), n.Event}
+	for _, part := range []string{n.Family, n.Zone, strings.TrimSuffix(n.FQDN, "."), n.RecordType} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	txnID := strings.Join(parts, "-")
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.url, url.PathEscape(m.room), url.PathEscape(txnID))
+
+	return sendJSON(ctx, m.client, http.MethodPut, endpoint, map[string]string{"Authorization": "Bearer " + m.token}, map[string]string{
+		"msgtype": "m.text",
+		"body":    msg.Title + ": " + msg.Text,
+	})
+}
+
+func sendJSON(ctx context.Context, client *http.Client, method, endpoint string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	return send(ctx, client, method, endpoint, headers, "application/json", bytes.NewReader(body))
+}
+
+func send(ctx context.Context, client *http.Client, method, endpoint string, headers map[string]string, contentType string, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+// default ports of the smtp (submission) and smtps URLs.
+const (
+	smtpPort  = "587"
+	smtpsPort = "465"
+)
+
+// smtpSender emails the message to its recipients.
+type smtpSender struct {
+	address     string
+	host        string
+	implicitTLS bool
+	plaintext   bool
+	tlsConfig   *tls.Config
+	auth        string
+	username    string
+	password    string
+	from        string
+	to          []string
+	html        map[string]*htmltemplate.Template
+}
+
+func newSMTP(notifierConfig config.NotifierConfig) (*smtpSender, error) {
+	server, err := url.Parse(notifierConfig.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp url: %w", err)
+	}
+
+	s := &smtpSender{
+		host:      server.Hostname(),
+		plaintext: notifierConfig.AllowUnencrypted,
+		auth:      notifierConfig.Auth,
+		username:  notifierConfig.Username,
+		password:  notifierConfig.Password,
+		from:      notifierConfig.From,
+		to:        notifierConfig.To,
+		html:      make(map[string]*htmltemplate.Template, len(notifierConfig.HTMLTemplates)),
+	}
+
+	port := server.Port()
+	switch server.Scheme {
+	case "smtp":
+		if port == "" {
+			port = smtpPort
+		}
+	case "smtps":
+		s.implicitTLS = true
+		if port == "" {
+			port = smtpsPort
+		}
+	default:
+		return nil, fmt.Errorf("smtp url scheme must be smtp or smtps, got %q", server.Scheme)
+	}
+
+	s.address = net.JoinHostPort(s.host, port)
+	s.tlsConfig = &tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}
+
+	for event, text := range notifierConfig.HTMLTemplates {
+		tmpl, err := htmltemplate.New("html-" + event).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s html template: %w", event, err)
+		}
+
+		s.html[event] = tmpl
+	}
+
+	return s, nil
+}
+
+func (s *smtpSender) send(ctx context.Context, msg message) error {
+	body, err := s.email(msg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close() //nolint:errcheck
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close() //nolint:errcheck
+
+	if err := s.startTLS(client); err != nil {
+		return err
+	}
+
+	if s.username != "" {
+		if err := client.Auth(s.smtpAuth()); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+
+	for _, to := range s.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// startTLS upgrades the connection of smtp URLs, refusing to send the
+// credentials and the email in clear text unless allowed.
+func (s *smtpSender) startTLS(client *smtp.Client) error {
+	if s.implicitTLS {
+		return nil
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		if s.plaintext {
+			return nil
+		}
+
+		return errors.New("starttls: not offered by the server, set allow-unencrypted to send without TLS")
+	}
+
+	if err := client.StartTLS(s.tlsConfig); err != nil {
+		return fmt.Errorf("starttls: %w", err)
+	}
+
+	return nil
+}
+
+func (s *smtpSender) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if s.implicitTLS {
+		return (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}).DialContext(ctx, "tcp", s.address)
+	}
+
+	return dialer.DialContext(ctx, "tcp", s.address)
+}
+
+func (s *smtpSender) smtpAuth() smtp.Auth {
+	if s.auth == "login" {
+		return &loginAuth{username: s.username, password: s.password, host: s.host}
+	}
+
+	return smtp.PlainAuth("", s.username, s.password, s.host)
+}
+
+// email is the message as a MIME email, with an HTML alternative when the
+// event has an HTML template.
+func (s *smtpSender) email(msg message) ([]byte, error) {
+	var html bytes.Buffer
+	tmpl, hasHTML := s.html[msg.Notification.Event]
+	if hasHTML {
+		if err := tmpl.Execute(&html, msg.Notification); err != nil {
+			return nil, fmt.Errorf("failed to render %s html message: %w", msg.Notification.Event, err)
+		}
+	}
+
+	var email bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", s.from)
+	header.Set("To", strings.Join(s.to, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Title))
+	header.Set("Date", msg.Notification.Time.Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(s.from))
+	header.Set("MIME-Version", "1.0")
+
+	if !hasHTML {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&email, header)
+
+		if err := writeQuotedPrintable(&email, msg.Text); err != nil {
+			return nil, err
+		}
+
+		return email.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&email)
+	header.Set("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	writeHeader(&email, header)
+
+	for _, part := range []struct {
+		contentType string
+		text        string
+	}{
+		{contentType: "text/plain; charset=utf-8", text: msg.Text},
+		{contentType: "text/html; charset=utf-8", text: html.String()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeQuotedPrintable(w, part.text); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return email.Bytes(), nil
+}
+
+func writeHeader(w *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(w, "%s: %s\r\n", key, value) //nolint:errcheck
+		}
+	}
+	w.WriteString("\r\n") //nolint:errcheck
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n"))); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if address, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(address.Address, "@"); at >= 0 {
+			domain = address.Address[at+1:]
+		}
+	}
+
+	id := make([]byte, 12)
+	rand.Read(id) //nolint:errcheck
+
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
+
+// loginAuth is the LOGIN mechanism, still the only one offered by some
+// servers. Like smtp.PlainAuth it refuses to send the password unencrypted
+// to anything but localhost.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+
+	return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,389 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+type receivedMail struct {
+	tls  bool
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is an SMTP server good enough for net/smtp, keeping the mails
+// it receives.
+type smtpServer struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	implicitTLS bool
+	rejectAuth  bool
+	noStartTLS  bool
+
+	mu    sync.Mutex
+	mails []receivedMail
+}
+
+func newSMTPServer(t *testing.T, implicitTLS bool) (*smtpServer, *x509.CertPool) {
+	t.Helper()
+
+	cert, pool := testCertificate(t)
+	server := &smtpServer{
+		tlsConfig:   &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		implicitTLS: implicitTLS,
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if implicitTLS {
+		listener = tls.NewListener(listener, server.tlsConfig)
+	}
+	server.listener = listener
+	t.Cleanup(func() { listener.Close() }) //nolint:errcheck
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server, pool
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck
+
+	received := receivedMail{tls: s.implicitTLS}
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP") //nolint:errcheck
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-localhost") //nolint:errcheck
+			if !received.tls && !s.noStartTLS {
+				text.PrintfLine("250-STARTTLS") //nolint:errcheck
+			}
+			text.PrintfLine("250 AUTH PLAIN LOGIN") //nolint:errcheck
+		case "STARTTLS":
+			text.PrintfLine("220 ready") //nolint:errcheck
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, text, received.tls = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			received.auth = s.authenticate(text, arg)
+			if s.rejectAuth {
+				text.PrintfLine("535 authentication failed") //nolint:errcheck
+				continue
+			}
+			text.PrintfLine("235 authenticated") //nolint:errcheck
+		case "MAIL":
+			received.from = address(arg)
+			text.PrintfLine("250 ok") //nolint:errcheck
+		case "RCPT":
+			received.to = append(received.to, address(arg))
+			text.PrintfLine("250 ok") //nolint:errcheck
+		case "DATA":
+			text.PrintfLine("354 go ahead") //nolint:errcheck
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			received.data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, received)
+			s.mu.Unlock()
+			text.PrintfLine("250 queued") //nolint:errcheck
+		case "QUIT":
+			text.PrintfLine("221 bye") //nolint:errcheck
+			return
+		default:
+			text.PrintfLine("250 ok") //nolint:errcheck
+		}
+	}
+}
+
+// authenticate returns the mechanism and the credentials received.
+func (s *smtpServer) authenticate(text *textproto.Conn, arg string) string {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if mechanism == "PLAIN" {
+		credentials, _ := base64.StdEncoding.DecodeString(initial)
+		return "PLAIN" + strings.ReplaceAll(string(credentials), "\x00", " ")
+	}
+
+	credentials := make([]string, 0, 2)
+	for _, challenge := range []string{"Username:", "Password:"} {
+		text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge))) //nolint:errcheck
+		line, _ := text.ReadLine()
+		value, _ := base64.StdEncoding.DecodeString(line)
+		credentials = append(credentials, string(value))
+	}
+
+	return "LOGIN " + strings.Join(credentials, " ")
+}
+
+func (s *smtpServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mails
+}
+
+func address(arg string) string {
+	_, value, _ := strings.Cut(arg, ":")
+	return strings.Trim(value, "<> ")
+}
+
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	parsed, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+var updateFailed = message{
+	Title: "ddns: updates failing",
+	Text:  "Updating the records failed 3 times in a row: throttled",
+	Notification: domain.Notification{
+		Event:    domain.NotifyUpdateFailed,
+		Time:     time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+		Failures: 3,
+		Error:    "throttled",
+	},
+}
+
+func TestSMTP_send(t *testing.T) {
+	testCases := []struct {
+		name          string
+		implicitTLS   bool
+		scheme        string
+		auth          string
+		htmlTemplates map[string]string
+		expectedAuth  string
+		expectedHTML  string
+	}{
+		{
+			name:         "starttls with plain auth",
+			scheme:       "smtp",
+			expectedAuth: "PLAIN ddns secret",
+		},
+		{
+			name:          "implicit tls with login auth and html",
+			implicitTLS:   true,
+			scheme:        "smtps",
+			auth:          "login",
+			htmlTemplates: map[string]string{domain.NotifyUpdateFailed: "<p>Failed {{.Failures}} times: <b>{{.Error}}</b></p>"},
+			expectedAuth:  "LOGIN ddns secret",
+			expectedHTML:  "<p>Failed 3 times: <b>throttled</b></p>",
+		},
+	}
+
+	for _, testCase := range testCases {
+		implicitTLS := testCase.implicitTLS
+		scheme := testCase.scheme
+		auth := testCase.auth
+		htmlTemplates := testCase.htmlTemplates
+		expectedAuth := testCase.expectedAuth
+		expectedHTML := testCase.expectedHTML
+
+		t.Run(testCase.name, func(t *testing.T) {
+			server, pool := newSMTPServer(t, implicitTLS)
+
+			sender, err := newSMTP(config.NotifierConfig{
+				Type:          "smtp",
+				URL:           scheme + "://" + server.listener.Addr().String(),
+				From:          "ddns@example.net",
+				To:            []string{"admin@example.net", "oncall@example.net"},
+				Username:      "ddns",
+				Password:      "secret",
+				Auth:          auth,
+				HTMLTemplates: htmlTemplates,
+			})
+			require.NoError(t, err)
+			sender.tlsConfig.RootCAs = pool
+
+			require.NoError(t, sender.send(context.Background(), updateFailed))
+
+			mails := server.received()
+			require.Len(t, mails, 1)
+			assert.True(t, mails[0].tls)
+			assert.Equal(t, expectedAuth, mails[0].auth)
+			assert.Equal(t, "ddns@example.net", mails[0].from)
+			assert.Equal(t, []string{"admin@example.net", "oncall@example.net"}, mails[0].to)
+
+			msg, err := mail.ReadMessage(strings.NewReader(mails[0].data))
+			require.NoError(t, err)
+			assert.Equal(t, "ddns: updates failing", msg.Header.Get("Subject"))
+			assert.Equal(t, "admin@example.net, oncall@example.net", msg.Header.Get("To"))
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			require.NoError(t, err)
+
+			if expectedHTML == "" {
+				assert.Equal(t, "text/plain", mediaType)
+				assert.Equal(t, updateFailed.Text, strings.TrimSpace(readAll(t, quotedprintable.NewReader(msg.Body))))
+				return
+			}
+
+			assert.Equal(t, "multipart/alternative", mediaType)
+			parts := multipart.NewReader(msg.Body, params["boundary"])
+			bodies := map[string]string{}
+			for {
+				part, err := parts.NextPart()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+				bodies[contentType] = readAll(t, part)
+			}
+			assert.Equal(t, map[string]string{"text/plain": updateFailed.Text, "text/html": expectedHTML}, bodies)
+		})
+	}
+}
+
+func TestSMTP_sendAuthRejected(t *testing.T) {
+	server, pool := newSMTPServer(t, false)
+	server.rejectAuth = true
+
+	sender, err := newSMTP(config.NotifierConfig{
+		Type:     "smtp",
+		URL:      "smtp://" + server.listener.Addr().String(),
+		From:     "ddns@example.net",
+		To:       []string{"admin@example.net"},
+		Username: "ddns",
+		Password: "wrong",
+	})
+	require.NoError(t, err)
+	sender.tlsConfig.RootCAs = pool
+
+	err = sender.send(context.Background(), updateFailed)
+
+	assert.ErrorContains(t, err, `auth: 535 "authentication failed"`)
+	assert.Empty(t, server.received())
+}
+
+func TestSMTP_sendWithoutStartTLS(t *testing.T) {
+	testCases := []struct {
+		name             string
+		allowUnencrypted bool
+		expectedErr      string
+		expectedMails    int
+	}{
+		{
+			name:        "refused by default",
+			expectedErr: "starttls: not offered by the server, set allow-unencrypted to send without TLS",
+		},
+		{
+			name:             "sent when allowed",
+			allowUnencrypted: true,
+			expectedMails:    1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		allowUnencrypted := testCase.allowUnencrypted
+		expectedErr := testCase.expectedErr
+		expectedMails := testCase.expectedMails
+
+		t.Run(testCase.name, func(t *testing.T) {
+			server, _ := newSMTPServer(t, false)
+			server.noStartTLS = true
+
+			sender, err := newSMTP(config.NotifierConfig{
+				Type:             "smtp",
+				URL:              "smtp://" + server.listener.Addr().String(),
+				From:             "ddns@example.net",
+				To:               []string{"admin@example.net"},
+				AllowUnencrypted: allowUnencrypted,
+			})
+			require.NoError(t, err)
+
+			err = sender.send(context.Background(), updateFailed)
+
+			if expectedErr != "" {
+				assert.EqualError(t, err, expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			mails := server.received()
+			require.Len(t, mails, expectedMails)
+			for _, received := range mails {
+				assert.False(t, received.tls)
+			}
+		})
+	}
+}
+
+func TestNewSMTP_InvalidScheme(t *testing.T) {
+	_, err := newSMTP(config.NotifierConfig{Type: "smtp", URL: "https://mail.example.net"})
+
+	assert.EqualError(t, err, `smtp url scheme must be smtp or smtps, got "https"`)
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return strings.ReplaceAll(string(data), "\r\n", "\n")
+}
@@ -0,0 +1,226 @@
+// Package schedule parses when records are checked: a fixed interval such as
+// 10m, or a cron expression of five fields (minute, hour, day of month,
+// month, day of week) or one of @hourly, @daily, @weekly, @monthly and
+// @yearly.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when the next check after a time is due.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Interval is due every duration.
+type Interval time.Duration
+
+func (i Interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse reads an interval or a cron expression.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval < time.Second {
+			return nil, fmt.Errorf("schedule %q: interval must be at least 1s", spec)
+		}
+		return Interval(interval), nil
+	}
+
+	expression := spec
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		expression = macro
+	}
+
+	c, err := parseCron(expression)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", spec, err)
+	}
+
+	return c, nil
+}
+
+// cron is a cron expression, each field a bit set of the values matching.
+// Like in Vixie cron a day matches either the day of month or the day of
+// week when both are restricted.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+func parseCron(expression string) (*cron, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("want an interval or %d cron fields, got %d fields", len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = set
+	}
+
+	// 7 is Sunday too
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		// like Vixie cron a field starting with * (e.g. */2) does not restrict the day
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseField reads a comma separated list of *, values and ranges, each with
+// an optional /step.
+func parseField(text string, f field) (uint64, error) {
+	var set uint64
+
+	for item := range strings.SplitSeq(text, ",") {
+		valueRange, stepText, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepText, f.name)
+			}
+		}
+
+		low, high, err := parseRange(valueRange, hasStep, f)
+		if err != nil {
+			return 0, err
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+
+	return set, nil
+}
+
+// parseRange reads *, a range or a value, which with a step starts a range
+// up to the last value of the field.
+func parseRange(text string, hasStep bool, f field) (int, int, error) {
+	if text == "*" {
+		return f.min, f.max, nil
+	}
+
+	lowText, highText, isRange := strings.Cut(text, "-")
+	low, err := parseValue(lowText, f)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch {
+	case !isRange && hasStep:
+		return low, f.max, nil
+	case !isRange:
+		return low, low, nil
+	}
+
+	high, err := parseValue(highText, f)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if low > high {
+		return 0, 0, fmt.Errorf("invalid range %q in %s", text, f.name)
+	}
+
+	return low, high, nil
+}
+
+func parseValue(text string, f field) (int, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid %s %q, want %d to %d", f.name, text, f.min, f.max)
+	}
+
+	return value, nil
+}
+
+// Next returns the first minute after after matching the expression, in the
+// location of after. The zero time is returned when none matches within
+// five years, as for the 30th of February.
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
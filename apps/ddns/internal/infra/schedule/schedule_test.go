@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	// a Friday
+	after := time.Date(2026, 5, 1, 10, 17, 30, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		spec         string
+		expectedNext time.Time
+	}{
+		{
+			name:         "interval",
+			spec:         "90s",
+			expectedNext: time.Date(2026, 5, 1, 10, 19, 0, 0, time.UTC),
+		},
+		{
+			name:         "every minute",
+			spec:         "* * * * *",
+			expectedNext: time.Date(2026, 5, 1, 10, 18, 0, 0, time.UTC),
+		},
+		{
+			name:         "every 15 minutes",
+			spec:         "*/15 * * * *",
+			expectedNext: time.Date(2026, 5, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:         "hourly",
+			spec:         "@hourly",
+			expectedNext: time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "working hours",
+			spec:         "0 8-18/2 * * 1-5",
+			expectedNext: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "next week day",
+			spec:         "30 6 * * 1",
+			expectedNext: time.Date(2026, 5, 4, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name:         "sunday as 7",
+			spec:         "0 0 * * 7",
+			expectedNext: time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "day of month or week",
+			spec:         "0 0 15 * 6",
+			expectedNext: time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "stepped day of month and day of week",
+			spec:         "0 0 */2 * 1",
+			expectedNext: time.Date(2026, 5, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "list of months",
+			spec:         "0 0 1 1,7 *",
+			expectedNext: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never",
+			spec: "0 0 30 2 *",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spec := testCase.spec
+		expectedNext := testCase.expectedNext
+
+		t.Run(testCase.name, func(t *testing.T) {
+			s, err := Parse(spec)
+			require.NoError(t, err)
+
+			assert.Equal(t, expectedNext, s.Next(after))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	testCases := []struct {
+		spec        string
+		expectedErr string
+	}{
+		{spec: "500ms", expectedErr: `schedule "500ms": interval must be at least 1s`},
+		{spec: "hourly", expectedErr: `schedule "hourly": want an interval or 5 cron fields, got 1 fields`},
+		{spec: "60 * * * *", expectedErr: `schedule "60 * * * *": invalid minute "60", want 0 to 59`},
+		{spec: "0 18-8 * * *", expectedErr: `schedule "0 18-8 * * *": invalid range "18-8" in hour`},
+		{spec: "*/0 * * * *", expectedErr: `schedule "*/0 * * * *": invalid step "0" in minute`},
+		{spec: "0 0 0 * *", expectedErr: `schedule "0 0 0 * *": invalid day of month "0", want 1 to 31`},
+	}
+
+	for _, testCase := range testCases {
+		spec := testCase.spec
+		expectedErr := testCase.expectedErr
+
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec)
+
+			assert.EqualError(t, err, expectedErr)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+)
+
+const (
+	stateFileName = "state.json"
+	stateVersion  = 1
+)
+
+// FileStore keeps the state as a JSON file in the state directory. The file
+// is replaced atomically so a crash never leaves a partial state behind.
+type FileStore struct {
+	path string
+}
+
+type fileState struct {
+	Version      int          `json:"version"`
+	IPv4         string       `json:"ipv4,omitempty"`
+	IPv6         string       `json:"ipv6,omitempty"`
+	Records      []fileRecord `json:"records"`
+	ReconciledAt time.Time    `json:"reconciled_at"`
+	ConfigHash   string       `json:"config_hash"`
+}
+
+type fileRecord struct {
+	FQDN        string             `json:"fqdn"`
+	Type        string             `json:"type"`
+	Zone        string             `json:"zone,omitempty"`
+	Value       string             `json:"value"`
+	Spec        *domain.RecordSpec `json:"spec,omitempty"`
+	Source      *domain.IPSource   `json:"source,omitempty"`
+	ChangeIDs   []string           `json:"change_ids,omitempty"`
+	PublishedAt time.Time          `json:"published_at,omitzero"`
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	return &FileStore{path: filepath.Join(dir, stateFileName)}, nil
+}
+
+// Load returns the saved state, or nil when there is none.
+func (s *FileStore) Load() (*domain.State, error) {
+	data, err := os.ReadFile(s.path) //nolint:gosec // path comes from the config
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var saved fileState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode state %s: %w", s.path, err)
+	}
+
+	if saved.Version != stateVersion {
+		return nil, nil
+	}
+
+	state := &domain.State{
+		IPv4:         saved.IPv4,
+		IPv6:         saved.IPv6,
+		Records:      make([]domain.PublishedRecord, 0, len(saved.Records)),
+		ReconciledAt: saved.ReconciledAt,
+		ConfigHash:   saved.ConfigHash,
+	}
+
+	for _, record := range saved.Records {
+		state.Records = append(state.Records, domain.PublishedRecord{
+			Record: domain.Record{
+				IP:     record.Value,
+				IPType: record.Type,
+				FQDN:   record.FQDN,
+				Spec:   record.Spec,
+				Zone:   record.Zone,
+				Source: record.Source,
+			},
+			ChangeIDs:   record.ChangeIDs,
+			PublishedAt: record.PublishedAt,
+		})
+	}
+
+	return state, nil
+}
+
+func (s *FileStore) Save(state *domain.State) error {
+	saved := fileState{
+		Version:      stateVersion,
+		IPv4:         state.IPv4,
+		IPv6:         state.IPv6,
+		Records:      make([]fileRecord, 0, len(state.Records)),
+		ReconciledAt: state.ReconciledAt,
+		ConfigHash:   state.ConfigHash,
+	}
+
+	for _, record := range state.Records {
+		saved.Records = append(saved.Records, fileRecord{
+			FQDN:        record.FQDN,
+			Type:        record.IPType,
+			Zone:        record.Zone,
+			Value:       record.IP,
+			Spec:        record.Spec,
+			Source:      record.Source,
+			ChangeIDs:   record.ChangeIDs,
+			PublishedAt: record.PublishedAt,
+		})
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), stateFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() //nolint:errcheck,gosec
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	return nil
+}
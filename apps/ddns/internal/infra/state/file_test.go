@@ -0,0 +1,108 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_SaveLoad(t *testing.T) {
+	reconciledAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	saved := &domain.State{
+		IPv4: "192.0.2.10",
+		IPv6: "2001:db8::10",
+		Records: []domain.PublishedRecord{
+			{
+				Record: domain.Record{
+					IP:     "192.0.2.10",
+					IPType: "A",
+					FQDN:   "vpn.example.net.",
+					Zone:   "Z123",
+				},
+				ChangeIDs:   []string{"C1"},
+				PublishedAt: reconciledAt.Add(-time.Minute),
+			},
+			{
+				Record: domain.Record{
+					IP:     "0 0 1194 vpn.example.net.",
+					IPType: "SRV",
+					FQDN:   "_openvpn._udp.example.net.",
+					Zone:   "Z123",
+					Spec:   &domain.RecordSpec{Target: "vpn.example.net.", Port: 1194},
+					Source: &domain.IPSource{Type: "static", IPv4: "192.168.1.10"},
+				},
+			},
+		},
+		ReconciledAt: reconciledAt,
+		ConfigHash:   "abc",
+	}
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "ddns"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Save(saved))
+
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, saved, loaded)
+
+	entries, err := os.ReadDir(filepath.Dir(store.path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+func TestFileStore_Load(t *testing.T) {
+	testCases := []struct {
+		name          string
+		content       string
+		expectedState *domain.State
+		expectedError bool
+	}{
+		{
+			name:          "no state saved",
+			expectedState: nil,
+		},
+		{
+			name:          "invalid state",
+			content:       "{",
+			expectedError: true,
+		},
+		{
+			name:          "state of another version",
+			content:       `{"version": 99, "ipv4": "192.0.2.10"}`,
+			expectedState: nil,
+		},
+		{
+			name:    "empty state",
+			content: `{"version": 1}`,
+			expectedState: &domain.State{
+				Records: []domain.PublishedRecord{},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		content := testCase.content
+		expectedState := testCase.expectedState
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			store, err := NewFileStore(t.TempDir())
+			require.NoError(t, err)
+
+			if content != "" {
+				require.NoError(t, os.WriteFile(store.path, []byte(content), 0o600))
+			}
+
+			state, err := store.Load()
+
+			assert.Equal(t, expectedState, state)
+			assert.Equal(t, expectedError, err != nil)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package r53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+// Route53 limits for a single ChangeResourceRecordSets request, UPSERT
+// changes count twice against both of them.
+const (
+	maxBatchRecords    = 1000
+	maxBatchCharacters = 32000
+	changeIDPrefix     = "/change/"
+)
+
+type zoneRequest struct {
+	zoneID  string
+	batches []changeBatch
+}
+
+type changeBatch struct {
+	input   *route53.ChangeResourceRecordSetsInput
+	records []Record
+}
+
+// splitChanges groups the changes of a zone in as few batches as the
+// Route53 request limits allow.
+func splitChanges(zoneID string, changes []types.Change, changed []Record) []changeBatch {
+	batches := make([]changeBatch, 0, 1)
+	current := changeBatch{}
+	size, characters := 0, 0
+
+	for i, change := range changes {
+		changeRecords, changeCharacters := changeSize(change)
+		if len(current.records) > 0 && (size+changeRecords > maxBatchRecords || characters+changeCharacters > maxBatchCharacters) {
+			batches = append(batches, current)
+			current = changeBatch{}
+			size, characters = 0, 0
+		}
+
+		if current.input == nil {
+			current.input = &route53.ChangeResourceRecordSetsInput{
+				HostedZoneId: aws.String(zoneID),
+				ChangeBatch:  new(types.ChangeBatch),
+			}
+		}
+
+		current.input.ChangeBatch.Changes = append(current.input.ChangeBatch.Changes, change)
+		current.records = append(current.records, changed[i])
+		size += changeRecords
+		characters += changeCharacters
+	}
+
+	if len(current.records) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+func changeSize(change types.Change) (int, int) {
+	weight := 1
+	if change.Action == types.ChangeActionUpsert {
+		weight = 2
+	}
+
+	if change.ResourceRecordSet == nil {
+		return 0, 0
+	}
+
+	characters := 0
+	for _, resourceRecord := range change.ResourceRecordSet.ResourceRecords {
+		characters += len(aws.ToString(resourceRecord.Value))
+	}
+
+	return weight * len(change.ResourceRecordSet.ResourceRecords), weight * characters
+}
+
+// apply sends the batches of a zone. When a batch is rejected and failure
+// isolation is enabled its changes are retried one by one, so a single
+// invalid record does not block the rest.
+func (ac awsClient) apply(ctx context.Context, request zoneRequest) ZoneUpdate {
+	update := ZoneUpdate{
+		ZoneID: request.zoneID,
+		Atomic: len(request.batches) == 1,
+	}
+
+	for _, batch := range request.batches {
+		changeID, err := ac.change(ctx, batch.input)
+		if err == nil {
+			update.ChangeIDs = append(update.ChangeIDs, changeID)
+			update.Updated = append(update.Updated, batch.records...)
+			continue
+		}
+
+		if !ac.isolateFailures || len(batch.records) == 1 {
+			update.Failed = append(update.Failed, batch.records...)
+			update.Err = errors.Join(update.Err, err)
+			continue
+		}
+
+		logging.FromContext(ctx).Warnf("change batch for aws hosted zone %s rejected, retrying record by record: %v", request.zoneID, err)
+		update.Atomic = false
+		ac.isolate(ctx, batch, &update)
+	}
+
+	return update
+}
+
+func (ac awsClient) isolate(ctx context.Context, batch changeBatch, update *ZoneUpdate) {
+	for i, change := range batch.input.ChangeBatch.Changes {
+		record := batch.records[i]
+
+		changeID, err := ac.change(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: batch.input.HostedZoneId,
+			ChangeBatch:  &types.ChangeBatch{Changes: []types.Change{change}},
+		})
+		if err != nil {
+			logging.FromContext(ctx).WithFields(log.Fields{
+				logging.FieldFQDN:   record.FQDN,
+				logging.FieldFamily: logging.Family(record.RecordType),
+			}).Warnf("%s record rejected: %v", record.RecordType, err)
+			update.Failed = append(update.Failed, record)
+			update.Err = errors.Join(update.Err, fmt.Errorf("%s %s: %w", record.FQDN, record.RecordType, err))
+			continue
+		}
+
+		update.ChangeIDs = append(update.ChangeIDs, changeID)
+		update.Updated = append(update.Updated, record)
+	}
+}
+
+func (ac awsClient) change(ctx context.Context, input *route53.ChangeResourceRecordSetsInput) (string, error) {
+	output, err := ac.client.ChangeResourceRecordSets(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	if output == nil || output.ChangeInfo == nil {
+		return "", nil
+	}
+
+	return strings.TrimPrefix(aws.ToString(output.ChangeInfo.Id), changeIDPrefix), nil
+}
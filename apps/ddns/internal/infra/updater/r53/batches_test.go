@@ -0,0 +1,154 @@
+package r53
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func upsert(fqdn, value string) types.Change {
+	return types.Change{
+		Action: types.ChangeActionUpsert,
+		ResourceRecordSet: &types.ResourceRecordSet{
+			Name:            aws.String(fqdn),
+			Type:            types.RRTypeA,
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String(value)}},
+		},
+	}
+}
+
+func TestSplitChanges(t *testing.T) {
+	testCases := []struct {
+		name            string
+		changes         int
+		valueLength     int
+		expectedBatches []int
+	}{
+		{
+			name:            "single batch",
+			changes:         3,
+			valueLength:     10,
+			expectedBatches: []int{3},
+		},
+		{
+			name:            "split by record count",
+			changes:         1200,
+			valueLength:     7,
+			expectedBatches: []int{500, 500, 200},
+		},
+		{
+			name:            "split by characters",
+			changes:         10,
+			valueLength:     4000,
+			expectedBatches: []int{4, 4, 2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		changesCount := testCase.changes
+		valueLength := testCase.valueLength
+		expectedBatches := testCase.expectedBatches
+
+		t.Run(testCase.name, func(t *testing.T) {
+			changes := make([]types.Change, 0, changesCount)
+			changed := make([]Record, 0, changesCount)
+			for i := range changesCount {
+				fqdn := fmt.Sprintf("host%d.example.com.", i)
+				changes = append(changes, upsert(fqdn, strings.Repeat("1", valueLength)))
+				changed = append(changed, Record{FQDN: fqdn, RecordType: "A"})
+			}
+
+			batches := splitChanges("Z123", changes, changed)
+
+			sizes := make([]int, 0, len(batches))
+			for _, batch := range batches {
+				assert.Equal(t, "Z123", aws.ToString(batch.input.HostedZoneId))
+				assert.Len(t, batch.records, len(batch.input.ChangeBatch.Changes))
+				sizes = append(sizes, len(batch.records))
+			}
+			assert.Equal(t, expectedBatches, sizes)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	good := Record{FQDN: "good.example.com.", RecordType: "A", IP: "192.0.2.1"}
+	other := Record{FQDN: "other.example.com.", RecordType: "A", IP: "192.0.2.2"}
+	bad := Record{FQDN: "bad.example.com.", RecordType: "A", IP: "not-an-ip"}
+
+	testCases := []struct {
+		name              string
+		isolateFailures   bool
+		records           []Record
+		expectedAtomic    bool
+		expectedUpdated   []Record
+		expectedFailed    []Record
+		expectedChangeIDs []string
+		expectedError     bool
+	}{
+		{
+			name:              "atomic update",
+			records:           []Record{good, other},
+			expectedAtomic:    true,
+			expectedUpdated:   []Record{good, other},
+			expectedChangeIDs: []string{"C1"},
+		},
+		{
+			name:           "rejected batch fails every record",
+			records:        []Record{good, bad, other},
+			expectedAtomic: true,
+			expectedFailed: []Record{good, bad, other},
+			expectedError:  true,
+		},
+		{
+			name:              "rejected batch retried record by record",
+			isolateFailures:   true,
+			records:           []Record{good, bad, other},
+			expectedAtomic:    false,
+			expectedUpdated:   []Record{good, other},
+			expectedFailed:    []Record{bad},
+			expectedChangeIDs: []string{"C2", "C4"},
+			expectedError:     true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		isolateFailures := testCase.isolateFailures
+		records := testCase.records
+		expectedAtomic := testCase.expectedAtomic
+		expectedUpdated := testCase.expectedUpdated
+		expectedFailed := testCase.expectedFailed
+		expectedChangeIDs := testCase.expectedChangeIDs
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			client := awsClient{
+				client:          &mockUpdateGetter{rejectValue: bad.IP},
+				isolateFailures: isolateFailures,
+			}
+
+			zones := []zone{{id: "Z123", records: records}}
+			requests := client.buildRequests(zones, records, nil)
+			require.Len(t, requests, 1)
+
+			update := client.apply(context.Background(), requests[0])
+
+			assert.Equal(t, "Z123", update.ZoneID)
+			assert.Equal(t, expectedAtomic, update.Atomic)
+			assert.Equal(t, expectedUpdated, update.Updated)
+			assert.Equal(t, expectedFailed, update.Failed)
+			assert.Equal(t, expectedChangeIDs, update.ChangeIDs)
+			if expectedError {
+				assert.ErrorContains(t, update.Err, "invalid value")
+			} else {
+				assert.NoError(t, update.Err)
+			}
+		})
+	}
+}
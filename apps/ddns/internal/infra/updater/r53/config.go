@@ -1,32 +1,59 @@
 package r53
 
-import (
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/jorgesanchez-e/localenvironment/config"
-)
+import "github.com/jorgesanchez-e/localenvironment/config"
 
 type AWSConfig config.AWSConfig
 
-func (c AWSConfig) getCredentials() credentials.StaticCredentialsProvider {
-	return credentials.NewStaticCredentialsProvider(c.AccessKey, c.SecretKey, "")
-}
-
 func (c AWSConfig) zones() []zone {
 	zones := make([]zone, 0, len(c.Zones))
 	for _, configZone := range c.Zones {
 		records := make([]Record, 0, len(configZone.Records))
 		for _, record := range configZone.Records {
-			records = append(records, Record{
-				FQDN:       record.FQDN,
-				RecordType: record.RecordType,
-				RecordTTL:  record.RecordTTL,
-			})
+			records = append(records, newRecord(record))
 		}
 		zones = append(zones, zone{
-			id:      configZone.ID,
-			records: records,
+			id:        configZone.ID,
+			name:      configZone.Name,
+			private:   configZone.Private || configZone.VPCID != "", // only private zones are associated with a VPC
+			vpcID:     configZone.VPCID,
+			vpcRegion: configZone.VPCRegion,
+			records:   records,
 		})
 	}
 
 	return zones
 }
+
+func (c AWSConfig) records() []Record {
+	records := make([]Record, 0, len(c.Records))
+	for _, record := range c.Records {
+		records = append(records, newRecord(record))
+	}
+
+	return records
+}
+
+func newRecord(record config.RecordConfig) Record {
+	r := Record{
+		FQDN:          record.FQDN,
+		RecordType:    record.RecordType,
+		RecordTTL:     record.RecordTTL,
+		SetIdentifier: record.SetIdentifier,
+		Failover:      record.Failover,
+		Source:        record.IPSource,
+		ptr:           record.PTR,
+	}
+
+	if record.HealthCheck != nil {
+		r.healthCheck = &healthCheck{
+			checkType:        record.HealthCheck.Type,
+			port:             record.HealthCheck.Port,
+			resourcePath:     record.HealthCheck.ResourcePath,
+			host:             record.HealthCheck.Host,
+			requestInterval:  record.HealthCheck.RequestInterval,
+			failureThreshold: record.HealthCheck.FailureThreshold,
+		}
+	}
+
+	return r
+}
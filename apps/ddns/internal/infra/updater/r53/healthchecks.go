@@ -0,0 +1,375 @@
+package r53
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+)
+
+const (
+	// healthCheckTag marks the health checks owned by ddns, its value is the
+	// key of the record the health check belongs to.
+	healthCheckTag = "ddns-record"
+	// ListTagsForResources accepts at most 10 resource IDs per call.
+	maxTagResources = 10
+)
+
+// What UpdateRecords does with the health check of a record.
+const (
+	healthCheckCreate = "create"
+	healthCheckUpdate = "update"
+	healthCheckReuse  = "reuse"
+)
+
+type healthCheck struct {
+	checkType        string
+	port             int
+	resourcePath     string
+	host             string
+	requestInterval  int
+	failureThreshold int
+}
+
+type managedHealthCheck struct {
+	id      string
+	version int64
+	config  *types.HealthCheckConfig
+}
+
+// healthCheckKey identifies the record set a health check belongs to.
+func healthCheckKey(record Record) string {
+	return strings.Join([]string{normalizeName(record.FQDN), record.RecordType, record.SetIdentifier}, "|")
+}
+
+// ensureHealthChecks creates or updates the health checks of the records that
+// have one configured so they point at the new address, and returns the
+// records with HealthCheckID set. It also returns, grouped by record key, the
+// health checks owned by ddns that are no longer needed once the records have
+// been updated.
+func (ac awsClient) ensureHealthChecks(ctx context.Context, zones []zone, inputRecords []Record) ([]Record, map[string][]string, error) {
+	configured := configuredHealthChecks(zones)
+	if len(configured) == 0 {
+		return inputRecords, nil, nil
+	}
+
+	existing, err := ac.managedHealthChecks(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inUse := make(map[string]struct{})
+	outputRecords := make([]Record, 0, len(inputRecords))
+
+	for _, record := range inputRecords {
+		key := healthCheckKey(record)
+		cfg, ok := configured[key]
+		if !ok {
+			outputRecords = append(outputRecords, record)
+			continue
+		}
+
+		id, err := ac.upsertHealthCheck(ctx, key, cfg, record.IP, existing[key])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		inUse[id] = struct{}{}
+		record.HealthCheckID = id
+		outputRecords = append(outputRecords, record)
+	}
+
+	obsolete := make(map[string][]string)
+	for key, checks := range existing {
+		_, stillConfigured := configured[key]
+		for _, check := range checks {
+			if _, used := inUse[check.id]; used {
+				continue
+			}
+
+			// health checks of records that did not change this cycle are still referenced
+			if stillConfigured && !changed(inputRecords, key) {
+				continue
+			}
+
+			obsolete[key] = append(obsolete[key], check.id)
+		}
+	}
+
+	return outputRecords, obsolete, nil
+}
+
+func (ac awsClient) upsertHealthCheck(ctx context.Context, key string, cfg healthCheck, ip string, existing []managedHealthCheck) (string, error) {
+	desired := cfg.toAWS(ip)
+
+	switch action, check := cfg.plan(ip, existing); action {
+	case healthCheckReuse:
+		return check.id, nil
+	case healthCheckUpdate:
+		_, err := ac.client.UpdateHealthCheck(ctx, &route53.UpdateHealthCheckInput{
+			HealthCheckId:            aws.String(check.id),
+			HealthCheckVersion:       aws.Int64(check.version),
+			IPAddress:                desired.IPAddress,
+			Port:                     desired.Port,
+			ResourcePath:             desired.ResourcePath,
+			FullyQualifiedDomainName: desired.FullyQualifiedDomainName,
+			FailureThreshold:         desired.FailureThreshold,
+			ResetElements:            resetElements(check.config, desired),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to update health check %s: %w", check.id, err)
+		}
+
+		logging.FromContext(ctx).Infof("health check %s updated to %s", check.id, ip)
+		return check.id, nil
+	}
+
+	created, err := ac.client.CreateHealthCheck(ctx, &route53.CreateHealthCheckInput{
+		CallerReference:   aws.String(fmt.Sprintf("ddns-%d", time.Now().UnixNano())),
+		HealthCheckConfig: desired,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create health check for %s: %w", key, err)
+	}
+
+	id := aws.ToString(created.HealthCheck.Id)
+	_, err = ac.client.ChangeTagsForResource(ctx, &route53.ChangeTagsForResourceInput{
+		ResourceId:   aws.String(id),
+		ResourceType: types.TagResourceTypeHealthcheck,
+		AddTags: []types.Tag{
+			{Key: aws.String(healthCheckTag), Value: aws.String(key)},
+		},
+	})
+	if err != nil {
+		// an untagged health check is not managed by ddns, it would never be deleted
+		if _, deleteErr := ac.client.DeleteHealthCheck(ctx, &route53.DeleteHealthCheckInput{HealthCheckId: aws.String(id)}); deleteErr != nil {
+			logging.FromContext(ctx).Errorf("failed to delete untagged health check %s: %v", id, deleteErr)
+		}
+
+		return "", fmt.Errorf("failed to tag health check %s: %w", id, err)
+	}
+
+	logging.FromContext(ctx).Infof("health check %s created for %s", id, ip)
+	return id, nil
+}
+
+// planHealthChecks returns inputRecords with the health check ID and action
+// UpdateRecords would use, without creating or updating any health check.
+// The records of health checks still to be created have no ID.
+func (ac awsClient) planHealthChecks(ctx context.Context, zones []zone, inputRecords []Record) ([]Record, error) {
+	configured := configuredHealthChecks(zones)
+	if len(configured) == 0 {
+		return inputRecords, nil
+	}
+
+	existing, err := ac.managedHealthChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputRecords := make([]Record, 0, len(inputRecords))
+	for _, record := range inputRecords {
+		if cfg, ok := configured[healthCheckKey(record)]; ok {
+			action, check := cfg.plan(record.IP, existing[healthCheckKey(record)])
+			record.healthCheckAction, record.HealthCheckID = action, check.id
+		}
+
+		outputRecords = append(outputRecords, record)
+	}
+
+	return outputRecords, nil
+}
+
+// managedHealthChecks returns the health checks tagged by ddns grouped by
+// record key.
+func (ac awsClient) managedHealthChecks(ctx context.Context) (map[string][]managedHealthCheck, error) {
+	checks := make(map[string]managedHealthCheck)
+
+	paginator := route53.NewListHealthChecksPaginator(ac.client, &route53.ListHealthChecksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, check := range page.HealthChecks {
+			checks[aws.ToString(check.Id)] = managedHealthCheck{
+				id:      aws.ToString(check.Id),
+				version: aws.ToInt64(check.HealthCheckVersion),
+				config:  check.HealthCheckConfig,
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(checks))
+	for id := range checks {
+		ids = append(ids, id)
+	}
+
+	managed := make(map[string][]managedHealthCheck)
+	for start := 0; start < len(ids); start += maxTagResources {
+		end := min(start+maxTagResources, len(ids))
+
+		tags, err := ac.client.ListTagsForResources(ctx, &route53.ListTagsForResourcesInput{
+			ResourceIds:  ids[start:end],
+			ResourceType: types.TagResourceTypeHealthcheck,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tagSet := range tags.ResourceTagSets {
+			for _, tag := range tagSet.Tags {
+				if aws.ToString(tag.Key) == healthCheckTag {
+					key := aws.ToString(tag.Value)
+					managed[key] = append(managed[key], checks[aws.ToString(tagSet.ResourceId)])
+				}
+			}
+		}
+	}
+
+	return managed, nil
+}
+
+// removable returns the obsolete health checks no record set refers to
+// anymore: those of the records updated this cycle and those of records that
+// were not part of it. The health checks of records whose update failed are
+// still attached to their record sets, so they are kept for the next cycle.
+func removable(obsolete map[string][]string, requested []Record, updates []ZoneUpdate) []string {
+	updated := make(map[string]struct{})
+	for _, update := range updates {
+		for _, record := range update.Updated {
+			updated[healthCheckKey(record)] = struct{}{}
+		}
+	}
+
+	ids := make([]string, 0)
+	for key, checks := range obsolete {
+		if _, ok := updated[key]; ok || !changed(requested, key) {
+			ids = append(ids, checks...)
+		}
+	}
+
+	return ids
+}
+
+func (ac awsClient) deleteHealthChecks(ctx context.Context, ids []string) {
+	for _, id := range ids {
+		if _, err := ac.client.DeleteHealthCheck(ctx, &route53.DeleteHealthCheckInput{HealthCheckId: aws.String(id)}); err != nil {
+			logging.FromContext(ctx).Errorf("failed to delete obsolete health check %s: %v", id, err)
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("obsolete health check %s deleted", id)
+	}
+}
+
+func configuredHealthChecks(zones []zone) map[string]healthCheck {
+	configured := make(map[string]healthCheck)
+	for _, zone := range zones {
+		for _, record := range zone.records {
+			if record.healthCheck != nil {
+				configured[healthCheckKey(record)] = *record.healthCheck
+			}
+		}
+	}
+
+	return configured
+}
+
+func changed(records []Record, key string) bool {
+	for _, record := range records {
+		if healthCheckKey(record) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (hc healthCheck) toAWS(ip string) *types.HealthCheckConfig {
+	cfg := &types.HealthCheckConfig{
+		Type:      types.HealthCheckType(hc.checkType),
+		IPAddress: aws.String(ip),
+		Port:      aws.Int32(int32(hc.port)), //nolint:gosec // validated to be a port number
+	}
+
+	if hc.resourcePath != "" {
+		cfg.ResourcePath = aws.String(hc.resourcePath)
+	}
+
+	if hc.host != "" {
+		cfg.FullyQualifiedDomainName = aws.String(hc.host)
+	}
+
+	if hc.requestInterval != 0 {
+		cfg.RequestInterval = aws.Int32(int32(hc.requestInterval)) //nolint:gosec // validated to be 10 or 30
+	}
+
+	if hc.failureThreshold != 0 {
+		cfg.FailureThreshold = aws.Int32(int32(hc.failureThreshold)) //nolint:gosec // validated to be between 1 and 10
+	}
+
+	return cfg
+}
+
+// resetElements returns the optional settings of current that are no longer
+// configured, UpdateHealthCheck keeps the settings it is not given.
+func resetElements(current, desired *types.HealthCheckConfig) []types.ResettableElementName {
+	reset := make([]types.ResettableElementName, 0)
+	if current.ResourcePath != nil && desired.ResourcePath == nil {
+		reset = append(reset, types.ResettableElementNameResourcePath)
+	}
+
+	if current.FullyQualifiedDomainName != nil && desired.FullyQualifiedDomainName == nil {
+		reset = append(reset, types.ResettableElementNameFullyQualifiedDomainName)
+	}
+
+	return reset
+}
+
+// updatable reports whether an existing health check can be updated in place,
+// the type and request interval of a health check cannot be changed.
+func (hc healthCheck) updatable(current *types.HealthCheckConfig) bool {
+	if current == nil || string(current.Type) != hc.checkType {
+		return false
+	}
+
+	return hc.requestInterval == 0 || aws.ToInt32(current.RequestInterval) == int32(hc.requestInterval) //nolint:gosec // validated to be 10 or 30
+}
+
+// plan returns whether the health check of a record pointing at ip has to be
+// created, updated or can be reused as it is, and the existing health check
+// that is updated or reused.
+func (hc healthCheck) plan(ip string, existing []managedHealthCheck) (string, managedHealthCheck) {
+	for _, check := range existing {
+		if !hc.updatable(check.config) {
+			continue
+		}
+
+		if hc.matches(check.config, ip) {
+			return healthCheckReuse, check
+		}
+
+		return healthCheckUpdate, check
+	}
+
+	return healthCheckCreate, managedHealthCheck{}
+}
+
+// matches reports whether an updatable health check already has the settings
+// of hc with ip. An unset failure threshold keeps the current one.
+func (hc healthCheck) matches(current *types.HealthCheckConfig, ip string) bool {
+	desired := hc.toAWS(ip)
+
+	return aws.ToString(current.IPAddress) == ip &&
+		aws.ToInt32(current.Port) == aws.ToInt32(desired.Port) &&
+		aws.ToString(current.ResourcePath) == aws.ToString(desired.ResourcePath) &&
+		aws.ToString(current.FullyQualifiedDomainName) == aws.ToString(desired.FullyQualifiedDomainName) &&
+		(desired.FailureThreshold == nil || aws.ToInt32(current.FailureThreshold) == aws.ToInt32(desired.FailureThreshold))
+}
@@ -0,0 +1,261 @@
+package r53
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRecords_HealthChecks(t *testing.T) {
+	healthCheckErr := errors.New("list health checks failed")
+	changeErr := errors.New("change batch rejected")
+	tagErr := errors.New("tag health check failed")
+	primary := Record{
+		FQDN:          "vpn.example.com.",
+		RecordType:    "A",
+		RecordTTL:     60,
+		SetIdentifier: "home",
+		Failover:      "PRIMARY",
+		healthCheck:   &healthCheck{checkType: "TCP", port: 1194, requestInterval: 30},
+	}
+	other := Record{FQDN: "www.example.com.", RecordType: "A", RecordTTL: 60}
+	primaryKey := healthCheckKey(primary)
+
+	existingCheck := func(id string, checkType types.HealthCheckType) types.HealthCheck {
+		return types.HealthCheck{
+			Id:                 aws.String(id),
+			HealthCheckVersion: aws.Int64(3),
+			HealthCheckConfig: &types.HealthCheckConfig{
+				Type:            checkType,
+				IPAddress:       aws.String("192.0.2.1"),
+				Port:            aws.Int32(1194),
+				RequestInterval: aws.Int32(30),
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                string
+		input               []Record
+		healthChecks        []types.HealthCheck
+		healthCheckTags     map[string]string
+		healthCheckErr      error
+		changeErr           error
+		tagErr              error
+		expectedErr         error
+		expectedFailed      int
+		expectedCreated     int
+		expectedUpdated     []string
+		expectedDeleted     []string
+		expectedHealthCheck *string
+		expectedChangeCnt   int
+	}{
+		{
+			name:                "creates health check for new record",
+			input:               []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			expectedCreated:     1,
+			expectedHealthCheck: aws.String("hc-new"),
+			expectedChangeCnt:   1,
+		},
+		{
+			name:                "updates existing health check in place",
+			input:               []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			healthChecks:        []types.HealthCheck{existingCheck("hc-1", types.HealthCheckTypeTcp)},
+			healthCheckTags:     map[string]string{"hc-1": primaryKey},
+			expectedUpdated:     []string{"hc-1"},
+			expectedHealthCheck: aws.String("hc-1"),
+			expectedChangeCnt:   1,
+		},
+		{
+			name:                "reuses health check already pointing at the address",
+			input:               []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.1"}},
+			healthChecks:        []types.HealthCheck{existingCheck("hc-1", types.HealthCheckTypeTcp)},
+			healthCheckTags:     map[string]string{"hc-1": primaryKey},
+			expectedHealthCheck: aws.String("hc-1"),
+			expectedChangeCnt:   1,
+		},
+		{
+			name:                "replaces health check whose type changed",
+			input:               []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			healthChecks:        []types.HealthCheck{existingCheck("hc-1", types.HealthCheckTypeHttp)},
+			healthCheckTags:     map[string]string{"hc-1": primaryKey},
+			expectedCreated:     1,
+			expectedDeleted:     []string{"hc-1"},
+			expectedHealthCheck: aws.String("hc-new"),
+			expectedChangeCnt:   1,
+		},
+		{
+			name:                "keeps replaced health check when the record update fails",
+			input:               []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			healthChecks:        []types.HealthCheck{existingCheck("hc-1", types.HealthCheckTypeHttp)},
+			healthCheckTags:     map[string]string{"hc-1": primaryKey},
+			changeErr:           changeErr,
+			expectedErr:         changeErr,
+			expectedFailed:      1,
+			expectedCreated:     1,
+			expectedHealthCheck: aws.String("hc-new"),
+			expectedChangeCnt:   1,
+		},
+		{
+			name:  "deletes health checks of records no longer configured",
+			input: []Record{{FQDN: other.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			healthChecks: []types.HealthCheck{
+				existingCheck("hc-1", types.HealthCheckTypeTcp),
+				existingCheck("hc-2", types.HealthCheckTypeTcp),
+				existingCheck("hc-3", types.HealthCheckTypeTcp),
+			},
+			healthCheckTags: map[string]string{
+				"hc-1": primaryKey,
+				"hc-2": "old.example.com.|A|",
+			},
+			expectedDeleted:   []string{"hc-2"},
+			expectedChangeCnt: 1,
+		},
+		{
+			name:              "deletes created health check when tagging fails",
+			input:             []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			tagErr:            tagErr,
+			expectedErr:       tagErr,
+			expectedFailed:    1,
+			expectedCreated:   1,
+			expectedDeleted:   []string{"hc-new"},
+			expectedChangeCnt: 0,
+		},
+		{
+			name:              "records not updated when health checks fail",
+			input:             []Record{{FQDN: primary.FQDN, RecordType: "A", IP: "192.0.2.10"}},
+			healthCheckErr:    healthCheckErr,
+			expectedErr:       healthCheckErr,
+			expectedFailed:    1,
+			expectedChangeCnt: 0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		name := testCase.name
+		input := testCase.input
+		mock := &mockUpdateGetter{
+			healthChecks:    testCase.healthChecks,
+			healthCheckTags: testCase.healthCheckTags,
+			healthCheckErr:  testCase.healthCheckErr,
+			changeErr:       testCase.changeErr,
+			tagErr:          testCase.tagErr,
+		}
+		tagged := testCase.tagErr == nil
+		expectedErr := testCase.expectedErr
+		expectedFailed := testCase.expectedFailed
+		expectedCreated := testCase.expectedCreated
+		expectedUpdated := testCase.expectedUpdated
+		expectedDeleted := testCase.expectedDeleted
+		expectedHealthCheck := testCase.expectedHealthCheck
+		expectedChangeCnt := testCase.expectedChangeCnt
+
+		t.Run(name, func(t *testing.T) {
+			updater := &Updater{
+				drivers: []awsClient{
+					{
+						client: mock,
+						zones:  []zone{{id: "Z123", records: []Record{primary, other}}},
+					},
+				},
+			}
+
+			updates, err := updater.UpdateRecords(context.Background(), input)
+			if expectedErr != nil {
+				require.ErrorIs(t, err, expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			failed := 0
+			for _, update := range updates {
+				failed += len(update.Failed)
+			}
+			assert.Equal(t, expectedFailed, failed)
+
+			assert.Len(t, mock.createdChecks, expectedCreated)
+			for _, created := range mock.createdChecks {
+				assert.Equal(t, "192.0.2.10", aws.ToString(created.HealthCheckConfig.IPAddress))
+				if tagged {
+					assert.Equal(t, primaryKey, mock.healthCheckTags["hc-new"])
+				}
+			}
+
+			updated := make([]string, 0, len(mock.updatedChecks))
+			for _, update := range mock.updatedChecks {
+				assert.Equal(t, "192.0.2.10", aws.ToString(update.IPAddress))
+				assert.Equal(t, int64(3), aws.ToInt64(update.HealthCheckVersion))
+				updated = append(updated, aws.ToString(update.HealthCheckId))
+			}
+			assert.ElementsMatch(t, expectedUpdated, updated)
+			assert.ElementsMatch(t, expectedDeleted, mock.deletedChecks)
+
+			require.Equal(t, expectedChangeCnt, mock.changeCalls)
+			if expectedHealthCheck != nil {
+				recordSet := mock.changeInputs[0].ChangeBatch.Changes[0].ResourceRecordSet
+				assert.Equal(t, *expectedHealthCheck, aws.ToString(recordSet.HealthCheckId))
+				assert.Equal(t, "home", aws.ToString(recordSet.SetIdentifier))
+				assert.Equal(t, types.ResourceRecordSetFailoverPrimary, recordSet.Failover)
+			}
+		})
+	}
+}
+
+func TestUpsertHealthCheck_ResetElements(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           healthCheck
+		expectedReset []types.ResettableElementName
+	}{
+		{
+			name: "settings still configured",
+			cfg:  healthCheck{checkType: "HTTPS", port: 443, resourcePath: "/healthz", host: "www.example.com"},
+		},
+		{
+			name:          "resource path removed",
+			cfg:           healthCheck{checkType: "HTTPS", port: 443, host: "www.example.com"},
+			expectedReset: []types.ResettableElementName{types.ResettableElementNameResourcePath},
+		},
+		{
+			name: "resource path and host removed",
+			cfg:  healthCheck{checkType: "HTTPS", port: 443},
+			expectedReset: []types.ResettableElementName{
+				types.ResettableElementNameResourcePath,
+				types.ResettableElementNameFullyQualifiedDomainName,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		cfg := testCase.cfg
+		expectedReset := testCase.expectedReset
+
+		t.Run(testCase.name, func(t *testing.T) {
+			mock := &mockUpdateGetter{}
+			client := awsClient{client: mock}
+			existing := managedHealthCheck{
+				id:      "hc-1",
+				version: 1,
+				config: &types.HealthCheckConfig{
+					Type:                     types.HealthCheckTypeHttps,
+					IPAddress:                aws.String("192.0.2.1"),
+					Port:                     aws.Int32(443),
+					ResourcePath:             aws.String("/healthz"),
+					FullyQualifiedDomainName: aws.String("www.example.com"),
+				},
+			}
+
+			id, err := client.upsertHealthCheck(context.Background(), "www.example.com.|A|", cfg, "192.0.2.10", []managedHealthCheck{existing})
+
+			require.NoError(t, err)
+			assert.Equal(t, "hc-1", id)
+			require.Len(t, mock.updatedChecks, 1)
+			assert.ElementsMatch(t, expectedReset, mock.updatedChecks[0].ResetElements)
+		})
+	}
+}
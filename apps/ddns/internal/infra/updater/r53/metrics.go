@@ -0,0 +1,46 @@
+package r53
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+)
+
+const providerRoute53 = "route53"
+
+// requestMetrics counts the Route53 API calls by operation, hosted zone and
+// outcome. Retries of a call are counted once.
+var requestMetrics = middleware.InitializeMiddlewareFunc("ddnsRequestMetrics",
+	func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		metrics.ProviderRequests.WithLabelValues(
+			providerRoute53,
+			awsmiddleware.GetOperationName(ctx),
+			requestZone(in.Parameters),
+			metrics.Result(err),
+		).Inc()
+
+		return out, metadata, err
+	})
+
+func addRequestMetrics(stack *middleware.Stack) error {
+	return stack.Initialize.Add(requestMetrics, middleware.After)
+}
+
+// requestZone returns the hosted zone of the calls made on a single zone.
+func requestZone(params any) string {
+	switch input := params.(type) {
+	case *route53.ListResourceRecordSetsInput:
+		return trimZoneID(aws.ToString(input.HostedZoneId))
+	case *route53.ChangeResourceRecordSetsInput:
+		return trimZoneID(aws.ToString(input.HostedZoneId))
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package r53
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/metrics"
+)
+
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRequestMetrics(t *testing.T) {
+	fail := false
+	client := route53.New(route53.Options{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		RetryMaxAttempts: 1,
+		APIOptions:       []func(*middleware.Stack) error{addRequestMetrics},
+		HTTPClient: httpClientFunc(func(*http.Request) (*http.Response, error) {
+			if fail {
+				return nil, errors.New("connection refused")
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/xml"}},
+				Body: io.NopCloser(strings.NewReader(
+					`<ListResourceRecordSetsResponse><ResourceRecordSets></ResourceRecordSets><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems></ListResourceRecordSetsResponse>`,
+				)),
+			}, nil
+		}),
+	})
+
+	input := &route53.ListResourceRecordSetsInput{HostedZoneId: aws.String("/hostedzone/ZMETRICS")}
+	success := metrics.ProviderRequests.WithLabelValues(providerRoute53, "ListResourceRecordSets", "ZMETRICS", metrics.OutcomeSuccess)
+	failed := metrics.ProviderRequests.WithLabelValues(providerRoute53, "ListResourceRecordSets", "ZMETRICS", metrics.OutcomeFailed)
+
+	_, err := client.ListResourceRecordSets(context.Background(), input)
+	assert.NoError(t, err)
+
+	fail = true
+	_, err = client.ListResourceRecordSets(context.Background(), input)
+	assert.Error(t, err)
+
+	assert.InDelta(t, 1, testutil.ToFloat64(success), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(failed), 0)
+}
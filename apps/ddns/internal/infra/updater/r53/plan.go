@@ -0,0 +1,78 @@
+package r53
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// PlannedChange is a change UpdateRecords would send to a hosted zone.
+type PlannedChange struct {
+	ZoneID     string
+	Action     string
+	FQDN       string
+	RecordType string
+	TTL        int
+	Values     []string
+	Previous   string
+	// HealthCheck is what happens to the health check of the record: create,
+	// update or reuse, empty without health check. HealthCheckID is the
+	// health check updated or reused.
+	HealthCheck   string
+	HealthCheckID string
+}
+
+// PlanRecords returns the changes UpdateRecords would send for recs without
+// calling any mutating API. Health checks are neither created nor updated,
+// the planned changes show what would happen to them.
+func (r *Updater) PlanRecords(ctx context.Context, recs []Record) ([]PlannedChange, error) {
+	planned := make([]PlannedChange, 0, len(recs))
+
+	for _, driver := range r.drivers {
+		zones, err := driver.hostedZones(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		driverRecords, err := driver.planHealthChecks(ctx, zones, withConfig(zones, records(recs).check()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, request := range driver.buildRequests(zones, driverRecords, driver.ptrChanges(ctx, zones, driverRecords)) {
+			for _, batch := range request.batches {
+				for i, change := range batch.input.ChangeBatch.Changes {
+					planned = append(planned, plannedChange(request.zoneID, change, batch.records[i]))
+				}
+			}
+		}
+	}
+
+	return planned, nil
+}
+
+func plannedChange(zoneID string, change types.Change, record Record) PlannedChange {
+	planned := PlannedChange{
+		ZoneID:   zoneID,
+		Action:   string(change.Action),
+		Previous: record.Previous,
+	}
+
+	if recordSet := change.ResourceRecordSet; recordSet != nil {
+		planned.FQDN = aws.ToString(recordSet.Name)
+		planned.RecordType = string(recordSet.Type)
+		planned.TTL = int(aws.ToInt64(recordSet.TTL))
+		for _, resourceRecord := range recordSet.ResourceRecords {
+			planned.Values = append(planned.Values, aws.ToString(resourceRecord.Value))
+		}
+	}
+
+	// the PTR changes of a record carry the record, not its health check
+	if change.Action != types.ChangeActionDelete && planned.RecordType == record.RecordType {
+		planned.HealthCheck = record.healthCheckAction
+		planned.HealthCheckID = record.HealthCheckID
+	}
+
+	return planned
+}
@@ -0,0 +1,153 @@
+package r53
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRecords(t *testing.T) {
+	mock := &mockUpdateGetter{
+		listOutput: &route53.ListResourceRecordSetsOutput{
+			ResourceRecordSets: []types.ResourceRecordSet{
+				{
+					Name:            aws.String("1.0.0.10.in-addr.arpa."),
+					Type:            types.RRTypePtr,
+					TTL:             aws.Int64(300),
+					ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+				},
+			},
+		},
+	}
+
+	updater := &Updater{
+		drivers: []awsClient{
+			{
+				client: mock,
+				zones: []zone{
+					{
+						id:      "Z123",
+						name:    "example.com.",
+						records: []Record{{FQDN: "vpn.example.com.", RecordType: "A", RecordTTL: 60, ptr: true}},
+					},
+					{id: "ZREV", name: "10.in-addr.arpa."},
+				},
+			},
+		},
+	}
+
+	planned, err := updater.PlanRecords(context.Background(), []Record{
+		{FQDN: "vpn.example.com.", IP: "10.0.0.2", Previous: "10.0.0.1", RecordType: "A"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []PlannedChange{
+		{
+			ZoneID:     "Z123",
+			Action:     "UPSERT",
+			FQDN:       "vpn.example.com.",
+			RecordType: "A",
+			TTL:        60,
+			Values:     []string{"10.0.0.2"},
+			Previous:   "10.0.0.1",
+		},
+		{
+			ZoneID:     "ZREV",
+			Action:     "UPSERT",
+			FQDN:       "2.0.0.10.in-addr.arpa.",
+			RecordType: "PTR",
+			TTL:        60,
+			Values:     []string{"vpn.example.com."},
+		},
+		{
+			ZoneID:     "ZREV",
+			Action:     "DELETE",
+			FQDN:       "1.0.0.10.in-addr.arpa.",
+			RecordType: "PTR",
+			TTL:        300,
+			Values:     []string{"vpn.example.com."},
+		},
+	}, planned)
+	assert.Zero(t, mock.changeCalls, "planning does not change any record")
+}
+
+func TestPlanRecords_HealthChecks(t *testing.T) {
+	primary := Record{
+		FQDN:          "vpn.example.com.",
+		RecordType:    "A",
+		RecordTTL:     60,
+		SetIdentifier: "home",
+		Failover:      "PRIMARY",
+		healthCheck:   &healthCheck{checkType: "TCP", port: 1194},
+	}
+	existing := types.HealthCheck{
+		Id:                 aws.String("hc-1"),
+		HealthCheckVersion: aws.Int64(3),
+		HealthCheckConfig: &types.HealthCheckConfig{
+			Type:      types.HealthCheckTypeTcp,
+			IPAddress: aws.String("192.0.2.1"),
+			Port:      aws.Int32(1194),
+		},
+	}
+
+	testCases := []struct {
+		name                  string
+		ip                    string
+		healthChecks          []types.HealthCheck
+		expectedHealthCheck   string
+		expectedHealthCheckID string
+	}{
+		{
+			name:                "create",
+			ip:                  "192.0.2.10",
+			expectedHealthCheck: "create",
+		},
+		{
+			name:                  "update",
+			ip:                    "192.0.2.10",
+			healthChecks:          []types.HealthCheck{existing},
+			expectedHealthCheck:   "update",
+			expectedHealthCheckID: "hc-1",
+		},
+		{
+			name:                  "reuse",
+			ip:                    "192.0.2.1",
+			healthChecks:          []types.HealthCheck{existing},
+			expectedHealthCheck:   "reuse",
+			expectedHealthCheckID: "hc-1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		ip := testCase.ip
+		mock := &mockUpdateGetter{
+			healthChecks:    testCase.healthChecks,
+			healthCheckTags: map[string]string{"hc-1": healthCheckKey(primary)},
+		}
+		expectedHealthCheck := testCase.expectedHealthCheck
+		expectedHealthCheckID := testCase.expectedHealthCheckID
+
+		t.Run(testCase.name, func(t *testing.T) {
+			updater := &Updater{
+				drivers: []awsClient{{client: mock, zones: []zone{{id: "Z123", records: []Record{primary}}}}},
+			}
+
+			planned, err := updater.PlanRecords(context.Background(), []Record{
+				{FQDN: primary.FQDN, RecordType: "A", IP: ip},
+			})
+
+			require.NoError(t, err)
+			require.Len(t, planned, 1)
+			assert.Equal(t, expectedHealthCheck, planned[0].HealthCheck)
+			assert.Equal(t, expectedHealthCheckID, planned[0].HealthCheckID)
+			assert.Empty(t, mock.createdChecks)
+			assert.Empty(t, mock.updatedChecks)
+			assert.Zero(t, mock.changeCalls, "planning does not change any record")
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package r53
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/awsutil"
+	"github.com/jorgesanchez-e/localenvironment/config"
+)
+
+const (
+	policyVersion      = "2012-10-17"
+	hostedZoneARN      = "arn:aws:route53:::hostedzone/"
+	changeARN          = "arn:aws:route53:::change/*"
+	healthCheckARN     = "arn:aws:route53:::healthcheck/*"
+	conditionAllValues = "ForAllValues:StringEquals"
+)
+
+// IAMPolicy returns the least privilege policy needed to manage the records
+// configured for account. Record changes are restricted to the configured
+// names and types through the Route53 ChangeResourceRecordSets condition
+// keys. Zones without ID are resolved at runtime, so they can only be
+// granted as hostedzone/* together with the listing permissions. PTR names
+// depend on the published addresses, so reverse zones get their own
+// statement limited to PTR records instead. The names of zones configured
+// only by ID are looked up at runtime to find the reverse zones, so those
+// zones are granted the PTR changes and GetHostedZone too.
+func IAMPolicy(account config.AWSConfig) awsutil.Policy {
+	zoneIDs := make([]string, 0, len(account.Zones))
+	reverseZoneIDs := make([]string, 0)
+	unnamedZoneIDs := make([]string, 0)
+	resolvesZones := len(account.Records) > 0
+	names := make(map[string]struct{})
+	recordTypes := make(map[string]struct{})
+	healthChecks := false
+	ptr := false
+
+	addRecords := func(records []config.RecordConfig) {
+		for _, record := range records {
+			names[strings.TrimSuffix(strings.ToLower(record.FQDN), ".")] = struct{}{}
+			recordTypes[record.RecordType] = struct{}{}
+			healthChecks = healthChecks || record.HealthCheck != nil
+			ptr = ptr || record.PTR
+		}
+	}
+
+	for _, zone := range account.Zones {
+		if zone.ID == "" {
+			resolvesZones = true
+		} else {
+			zoneIDs = append(zoneIDs, hostedZoneARN+zone.ID)
+			switch {
+			case zone.Name == "":
+				unnamedZoneIDs = append(unnamedZoneIDs, hostedZoneARN+zone.ID)
+			case strings.HasSuffix(normalizeName(zone.Name), reverseZoneSuffix):
+				reverseZoneIDs = append(reverseZoneIDs, hostedZoneARN+zone.ID)
+			}
+		}
+		addRecords(zone.Records)
+	}
+	addRecords(account.Records)
+
+	if ptr {
+		reverseZoneIDs = append(reverseZoneIDs, unnamedZoneIDs...)
+	}
+
+	if resolvesZones {
+		zoneIDs = []string{hostedZoneARN + "*"}
+		reverseZoneIDs = zoneIDs
+	}
+
+	statements := []awsutil.Statement{
+		{
+			Sid:      "ListRecords",
+			Effect:   "Allow",
+			Action:   []string{"route53:ListResourceRecordSets"},
+			Resource: zoneIDs,
+		},
+		{
+			Sid:      "ChangeRecords",
+			Effect:   "Allow",
+			Action:   []string{"route53:ChangeResourceRecordSets"},
+			Resource: zoneIDs,
+			Condition: map[string]map[string][]string{
+				conditionAllValues: {
+					"route53:ChangeResourceRecordSetsNormalizedRecordNames": sortedKeys(names),
+					"route53:ChangeResourceRecordSetsRecordTypes":           sortedKeys(recordTypes),
+					"route53:ChangeResourceRecordSetsActions":               {"UPSERT"},
+				},
+			},
+		},
+		{
+			Sid:      "GetChange",
+			Effect:   "Allow",
+			Action:   []string{"route53:GetChange"},
+			Resource: []string{changeARN},
+		},
+	}
+
+	if ptr && len(reverseZoneIDs) > 0 {
+		statements = append(statements, awsutil.Statement{
+			Sid:      "ChangeReverseRecords",
+			Effect:   "Allow",
+			Action:   []string{"route53:ChangeResourceRecordSets"},
+			Resource: reverseZoneIDs,
+			Condition: map[string]map[string][]string{
+				conditionAllValues: {
+					"route53:ChangeResourceRecordSetsRecordTypes": {ptrType},
+					"route53:ChangeResourceRecordSetsActions":     {"DELETE", "UPSERT"},
+				},
+			},
+		})
+	}
+
+	if ptr && len(unnamedZoneIDs) > 0 {
+		statements = append(statements, awsutil.Statement{
+			Sid:      "GetZoneNames",
+			Effect:   "Allow",
+			Action:   []string{"route53:GetHostedZone"},
+			Resource: unnamedZoneIDs,
+		})
+	}
+
+	if resolvesZones {
+		statements = append(statements, awsutil.Statement{
+			Sid:    "ResolveZones",
+			Effect: "Allow",
+			Action: []string{
+				"route53:ListHostedZones",
+				"route53:ListHostedZonesByName",
+				"route53:ListHostedZonesByVPC",
+			},
+			Resource: []string{"*"},
+		})
+	}
+
+	if healthChecks {
+		statements = append(statements,
+			awsutil.Statement{
+				Sid:      "CreateHealthChecks",
+				Effect:   "Allow",
+				Action:   []string{"route53:CreateHealthCheck", "route53:ListHealthChecks"},
+				Resource: []string{"*"},
+			},
+			awsutil.Statement{
+				Sid:    "ManageHealthChecks",
+				Effect: "Allow",
+				Action: []string{
+					"route53:ChangeTagsForResource",
+					"route53:DeleteHealthCheck",
+					"route53:ListTagsForResources",
+					"route53:UpdateHealthCheck",
+				},
+				Resource: []string{healthCheckARN},
+			},
+		)
+	}
+
+	return awsutil.Policy{
+		Version:   policyVersion,
+		Statement: statements,
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,217 @@
+package r53
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jorgesanchez-e/localenvironment/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	testCases := []struct {
+		name         string
+		account      config.AWSConfig
+		expectedJSON string
+	}{
+		{
+			name: "zones configured by id",
+			account: config.AWSConfig{
+				AccountName: "example",
+				Zones: []config.ZoneConfig{
+					{
+						ID: "Z111",
+						Records: []config.RecordConfig{
+							{FQDN: "VPN.example.com.", RecordType: "A"},
+							{FQDN: "vpn.example.com.", RecordType: "AAAA"},
+						},
+					},
+					{
+						ID: "Z222",
+						Records: []config.RecordConfig{
+							{FQDN: "app.example.org.", RecordType: "A"},
+						},
+					},
+				},
+			},
+			expectedJSON: `{
+				"Version": "2012-10-17",
+				"Statement": [
+					{
+						"Sid": "ListRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ListResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/Z111", "arn:aws:route53:::hostedzone/Z222"]
+					},
+					{
+						"Sid": "ChangeRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ChangeResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/Z111", "arn:aws:route53:::hostedzone/Z222"],
+						"Condition": {
+							"ForAllValues:StringEquals": {
+								"route53:ChangeResourceRecordSetsNormalizedRecordNames": ["app.example.org", "vpn.example.com"],
+								"route53:ChangeResourceRecordSetsRecordTypes": ["A", "AAAA"],
+								"route53:ChangeResourceRecordSetsActions": ["UPSERT"]
+							}
+						}
+					},
+					{
+						"Sid": "GetChange",
+						"Effect": "Allow",
+						"Action": ["route53:GetChange"],
+						"Resource": ["arn:aws:route53:::change/*"]
+					}
+				]
+			}`,
+		},
+		{
+			name: "zones resolved at runtime with health checks",
+			account: config.AWSConfig{
+				AccountName: "example",
+				Zones: []config.ZoneConfig{
+					{
+						ID: "Z111",
+						Records: []config.RecordConfig{
+							{
+								FQDN:        "vpn.example.com.",
+								RecordType:  "A",
+								HealthCheck: &config.HealthCheckConfig{Type: "TCP", Port: 1194},
+							},
+						},
+					},
+				},
+				Records: []config.RecordConfig{
+					{FQDN: "home.example.org.", RecordType: "A"},
+				},
+			},
+			expectedJSON: `{
+				"Version": "2012-10-17",
+				"Statement": [
+					{
+						"Sid": "ListRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ListResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/*"]
+					},
+					{
+						"Sid": "ChangeRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ChangeResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/*"],
+						"Condition": {
+							"ForAllValues:StringEquals": {
+								"route53:ChangeResourceRecordSetsNormalizedRecordNames": ["home.example.org", "vpn.example.com"],
+								"route53:ChangeResourceRecordSetsRecordTypes": ["A"],
+								"route53:ChangeResourceRecordSetsActions": ["UPSERT"]
+							}
+						}
+					},
+					{
+						"Sid": "GetChange",
+						"Effect": "Allow",
+						"Action": ["route53:GetChange"],
+						"Resource": ["arn:aws:route53:::change/*"]
+					},
+					{
+						"Sid": "ResolveZones",
+						"Effect": "Allow",
+						"Action": ["route53:ListHostedZones", "route53:ListHostedZonesByName", "route53:ListHostedZonesByVPC"],
+						"Resource": ["*"]
+					},
+					{
+						"Sid": "CreateHealthChecks",
+						"Effect": "Allow",
+						"Action": ["route53:CreateHealthCheck", "route53:ListHealthChecks"],
+						"Resource": ["*"]
+					},
+					{
+						"Sid": "ManageHealthChecks",
+						"Effect": "Allow",
+						"Action": ["route53:ChangeTagsForResource", "route53:DeleteHealthCheck", "route53:ListTagsForResources", "route53:UpdateHealthCheck"],
+						"Resource": ["arn:aws:route53:::healthcheck/*"]
+					}
+				]
+			}`,
+		},
+		{
+			name: "reverse zone for ptr records",
+			account: config.AWSConfig{
+				AccountName: "example",
+				Zones: []config.ZoneConfig{
+					{
+						ID: "Z111",
+						Records: []config.RecordConfig{
+							{FQDN: "vpn.example.com.", RecordType: "AAAA", PTR: true},
+						},
+					},
+					{
+						ID:   "Z333",
+						Name: "8.b.d.0.1.0.0.2.ip6.arpa",
+					},
+				},
+			},
+			expectedJSON: `{
+				"Version": "2012-10-17",
+				"Statement": [
+					{
+						"Sid": "ListRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ListResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/Z111", "arn:aws:route53:::hostedzone/Z333"]
+					},
+					{
+						"Sid": "ChangeRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ChangeResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/Z111", "arn:aws:route53:::hostedzone/Z333"],
+						"Condition": {
+							"ForAllValues:StringEquals": {
+								"route53:ChangeResourceRecordSetsNormalizedRecordNames": ["vpn.example.com"],
+								"route53:ChangeResourceRecordSetsRecordTypes": ["AAAA"],
+								"route53:ChangeResourceRecordSetsActions": ["UPSERT"]
+							}
+						}
+					},
+					{
+						"Sid": "GetChange",
+						"Effect": "Allow",
+						"Action": ["route53:GetChange"],
+						"Resource": ["arn:aws:route53:::change/*"]
+					},
+					{
+						"Sid": "ChangeReverseRecords",
+						"Effect": "Allow",
+						"Action": ["route53:ChangeResourceRecordSets"],
+						"Resource": ["arn:aws:route53:::hostedzone/Z333", "arn:aws:route53:::hostedzone/Z111"],
+						"Condition": {
+							"ForAllValues:StringEquals": {
+								"route53:ChangeResourceRecordSetsRecordTypes": ["PTR"],
+								"route53:ChangeResourceRecordSetsActions": ["DELETE", "UPSERT"]
+							}
+						}
+					},
+					{
+						"Sid": "GetZoneNames",
+						"Effect": "Allow",
+						"Action": ["route53:GetHostedZone"],
+						"Resource": ["arn:aws:route53:::hostedzone/Z111"]
+					}
+				]
+			}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		account := testCase.account
+		expectedJSON := testCase.expectedJSON
+
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := json.Marshal(IAMPolicy(account))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, expectedJSON, string(got))
+		})
+	}
+}
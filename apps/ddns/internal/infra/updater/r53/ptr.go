@@ -0,0 +1,221 @@
+package r53
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ptrType           = "PTR"
+	reverseZoneSuffix = ".arpa."
+	reverseIPv4Suffix = "in-addr.arpa."
+	reverseIPv6Suffix = "ip6.arpa."
+)
+
+// pendingChanges are changes to send to a zone on behalf of records managed
+// in other zones.
+type pendingChanges struct {
+	changes []types.Change
+	records []Record
+}
+
+// ptrChanges returns, grouped by reverse zone ID, the changes pointing the
+// reverse record of the new address of each record with PTR enabled at its
+// FQDN. The reverse record of the previous address is removed when it still
+// points at the record. Addresses without a configured reverse zone are
+// skipped.
+func (ac awsClient) ptrChanges(ctx context.Context, zones []zone, inputRecords []Record) map[string]*pendingChanges {
+	pending := make(map[string]*pendingChanges)
+	reverse := reverseZones(zones)
+
+	add := func(zoneID string, change types.Change, record Record) {
+		if _, ok := pending[zoneID]; !ok {
+			pending[zoneID] = &pendingChanges{}
+		}
+
+		pending[zoneID].changes = append(pending[zoneID].changes, change)
+		pending[zoneID].records = append(pending[zoneID].records, record)
+	}
+
+	for _, record := range inputRecords {
+		if !record.ptr || !addressTypes[record.RecordType] {
+			continue
+		}
+
+		target := normalizeName(record.FQDN)
+		ctx := logging.WithFields(ctx, log.Fields{logging.FieldFQDN: record.FQDN, logging.FieldFamily: logging.Family(record.RecordType)})
+
+		name, owner, err := reverseZone(reverse, record.IP)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("reverse dns of %s not updated: %v", record.FQDN, err)
+			continue
+		}
+
+		ptr := Record{FQDN: name, IP: target, RecordType: ptrType, RecordTTL: record.RecordTTL}
+		change, err := ac.ptrUpsert(ctx, owner, ptr)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("reverse dns of %s not updated: %v", record.FQDN, err)
+			continue
+		}
+
+		add(owner.id, change, ptr)
+
+		if record.Previous == "" || record.Previous == record.IP {
+			continue
+		}
+
+		zoneID, change, stale, err := ac.stalePTR(ctx, reverse, record.Previous, target)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("reverse dns of previous address %s of %s not removed: %v", record.Previous, record.FQDN, err)
+			continue
+		}
+
+		if stale != nil {
+			add(zoneID, change, *stale)
+		}
+	}
+
+	return pending
+}
+
+// ptrUpsert returns the change pointing the reverse record of ptr at its
+// target, keeping the other names the record set already points at.
+func (ac awsClient) ptrUpsert(ctx context.Context, owner zone, ptr Record) (types.Change, error) {
+	recordSet := resourceRecordSet(ptr, ptr)
+
+	current, err := ac.ptrRecordSet(ctx, owner, ptr.FQDN)
+	if err != nil {
+		return types.Change{}, err
+	}
+
+	if current != nil {
+		for _, resourceRecord := range current.ResourceRecords {
+			if normalizeName(aws.ToString(resourceRecord.Value)) != ptr.IP {
+				recordSet.ResourceRecords = append(recordSet.ResourceRecords, resourceRecord)
+			}
+		}
+	}
+
+	return types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: recordSet}, nil
+}
+
+// stalePTR returns the change removing target from the reverse record of
+// previous, or a nil record when the reverse record does not point at it.
+func (ac awsClient) stalePTR(ctx context.Context, zones []zone, previous, target string) (string, types.Change, *Record, error) {
+	name, owner, err := reverseZone(zones, previous)
+	if err != nil {
+		return "", types.Change{}, nil, err
+	}
+
+	recordSet, err := ac.ptrRecordSet(ctx, owner, name)
+	if err != nil || recordSet == nil {
+		return "", types.Change{}, nil, err
+	}
+
+	remaining := make([]types.ResourceRecord, 0, len(recordSet.ResourceRecords))
+	for _, resourceRecord := range recordSet.ResourceRecords {
+		if normalizeName(aws.ToString(resourceRecord.Value)) != target {
+			remaining = append(remaining, resourceRecord)
+		}
+	}
+
+	if len(remaining) == len(recordSet.ResourceRecords) {
+		return "", types.Change{}, nil, nil
+	}
+
+	stale := &Record{FQDN: name, IP: target, RecordType: ptrType, RecordTTL: int(aws.ToInt64(recordSet.TTL))}
+
+	// deletions must match the record set exactly, other names keep the set alive
+	if len(remaining) == 0 {
+		return owner.id, types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: recordSet}, stale, nil
+	}
+
+	recordSet.ResourceRecords = remaining
+	return owner.id, types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: recordSet}, stale, nil
+}
+
+// ptrRecordSet returns the PTR record set named name in the reverse zone
+// owner, or nil when it does not exist.
+func (ac awsClient) ptrRecordSet(ctx context.Context, owner zone, name string) (*types.ResourceRecordSet, error) {
+	output, err := ac.client.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(owner.id),
+		StartRecordName: aws.String(name),
+		StartRecordType: types.RRTypePtr,
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recordSet := range output.ResourceRecordSets {
+		if normalizeName(aws.ToString(recordSet.Name)) == name && recordSet.Type == types.RRTypePtr {
+			return &recordSet, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// reverseZones returns the configured zones that are reverse zones, with
+// their names normalized.
+func reverseZones(zones []zone) []zone {
+	reverse := make([]zone, 0)
+	for _, z := range zones {
+		if z.name == "" {
+			continue
+		}
+
+		z.name = normalizeName(z.name)
+		if strings.HasSuffix(z.name, reverseZoneSuffix) {
+			reverse = append(reverse, z)
+		}
+	}
+
+	return reverse
+}
+
+// reverseZone returns the reverse name of ip and the zone it belongs to.
+func reverseZone(zones []zone, ip string) (string, zone, error) {
+	name, err := reverseName(ip)
+	if err != nil {
+		return "", zone{}, err
+	}
+
+	owner, err := zoneForFQDN(zones, name)
+	if err != nil {
+		return "", zone{}, err
+	}
+
+	return name, owner, nil
+}
+
+// reverseName returns the name of the PTR record of ip.
+func reverseName(ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", ip, err)
+	}
+
+	addr = addr.Unmap()
+	if addr.Is4() {
+		octets := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.%s", octets[3], octets[2], octets[1], octets[0], reverseIPv4Suffix), nil
+	}
+
+	bytes := addr.As16()
+	var name strings.Builder
+	for i := len(bytes) - 1; i >= 0; i-- {
+		fmt.Fprintf(&name, "%x.%x.", bytes[i]&0x0f, bytes[i]>>4)
+	}
+	name.WriteString(reverseIPv6Suffix)
+
+	return name.String(), nil
+}
@@ -0,0 +1,265 @@
+package r53
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseName(t *testing.T) {
+	testCases := []struct {
+		name          string
+		ip            string
+		expectedName  string
+		expectedError bool
+	}{
+		{
+			name:         "ipv4",
+			ip:           "10.1.2.3",
+			expectedName: "3.2.1.10.in-addr.arpa.",
+		},
+		{
+			name:         "ipv4 mapped ipv6",
+			ip:           "::ffff:10.1.2.3",
+			expectedName: "3.2.1.10.in-addr.arpa.",
+		},
+		{
+			name:         "ipv6",
+			ip:           "2001:db8::567:89ab",
+			expectedName: "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		},
+		{
+			name:          "invalid address",
+			ip:            "vpn.example.com",
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ip := testCase.ip
+		expectedName := testCase.expectedName
+		expectedError := testCase.expectedError
+
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := reverseName(ip)
+
+			assert.Equal(t, expectedName, got)
+			assert.Equal(t, expectedError, err != nil)
+		})
+	}
+}
+
+func TestPTRChanges(t *testing.T) {
+	zones := []zone{
+		{id: "Z123", name: "example.com"},
+		{id: "ZREV4", name: "10.in-addr.arpa"},
+		{id: "ZREV6", name: "8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	testCases := []struct {
+		name            string
+		records         []Record
+		listOutput      *route53.ListResourceRecordSetsOutput
+		expectedChanges map[string][]types.Change
+	}{
+		{
+			name: "records without ptr are ignored",
+			records: []Record{
+				{FQDN: "vpn.example.com.", IP: "10.0.0.1", RecordType: "A", RecordTTL: 60},
+			},
+			expectedChanges: map[string][]types.Change{},
+		},
+		{
+			name: "address without reverse zone",
+			records: []Record{
+				{FQDN: "vpn.example.com.", IP: "192.0.2.1", RecordType: "A", RecordTTL: 60, ptr: true},
+			},
+			expectedChanges: map[string][]types.Change{},
+		},
+		{
+			name: "upserts the reverse record of the new address",
+			records: []Record{
+				{FQDN: "vpn.example.com.", IP: "2001:db8::1", RecordType: "AAAA", RecordTTL: 60, ptr: true},
+			},
+			expectedChanges: map[string][]types.Change{
+				"ZREV6": {
+					{
+						Action: types.ChangeActionUpsert,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name:            aws.String("1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."),
+							Type:            types.RRTypePtr,
+							TTL:             aws.Int64(60),
+							ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "keeps the other names of the new reverse record",
+			records: []Record{
+				{FQDN: "vpn.example.com.", IP: "10.0.0.2", RecordType: "A", RecordTTL: 60, ptr: true},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name: aws.String("2.0.0.10.in-addr.arpa."),
+						Type: types.RRTypePtr,
+						TTL:  aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String("nas.example.com.")},
+							{Value: aws.String("vpn.example.com.")},
+						},
+					},
+				},
+			},
+			expectedChanges: map[string][]types.Change{
+				"ZREV4": {
+					{
+						Action: types.ChangeActionUpsert,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name: aws.String("2.0.0.10.in-addr.arpa."),
+							Type: types.RRTypePtr,
+							TTL:  aws.Int64(60),
+							ResourceRecords: []types.ResourceRecord{
+								{Value: aws.String("vpn.example.com.")},
+								{Value: aws.String("nas.example.com.")},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "deletes the reverse record of the previous address",
+			records: []Record{
+				{FQDN: "vpn.example.com", IP: "10.0.0.2", Previous: "10.0.0.1", RecordType: "A", RecordTTL: 60, ptr: true},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name:            aws.String("1.0.0.10.in-addr.arpa."),
+						Type:            types.RRTypePtr,
+						TTL:             aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+					},
+				},
+			},
+			expectedChanges: map[string][]types.Change{
+				"ZREV4": {
+					{
+						Action: types.ChangeActionUpsert,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name:            aws.String("2.0.0.10.in-addr.arpa."),
+							Type:            types.RRTypePtr,
+							TTL:             aws.Int64(60),
+							ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+						},
+					},
+					{
+						Action: types.ChangeActionDelete,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name:            aws.String("1.0.0.10.in-addr.arpa."),
+							Type:            types.RRTypePtr,
+							TTL:             aws.Int64(300),
+							ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "keeps the other names of the previous reverse record",
+			records: []Record{
+				{FQDN: "vpn.example.com.", IP: "10.0.0.2", Previous: "10.0.0.1", RecordType: "A", RecordTTL: 60, ptr: true},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name: aws.String("1.0.0.10.in-addr.arpa."),
+						Type: types.RRTypePtr,
+						TTL:  aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String("vpn.example.com.")},
+							{Value: aws.String("nas.example.com.")},
+						},
+					},
+				},
+			},
+			expectedChanges: map[string][]types.Change{
+				"ZREV4": {
+					{
+						Action: types.ChangeActionUpsert,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name:            aws.String("2.0.0.10.in-addr.arpa."),
+							Type:            types.RRTypePtr,
+							TTL:             aws.Int64(60),
+							ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+						},
+					},
+					{
+						Action: types.ChangeActionUpsert,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name:            aws.String("1.0.0.10.in-addr.arpa."),
+							Type:            types.RRTypePtr,
+							TTL:             aws.Int64(300),
+							ResourceRecords: []types.ResourceRecord{{Value: aws.String("nas.example.com.")}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "previous reverse record owned by another name",
+			records: []Record{
+				{FQDN: "vpn.example.com.", IP: "10.0.0.2", Previous: "10.0.0.1", RecordType: "A", RecordTTL: 60, ptr: true},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name:            aws.String("1.0.0.10.in-addr.arpa."),
+						Type:            types.RRTypePtr,
+						TTL:             aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{{Value: aws.String("nas.example.com.")}},
+					},
+				},
+			},
+			expectedChanges: map[string][]types.Change{
+				"ZREV4": {
+					{
+						Action: types.ChangeActionUpsert,
+						ResourceRecordSet: &types.ResourceRecordSet{
+							Name:            aws.String("2.0.0.10.in-addr.arpa."),
+							Type:            types.RRTypePtr,
+							TTL:             aws.Int64(60),
+							ResourceRecords: []types.ResourceRecord{{Value: aws.String("vpn.example.com.")}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		records := testCase.records
+		listOutput := testCase.listOutput
+		expectedChanges := testCase.expectedChanges
+
+		t.Run(testCase.name, func(t *testing.T) {
+			client := awsClient{client: &mockUpdateGetter{listOutput: listOutput}}
+
+			pending := client.ptrChanges(context.Background(), zones, records)
+
+			got := make(map[string][]types.Change, len(pending))
+			for zoneID, changes := range pending {
+				got[zoneID] = changes.changes
+				assert.Len(t, changes.records, len(changes.changes))
+			}
+			assert.Equal(t, expectedChanges, got)
+		})
+	}
+}
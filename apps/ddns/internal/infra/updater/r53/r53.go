@@ -2,11 +2,16 @@ package r53
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/awsutil"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
 	"github.com/jorgesanchez-e/localenvironment/config"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,56 +19,121 @@ import (
 type updateGetter interface {
 	ListResourceRecordSets(context.Context, *route53.ListResourceRecordSetsInput, ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error)
 	ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error)
+	GetHostedZone(ctx context.Context, params *route53.GetHostedZoneInput, optFns ...func(*route53.Options)) (*route53.GetHostedZoneOutput, error)
+	ListHostedZones(ctx context.Context, params *route53.ListHostedZonesInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error)
+	ListHostedZonesByName(ctx context.Context, params *route53.ListHostedZonesByNameInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error)
+	ListHostedZonesByVPC(ctx context.Context, params *route53.ListHostedZonesByVPCInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesByVPCOutput, error)
+	ListHealthChecks(ctx context.Context, params *route53.ListHealthChecksInput, optFns ...func(*route53.Options)) (*route53.ListHealthChecksOutput, error)
+	CreateHealthCheck(ctx context.Context, params *route53.CreateHealthCheckInput, optFns ...func(*route53.Options)) (*route53.CreateHealthCheckOutput, error)
+	UpdateHealthCheck(ctx context.Context, params *route53.UpdateHealthCheckInput, optFns ...func(*route53.Options)) (*route53.UpdateHealthCheckOutput, error)
+	DeleteHealthCheck(ctx context.Context, params *route53.DeleteHealthCheckInput, optFns ...func(*route53.Options)) (*route53.DeleteHealthCheckOutput, error)
+	ChangeTagsForResource(ctx context.Context, params *route53.ChangeTagsForResourceInput, optFns ...func(*route53.Options)) (*route53.ChangeTagsForResourceOutput, error)
+	ListTagsForResources(ctx context.Context, params *route53.ListTagsForResourcesInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourcesOutput, error)
 }
 
 type awsClient struct {
-	client updateGetter
-	zones  []zone
+	account         string
+	client          updateGetter
+	zones           []zone
+	records         []Record
+	cache           *zoneCache
+	isolateFailures bool
 }
 
 type zone struct {
-	id      string
-	records []Record
+	id        string
+	name      string
+	private   bool
+	vpcID     string
+	vpcRegion string
+	records   []Record
 }
 
 type Record struct {
-	FQDN       string
-	IP         string
-	RecordType string
-	RecordTTL  int
+	FQDN          string
+	IP            string
+	RecordType    string
+	RecordTTL     int
+	SetIdentifier string
+	Failover      string
+	HealthCheckID string
+	Previous      string
+	ZoneID        string
+	Source        *config.IPSourceConfig
+	healthCheck   *healthCheck
+	// healthCheckAction is set by planHealthChecks.
+	healthCheckAction string
+	ptr               bool
 }
 
 type records []Record
 
+var (
+	addressTypes = map[string]bool{"A": true, "AAAA": true}
+	derivedTypes = map[string]bool{"TXT": true, "CNAME": true, "SRV": true, "HTTPS": true, "SVCB": true}
+)
+
 type Updater struct {
 	drivers []awsClient
 }
 
-func NewR53(accounts []config.AWSConfig) *Updater {
+// ZoneUpdate is the outcome of updating the records of a hosted zone. Atomic
+// reports whether all the changes of the zone were sent in a single change
+// batch, so they were applied all together or not at all.
+type ZoneUpdate struct {
+	ZoneID    string
+	Atomic    bool
+	ChangeIDs []string
+	Updated   []Record
+	Failed    []Record
+	Err       error
+}
+
+func NewR53(accounts []config.AWSConfig) (*Updater, error) {
 	updater := &Updater{
 		drivers: []awsClient{},
 	}
 
 	for _, account := range accounts {
 		awsConfig := AWSConfig(account)
-		creds := awsConfig.getCredentials()
-		awsRoute53Client := route53.NewFromConfig(aws.Config{Credentials: creds, Region: account.Region})
+		cfg, err := awsutil.LoadConfig(context.Background(), account)
+		if err != nil {
+			return nil, err
+		}
+
+		awsRoute53Client := route53.NewFromConfig(cfg, func(o *route53.Options) {
+			o.APIOptions = append(o.APIOptions, addRequestSpans, addRequestMetrics)
+		})
 		awsDriver := awsClient{
-			client: awsRoute53Client,
-			zones:  awsConfig.zones(),
+			account:         account.AccountName,
+			client:          awsRoute53Client,
+			zones:           awsConfig.zones(),
+			records:         awsConfig.records(),
+			cache:           newZoneCache(time.Duration(account.ZoneCacheSeconds) * time.Second),
+			isolateFailures: account.IsolateFailures,
 		}
 
 		updater.drivers = append(updater.drivers, awsDriver)
 	}
 
-	return updater
+	return updater, nil
 }
 
 func (r *Updater) GetRecords(ctx context.Context, domains []string) ([]Record, error) {
 	records := make([]Record, 0)
+	requested := make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		requested[domain] = struct{}{}
+	}
 
 	for _, driver := range r.drivers {
-		for _, zone := range driver.zones {
+		ctx := driver.logContext(ctx)
+		zones, err := driver.hostedZones(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, zone := range zones {
 			params := &route53.ListResourceRecordSetsInput{
 				HostedZoneId: aws.String(zone.id),
 			}
@@ -76,15 +146,17 @@ func (r *Updater) GetRecords(ctx context.Context, domains []string) ([]Record, e
 				}
 				for _, cloudRecord := range page.ResourceRecordSets {
 					localRecord := translateRecord(&cloudRecord)
-					if localRecord == nil {
+					if localRecord == nil || localRecord.SetIdentifier != zone.setIdentifier(localRecord) {
 						continue
 					}
 
-					if localRecord.RecordType == "A" || localRecord.RecordType == "AAAA" {
-						for _, domain := range domains {
-							if domain == localRecord.FQDN {
-								records = append(records, *localRecord)
-							}
+					if zone.manages(localRecord) {
+						configured, _ := zone.configured(*localRecord)
+						localRecord.ZoneID = zone.id
+						localRecord.Source = configured.Source
+
+						if _, ok := requested[localRecord.FQDN]; ok {
+							records = append(records, *localRecord)
 						}
 					}
 				}
@@ -95,87 +167,223 @@ func (r *Updater) GetRecords(ctx context.Context, domains []string) ([]Record, e
 	return records, nil
 }
 
-func (r *Updater) UpdateRecords(ctx context.Context, recs []Record) error {
-	ch := make(chan struct{})
+func (r *Updater) UpdateRecords(ctx context.Context, recs []Record) ([]ZoneUpdate, error) {
+	driverZones := make([][]zone, 0, len(r.drivers))
+	for _, driver := range r.drivers {
+		zones, err := driver.hostedZones(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		driverZones = append(driverZones, zones)
+	}
+
 	wg := sync.WaitGroup{}
+	updates := make([][]ZoneUpdate, len(r.drivers))
+	requested := make([][]Record, len(r.drivers))
+	obsoleteHealthChecks := make([]map[string][]string, len(r.drivers))
+	for i, driver := range r.drivers {
+		ctx := driver.logContext(ctx)
+		inputRecords := withConfig(driverZones[i], records(recs).check())
+		driverRecords, obsolete, err := driver.ensureHealthChecks(ctx, driverZones[i], inputRecords)
+		requested[i], obsoleteHealthChecks[i] = driverRecords, obsolete
+		if err != nil {
+			// updating the records without their health check would detach it from the record set
+			logging.FromContext(ctx).Errorf("failed to update health checks, records not updated: %v", err)
+			updates[i] = failedUpdates(driverZones[i], inputRecords, fmt.Errorf("failed to update health checks: %w", err))
+			continue
+		}
 
-	for _, driver := range r.drivers {
-		for _, request := range driver.buildRequests(records(recs).check()) {
+		requests := driver.buildRequests(driverZones[i], driverRecords, driver.ptrChanges(ctx, driverZones[i], driverRecords))
+		updates[i] = make([]ZoneUpdate, len(requests))
+		for j, request := range requests {
 			wg.Add(1)
-			go driver.do(ctx, &wg, ch, request)
+			go driver.do(ctx, &wg, request, &updates[i][j])
 		}
 	}
 
-	close(ch)
 	wg.Wait()
 
-	return nil
+	for i, driver := range r.drivers {
+		driver.deleteHealthChecks(driver.logContext(ctx), removable(obsoleteHealthChecks[i], requested[i], updates[i]))
+	}
+
+	var errs error
+	zoneUpdates := make([]ZoneUpdate, 0, len(updates))
+	for _, driverUpdates := range updates {
+		for _, update := range driverUpdates {
+			errs = errors.Join(errs, update.Err)
+			zoneUpdates = append(zoneUpdates, update)
+		}
+	}
+
+	return zoneUpdates, errs
 }
 
-func (ac awsClient) buildRequests(inputRecords []Record) []*route53.ChangeResourceRecordSetsInput {
-	requests := make([]*route53.ChangeResourceRecordSetsInput, 0, len(ac.zones))
+// failedUpdates reports the records of every zone as failed with err.
+func failedUpdates(zones []zone, inputRecords []Record, err error) []ZoneUpdate {
+	updates := make([]ZoneUpdate, 0, len(zones))
+	for _, zone := range zones {
+		failed := make([]Record, 0, len(inputRecords))
+		for _, record := range inputRecords {
+			if _, ok := zone.configured(record); ok && (record.ZoneID == "" || record.ZoneID == zone.id) {
+				failed = append(failed, record)
+			}
+		}
 
-	for _, zone := range ac.zones {
-		batch := new(types.ChangeBatch)
+		if len(failed) > 0 {
+			updates = append(updates, ZoneUpdate{ZoneID: zone.id, Failed: failed, Err: err})
+		}
+	}
+
+	return updates
+}
+
+func (ac awsClient) buildRequests(zones []zone, inputRecords []Record, reverse map[string]*pendingChanges) []zoneRequest {
+	requests := make([]zoneRequest, 0, len(zones))
+
+	for _, zone := range zones {
+		changes := make([]types.Change, 0, len(inputRecords))
+		changed := make([]Record, 0, len(inputRecords))
 
 		for _, record := range zone.records {
-			changes := make([]types.Change, 0, len(inputRecords))
 			for _, ir := range inputRecords {
-				if ir.FQDN == record.FQDN && ir.RecordType == record.RecordType {
+				if ir.FQDN == record.FQDN && ir.RecordType == record.RecordType && (ir.ZoneID == "" || ir.ZoneID == zone.id) {
 					changes = append(changes, types.Change{
-						Action: types.ChangeActionUpsert,
-						ResourceRecordSet: &types.ResourceRecordSet{
-							Name: aws.String(ir.FQDN),
-							Type: types.RRType(ir.RecordType),
-							TTL:  aws.Int64(int64(record.RecordTTL)),
-							ResourceRecords: []types.ResourceRecord{
-								{
-									Value: aws.String(ir.IP),
-								},
-							},
-						},
+						Action:            types.ChangeActionUpsert,
+						ResourceRecordSet: resourceRecordSet(record, ir),
 					})
+					changed = append(changed, ir)
 				}
 			}
+		}
 
-			if len(changes) > 0 {
-				batch.Changes = append(batch.Changes, changes...)
-			}
+		if pending, ok := reverse[zone.id]; ok {
+			changes = append(changes, pending.changes...)
+			changed = append(changed, pending.records...)
 		}
 
-		if len(batch.Changes) == 0 {
+		if len(changes) == 0 {
 			continue
 		}
 
-		requests = append(requests, &route53.ChangeResourceRecordSetsInput{
-			HostedZoneId: aws.String(zone.id),
-			ChangeBatch:  batch,
+		requests = append(requests, zoneRequest{
+			zoneID:  zone.id,
+			batches: splitChanges(zone.id, changes, changed),
 		})
 	}
 
 	return requests
 }
 
-func (ac awsClient) do(ctx context.Context, wg *sync.WaitGroup, ch <-chan struct{}, request *route53.ChangeResourceRecordSetsInput) {
-	defer wg.Done()
+func resourceRecordSet(record, ir Record) *types.ResourceRecordSet {
+	recordSet := &types.ResourceRecordSet{
+		Name: aws.String(ir.FQDN),
+		Type: types.RRType(ir.RecordType),
+		TTL:  aws.Int64(int64(record.RecordTTL)),
+		ResourceRecords: []types.ResourceRecord{
+			{
+				Value: aws.String(ir.IP),
+			},
+		},
+	}
+
+	if record.SetIdentifier != "" {
+		recordSet.SetIdentifier = aws.String(record.SetIdentifier)
+	}
+
+	if record.Failover != "" {
+		recordSet.Failover = types.ResourceRecordSetFailover(record.Failover)
+	}
+
+	if ir.HealthCheckID != "" {
+		recordSet.HealthCheckId = aws.String(ir.HealthCheckID)
+	}
+
+	return recordSet
+}
+
+// configured returns the record of the zone configuration matching record.
+func (z zone) configured(record Record) (Record, bool) {
+	for _, configured := range z.records {
+		if normalizeName(configured.FQDN) == normalizeName(record.FQDN) && configured.RecordType == record.RecordType {
+			return configured, true
+		}
+	}
+
+	return Record{}, false
+}
+
+// manages reports whether the record set is managed by ddns: any A or AAAA
+// record of the requested domains, and the derived record types only when
+// they are configured in the zone.
+func (z zone) manages(record *Record) bool {
+	if addressTypes[record.RecordType] {
+		return true
+	}
+
+	_, configured := z.configured(*record)
+	return configured && derivedTypes[record.RecordType]
+}
+
+// setIdentifier returns the set identifier configured for the record, record
+// sets with a different identifier (e.g. the secondary of a failover pair)
+// are not managed by ddns.
+func (z zone) setIdentifier(record *Record) string {
+	configured, _ := z.configured(*record)
+	return configured.SetIdentifier
+}
+
+// withConfig completes the records with the TTL, routing, health check and
+// reverse DNS settings configured for them in their zone.
+func withConfig(zones []zone, inputRecords []Record) []Record {
+	completed := make([]Record, 0, len(inputRecords))
+	for _, record := range inputRecords {
+		for _, zone := range zones {
+			if record.ZoneID != "" && record.ZoneID != zone.id {
+				continue
+			}
 
-	select {
-	case <-ch:
-		if _, err := ac.client.ChangeResourceRecordSets(ctx, request); err != nil {
-			log.Errorf("failed to update records for aws hosted zone %s: %v", *request.HostedZoneId, err)
-		} else {
-			log.Infof("records updated successfully for aws hosted zone %s", *request.HostedZoneId)
+			if configured, ok := zone.configured(record); ok {
+				record.RecordTTL = configured.RecordTTL
+				record.SetIdentifier = configured.SetIdentifier
+				record.Failover = configured.Failover
+				record.healthCheck = configured.healthCheck
+				record.ptr = configured.ptr
+				break
+			}
 		}
-	case <-ctx.Done():
-		return
+
+		completed = append(completed, record)
 	}
+
+	return completed
+}
+
+func (ac awsClient) do(ctx context.Context, wg *sync.WaitGroup, request zoneRequest, update *ZoneUpdate) {
+	defer wg.Done()
+
+	ctx = logging.WithFields(ctx, log.Fields{logging.FieldZone: request.zoneID})
+	*update = ac.apply(ctx, request)
+	if update.Err != nil {
+		logging.FromContext(ctx).Errorf("failed to update records for aws hosted zone %s: %v", request.zoneID, update.Err)
+	}
+
+	if len(update.Updated) > 0 {
+		logging.FromContext(ctx).Infof("records updated successfully for aws hosted zone %s (atomic: %t)", request.zoneID, update.Atomic)
+	}
+}
+
+// logContext returns a context whose logs have the account of the client.
+func (ac awsClient) logContext(ctx context.Context) context.Context {
+	return logging.WithFields(ctx, log.Fields{logging.FieldAccount: ac.account})
 }
 
 func (r records) check() []Record {
 	checked := make([]Record, 0, len(r))
 
 	for _, record := range r {
-		if record.IP != "" && record.FQDN != "" && (record.RecordType == "A" || record.RecordType == "AAA") {
+		if record.IP != "" && record.FQDN != "" && (addressTypes[record.RecordType] || derivedTypes[record.RecordType]) {
 			checked = append(checked, record)
 		}
 	}
@@ -188,9 +396,12 @@ func translateRecord(record *types.ResourceRecordSet) *Record {
 	}
 
 	return &Record{
-		FQDN:       *record.Name,
-		IP:         *record.ResourceRecords[0].Value,
-		RecordType: string(record.Type),
-		RecordTTL:  int(*record.TTL),
+		FQDN:          *record.Name,
+		IP:            *record.ResourceRecords[0].Value,
+		RecordType:    string(record.Type),
+		RecordTTL:     int(*record.TTL),
+		SetIdentifier: aws.ToString(record.SetIdentifier),
+		Failover:      string(record.Failover),
+		HealthCheckID: aws.ToString(record.HealthCheckId),
 	}
 }
@@ -3,6 +3,8 @@ package r53
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,10 +16,25 @@ import (
 )
 
 type mockUpdateGetter struct {
-	listOutput  *route53.ListResourceRecordSetsOutput
-	listErr     error
-	changeErr   error
-	changeCalls int
+	listOutput       *route53.ListResourceRecordSetsOutput
+	listErr          error
+	changeErr        error
+	rejectValue      string
+	changeCalls      int
+	hostedZones      []types.HostedZone
+	vpcZones         []types.HostedZoneSummary
+	zonesErr         error
+	listZonesCalls   int
+	zonesByNameCalls int
+	changeInputs     []*route53.ChangeResourceRecordSetsInput
+	healthChecks     []types.HealthCheck
+	healthCheckTags  map[string]string
+	healthCheckErr   error
+	tagErr           error
+	createdChecks    []*route53.CreateHealthCheckInput
+	updatedChecks    []*route53.UpdateHealthCheckInput
+	deletedChecks    []string
+	mu               sync.Mutex
 }
 
 func (m *mockUpdateGetter) ListResourceRecordSets(
@@ -35,15 +52,160 @@ func (m *mockUpdateGetter) ListResourceRecordSets(
 }
 
 func (m *mockUpdateGetter) ChangeResourceRecordSets(
-	context.Context,
-	*route53.ChangeResourceRecordSetsInput,
-	...func(*route53.Options),
+	_ context.Context,
+	params *route53.ChangeResourceRecordSetsInput,
+	_ ...func(*route53.Options),
 ) (*route53.ChangeResourceRecordSetsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.changeCalls++
+	m.changeInputs = append(m.changeInputs, params)
 	if m.changeErr != nil {
 		return nil, m.changeErr
 	}
-	return &route53.ChangeResourceRecordSetsOutput{}, nil
+	for _, change := range params.ChangeBatch.Changes {
+		for _, resourceRecord := range change.ResourceRecordSet.ResourceRecords {
+			if m.rejectValue != "" && aws.ToString(resourceRecord.Value) == m.rejectValue {
+				return nil, errors.New("invalid value " + m.rejectValue)
+			}
+		}
+	}
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: &types.ChangeInfo{Id: aws.String(fmt.Sprintf("/change/C%d", m.changeCalls))},
+	}, nil
+}
+
+func (m *mockUpdateGetter) GetHostedZone(
+	_ context.Context,
+	params *route53.GetHostedZoneInput,
+	_ ...func(*route53.Options),
+) (*route53.GetHostedZoneOutput, error) {
+	if m.zonesErr != nil {
+		return nil, m.zonesErr
+	}
+	for _, hostedZone := range m.hostedZones {
+		if trimZoneID(aws.ToString(hostedZone.Id)) == aws.ToString(params.Id) {
+			return &route53.GetHostedZoneOutput{HostedZone: &hostedZone}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrZoneNotFound, aws.ToString(params.Id))
+}
+
+func (m *mockUpdateGetter) ListHostedZones(
+	context.Context,
+	*route53.ListHostedZonesInput,
+	...func(*route53.Options),
+) (*route53.ListHostedZonesOutput, error) {
+	m.listZonesCalls++
+	if m.zonesErr != nil {
+		return nil, m.zonesErr
+	}
+	return &route53.ListHostedZonesOutput{HostedZones: m.hostedZones}, nil
+}
+
+func (m *mockUpdateGetter) ListHostedZonesByName(
+	_ context.Context,
+	params *route53.ListHostedZonesByNameInput,
+	_ ...func(*route53.Options),
+) (*route53.ListHostedZonesByNameOutput, error) {
+	m.zonesByNameCalls++
+	if m.zonesErr != nil {
+		return nil, m.zonesErr
+	}
+
+	// emulate route53 ordering: zones whose name sorts at or after DNSName
+	hostedZones := make([]types.HostedZone, 0, len(m.hostedZones))
+	for _, hostedZone := range m.hostedZones {
+		if aws.ToString(hostedZone.Name) >= aws.ToString(params.DNSName) {
+			hostedZones = append(hostedZones, hostedZone)
+		}
+	}
+	return &route53.ListHostedZonesByNameOutput{HostedZones: hostedZones}, nil
+}
+
+func (m *mockUpdateGetter) ListHostedZonesByVPC(
+	context.Context,
+	*route53.ListHostedZonesByVPCInput,
+	...func(*route53.Options),
+) (*route53.ListHostedZonesByVPCOutput, error) {
+	if m.zonesErr != nil {
+		return nil, m.zonesErr
+	}
+	return &route53.ListHostedZonesByVPCOutput{HostedZoneSummaries: m.vpcZones}, nil
+}
+
+func (m *mockUpdateGetter) ListHealthChecks(
+	context.Context,
+	*route53.ListHealthChecksInput,
+	...func(*route53.Options),
+) (*route53.ListHealthChecksOutput, error) {
+	if m.healthCheckErr != nil {
+		return nil, m.healthCheckErr
+	}
+	return &route53.ListHealthChecksOutput{HealthChecks: m.healthChecks}, nil
+}
+
+func (m *mockUpdateGetter) CreateHealthCheck(
+	_ context.Context,
+	params *route53.CreateHealthCheckInput,
+	_ ...func(*route53.Options),
+) (*route53.CreateHealthCheckOutput, error) {
+	m.createdChecks = append(m.createdChecks, params)
+	return &route53.CreateHealthCheckOutput{
+		HealthCheck: &types.HealthCheck{Id: aws.String("hc-new")},
+	}, nil
+}
+
+func (m *mockUpdateGetter) UpdateHealthCheck(
+	_ context.Context,
+	params *route53.UpdateHealthCheckInput,
+	_ ...func(*route53.Options),
+) (*route53.UpdateHealthCheckOutput, error) {
+	m.updatedChecks = append(m.updatedChecks, params)
+	return &route53.UpdateHealthCheckOutput{}, nil
+}
+
+func (m *mockUpdateGetter) DeleteHealthCheck(
+	_ context.Context,
+	params *route53.DeleteHealthCheckInput,
+	_ ...func(*route53.Options),
+) (*route53.DeleteHealthCheckOutput, error) {
+	m.deletedChecks = append(m.deletedChecks, aws.ToString(params.HealthCheckId))
+	return &route53.DeleteHealthCheckOutput{}, nil
+}
+
+func (m *mockUpdateGetter) ChangeTagsForResource(
+	_ context.Context,
+	params *route53.ChangeTagsForResourceInput,
+	_ ...func(*route53.Options),
+) (*route53.ChangeTagsForResourceOutput, error) {
+	if m.tagErr != nil {
+		return nil, m.tagErr
+	}
+	if m.healthCheckTags == nil {
+		m.healthCheckTags = make(map[string]string)
+	}
+	for _, tag := range params.AddTags {
+		m.healthCheckTags[aws.ToString(params.ResourceId)] = aws.ToString(tag.Value)
+	}
+	return &route53.ChangeTagsForResourceOutput{}, nil
+}
+
+func (m *mockUpdateGetter) ListTagsForResources(
+	_ context.Context,
+	params *route53.ListTagsForResourcesInput,
+	_ ...func(*route53.Options),
+) (*route53.ListTagsForResourcesOutput, error) {
+	tagSets := make([]types.ResourceTagSet, 0, len(params.ResourceIds))
+	for _, id := range params.ResourceIds {
+		tagSet := types.ResourceTagSet{ResourceId: aws.String(id)}
+		if value, ok := m.healthCheckTags[id]; ok {
+			tagSet.Tags = []types.Tag{{Key: aws.String(healthCheckTag), Value: aws.String(value)}}
+		}
+		tagSets = append(tagSets, tagSet)
+	}
+	return &route53.ListTagsForResourcesOutput{ResourceTagSets: tagSets}, nil
 }
 
 func TestGetRecords(t *testing.T) {
@@ -84,6 +246,7 @@ func TestGetRecords(t *testing.T) {
 					IP:         "192.0.2.1",
 					RecordType: "A",
 					RecordTTL:  300,
+					ZoneID:     "Z123",
 				},
 			},
 		},
@@ -113,11 +276,12 @@ func TestGetRecords(t *testing.T) {
 					IP:         "2001:db8::1",
 					RecordType: "AAAA",
 					RecordTTL:  60,
+					ZoneID:     "Z123",
 				},
 			},
 		},
 		{
-			name: "skips non A and AAAA records",
+			name: "skips record types not configured in the zone",
 			domains: []string{
 				"example.com",
 			},
@@ -138,6 +302,49 @@ func TestGetRecords(t *testing.T) {
 			},
 			expectedRecords: []Record{},
 		},
+		{
+			name: "returns configured derived records",
+			domains: []string{
+				"www.example.com",
+			},
+			zones: []zone{
+				{
+					id: "Z123",
+					records: []Record{
+						{FQDN: "www.example.com", RecordType: "CNAME"},
+					},
+				},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name: aws.String("www.example.com"),
+						Type: types.RRTypeCname,
+						TTL:  aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String("vpn.example.com")},
+						},
+					},
+					{
+						Name: aws.String("www.example.com"),
+						Type: types.RRTypeTxt,
+						TTL:  aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String(`"v=spf1 -all"`)},
+						},
+					},
+				},
+			},
+			expectedRecords: []Record{
+				{
+					FQDN:       "www.example.com",
+					IP:         "vpn.example.com",
+					RecordType: "CNAME",
+					RecordTTL:  300,
+					ZoneID:     "Z123",
+				},
+			},
+		},
 		{
 			name: "skips records whose fqdn is not requested",
 			domains: []string{
@@ -160,6 +367,57 @@ func TestGetRecords(t *testing.T) {
 			},
 			expectedRecords: []Record{},
 		},
+		{
+			name: "skips record sets with another set identifier",
+			domains: []string{
+				"vpn.example.com",
+			},
+			zones: []zone{
+				{
+					id: "Z123",
+					records: []Record{
+						{FQDN: "vpn.example.com", RecordType: "A", SetIdentifier: "home"},
+					},
+				},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name:          aws.String("vpn.example.com"),
+						Type:          types.RRTypeA,
+						TTL:           aws.Int64(60),
+						SetIdentifier: aws.String("home"),
+						Failover:      types.ResourceRecordSetFailoverPrimary,
+						HealthCheckId: aws.String("hc-1"),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String("192.0.2.1")},
+						},
+					},
+					{
+						Name:          aws.String("vpn.example.com"),
+						Type:          types.RRTypeA,
+						TTL:           aws.Int64(60),
+						SetIdentifier: aws.String("office"),
+						Failover:      types.ResourceRecordSetFailoverSecondary,
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String("198.51.100.1")},
+						},
+					},
+				},
+			},
+			expectedRecords: []Record{
+				{
+					FQDN:          "vpn.example.com",
+					IP:            "192.0.2.1",
+					RecordType:    "A",
+					RecordTTL:     60,
+					ZoneID:        "Z123",
+					SetIdentifier: "home",
+					Failover:      "PRIMARY",
+					HealthCheckID: "hc-1",
+				},
+			},
+		},
 		{
 			name: "skips invalid resource record sets",
 			domains: []string{
@@ -194,6 +452,58 @@ func TestGetRecords(t *testing.T) {
 			},
 			expectedRecords: []Record{},
 		},
+		{
+			name: "returns each record once when its name is configured for several types",
+			domains: []string{
+				"home.example.com",
+				"home.example.com",
+			},
+			zones: []zone{
+				{
+					id: "Z123",
+					records: []Record{
+						{FQDN: "home.example.com", RecordType: "A"},
+						{FQDN: "home.example.com", RecordType: "TXT"},
+					},
+				},
+			},
+			listOutput: &route53.ListResourceRecordSetsOutput{
+				ResourceRecordSets: []types.ResourceRecordSet{
+					{
+						Name: aws.String("home.example.com"),
+						Type: types.RRTypeA,
+						TTL:  aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String("192.0.2.1")},
+						},
+					},
+					{
+						Name: aws.String("home.example.com"),
+						Type: types.RRTypeTxt,
+						TTL:  aws.Int64(300),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String(`"ip=192.0.2.1"`)},
+						},
+					},
+				},
+			},
+			expectedRecords: []Record{
+				{
+					FQDN:       "home.example.com",
+					IP:         "192.0.2.1",
+					RecordType: "A",
+					RecordTTL:  300,
+					ZoneID:     "Z123",
+				},
+				{
+					FQDN:       "home.example.com",
+					IP:         `"ip=192.0.2.1"`,
+					RecordType: "TXT",
+					RecordTTL:  300,
+					ZoneID:     "Z123",
+				},
+			},
+		},
 		{
 			name: "list resource record sets error",
 			domains: []string{
@@ -306,6 +616,7 @@ func TestUpdateRecords(t *testing.T) {
 				},
 			},
 			changeErr:         changeErr,
+			expectedError:     changeErr,
 			expectedChangeCnt: 1,
 		},
 		{
@@ -332,6 +643,30 @@ func TestUpdateRecords(t *testing.T) {
 			},
 			expectedChangeCnt: 0,
 		},
+		{
+			name: "records of a view only change their zone",
+			records: []Record{
+				{
+					FQDN:       "nas.example.com",
+					IP:         "192.168.1.10",
+					RecordType: "A",
+					RecordTTL:  300,
+					ZoneID:     "ZPRIVATE",
+				},
+			},
+			zones: []zone{
+				{
+					id:      "ZPUBLIC",
+					records: []Record{{FQDN: "nas.example.com", RecordType: "A", RecordTTL: 300}},
+				},
+				{
+					id:      "ZPRIVATE",
+					private: true,
+					records: []Record{{FQDN: "nas.example.com", RecordType: "A", RecordTTL: 300}},
+				},
+			},
+			expectedChangeCnt: 1,
+		},
 		{
 			name:              "no zones configured",
 			records:           []Record{{FQDN: "example.com", IP: "192.0.2.1", RecordType: "A"}},
@@ -361,7 +696,7 @@ func TestUpdateRecords(t *testing.T) {
 				},
 			}
 
-			err := updater.UpdateRecords(context.Background(), records)
+			_, err := updater.UpdateRecords(context.Background(), records)
 
 			if expectedError != nil {
 				assert.EqualError(t, err, expectedError.Error())
@@ -420,14 +755,16 @@ func TestNewR53(t *testing.T) {
 		},
 	}
 
-	updater := NewR53(accounts)
+	updater, err := NewR53(accounts)
 
+	require.NoError(t, err)
 	require.NotNil(t, updater)
 	require.Len(t, updater.drivers, len(accounts))
 
 	assert.Equal(t, []zone{
 		{
-			id: "Z111",
+			id:   "Z111",
+			name: "example.com zone",
 			records: []Record{
 				{FQDN: "vpn.example.com", RecordType: "A", RecordTTL: 300},
 				{FQDN: "ipv6.example.com", RecordType: "AAAA", RecordTTL: 60},
@@ -435,10 +772,12 @@ func TestNewR53(t *testing.T) {
 		},
 	}, updater.drivers[0].zones)
 	assert.NotNil(t, updater.drivers[0].client)
+	assert.NotNil(t, updater.drivers[0].cache)
 
 	assert.Equal(t, []zone{
 		{
-			id: "Z222",
+			id:   "Z222",
+			name: "example.org zone",
 			records: []Record{
 				{FQDN: "app.example.org", RecordType: "A", RecordTTL: 120},
 			},
@@ -0,0 +1,36 @@
+package r53
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/tracing"
+)
+
+// requestSpans traces every Route53 API call, retries included, as a child
+// of the span of the caller.
+var requestSpans = middleware.InitializeMiddlewareFunc("ddnsRequestSpans",
+	func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		operation := awsmiddleware.GetOperationName(ctx)
+		attrs := []attribute.KeyValue{
+			attribute.String("rpc.system", "aws-api"),
+			attribute.String("rpc.service", "Route53"),
+			attribute.String("rpc.method", operation),
+		}
+		if zone := requestZone(in.Parameters); zone != "" {
+			attrs = append(attrs, attribute.String("route53.zone", zone))
+		}
+
+		ctx, span := tracing.Start(ctx, "Route53."+operation, attrs...)
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		tracing.End(span, err)
+
+		return out, metadata, err
+	})
+
+func addRequestSpans(stack *middleware.Stack) error {
+	return stack.Initialize.Add(requestSpans, middleware.After)
+}
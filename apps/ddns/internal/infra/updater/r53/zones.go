@@ -0,0 +1,314 @@
+package r53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+const (
+	defaultZoneCacheTTL = time.Hour
+	hostedZoneIDPrefix  = "/hostedzone/"
+)
+
+var (
+	ErrZoneNotFound  = errors.New("hosted zone not found")
+	ErrAmbiguousZone = errors.New("hosted zone name is ambiguous")
+)
+
+type zoneCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	expires time.Time
+	zones   []zone
+}
+
+func newZoneCache(ttl time.Duration) *zoneCache {
+	if ttl <= 0 {
+		ttl = defaultZoneCacheTTL
+	}
+
+	return &zoneCache{ttl: ttl}
+}
+
+// hostedZones returns the zones of the account with their IDs resolved. Zones
+// configured by ID are returned as they are, zones configured by name are
+// looked up and records configured without a zone are assigned to the hosted
+// zone that owns their FQDN. When a record has PTR enabled, the names of the
+// zones configured only by ID are looked up to find the reverse zones.
+// Lookups are cached until the cache expires.
+func (ac awsClient) hostedZones(ctx context.Context) ([]zone, error) {
+	if ac.cache == nil {
+		return ac.zones, nil
+	}
+
+	ac.cache.mu.Lock()
+	defer ac.cache.mu.Unlock()
+
+	if ac.cache.zones != nil && time.Now().Before(ac.cache.expires) {
+		return ac.cache.zones, nil
+	}
+
+	zones, err := ac.resolveZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ac.cache.zones = zones
+	ac.cache.expires = time.Now().Add(ac.cache.ttl)
+
+	return zones, nil
+}
+
+func (ac awsClient) resolveZones(ctx context.Context) ([]zone, error) {
+	zones := make([]zone, 0, len(ac.zones))
+	ptr := ac.ptrEnabled()
+
+	for _, configured := range ac.zones {
+		configured.records = append([]Record(nil), configured.records...)
+		switch {
+		case configured.id == "":
+			id, err := ac.zoneIDByName(ctx, configured)
+			if err != nil {
+				return nil, err
+			}
+			configured.id = id
+		case configured.name == "" && ptr:
+			name, err := ac.zoneName(ctx, configured.id)
+			if err != nil {
+				return nil, err
+			}
+			configured.name = name
+		}
+
+		zones = append(zones, configured)
+	}
+
+	if len(ac.records) == 0 {
+		return zones, nil
+	}
+
+	available, err := ac.listZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range ac.records {
+		owner, err := zoneForFQDN(available, record.FQDN)
+		if err != nil {
+			return nil, err
+		}
+
+		zones = addRecordToZone(zones, owner, record)
+	}
+
+	return zones, nil
+}
+
+// ptrEnabled reports whether any record of the account has PTR enabled.
+func (ac awsClient) ptrEnabled() bool {
+	for _, configured := range ac.zones {
+		for _, record := range configured.records {
+			if record.ptr {
+				return true
+			}
+		}
+	}
+
+	for _, record := range ac.records {
+		if record.ptr {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ac awsClient) zoneName(ctx context.Context, id string) (string, error) {
+	output, err := ac.client.GetHostedZone(ctx, &route53.GetHostedZoneInput{Id: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the name of hosted zone %s: %w", id, err)
+	}
+
+	return normalizeName(aws.ToString(output.HostedZone.Name)), nil
+}
+
+func (ac awsClient) zoneIDByName(ctx context.Context, z zone) (string, error) {
+	var (
+		candidates []zone
+		err        error
+	)
+
+	if z.vpcID != "" {
+		candidates, err = ac.listZonesByVPC(ctx, z.vpcID, z.vpcRegion)
+	} else {
+		candidates, err = ac.listZonesByName(ctx, z.name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ids := make([]string, 0, 1)
+	for _, candidate := range candidates {
+		if candidate.name == normalizeName(z.name) && candidate.private == z.private {
+			ids = append(ids, candidate.id)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%w: %s (private: %t)", ErrZoneNotFound, z.name, z.private)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s matches %s", ErrAmbiguousZone, z.name, strings.Join(ids, ", "))
+	}
+}
+
+func (ac awsClient) listZonesByName(ctx context.Context, name string) ([]zone, error) {
+	zones := make([]zone, 0)
+	params := &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(normalizeName(name)),
+	}
+
+	for {
+		page, err := ac.client.ListHostedZonesByName(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hostedZone := range page.HostedZones {
+			zones = append(zones, translateHostedZone(hostedZone))
+		}
+
+		// results are sorted by name, once the name changes there are no more candidates
+		if !page.IsTruncated || page.NextDNSName == nil || normalizeName(*page.NextDNSName) != normalizeName(name) {
+			return zones, nil
+		}
+
+		params.DNSName = page.NextDNSName
+		params.HostedZoneId = page.NextHostedZoneId
+	}
+}
+
+func (ac awsClient) listZonesByVPC(ctx context.Context, vpcID, vpcRegion string) ([]zone, error) {
+	zones := make([]zone, 0)
+	params := &route53.ListHostedZonesByVPCInput{
+		VPCId:     aws.String(vpcID),
+		VPCRegion: types.VPCRegion(vpcRegion),
+	}
+
+	for {
+		page, err := ac.client.ListHostedZonesByVPC(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, summary := range page.HostedZoneSummaries {
+			zones = append(zones, zone{
+				id:      trimZoneID(aws.ToString(summary.HostedZoneId)),
+				name:    normalizeName(aws.ToString(summary.Name)),
+				private: true,
+			})
+		}
+
+		if page.NextToken == nil {
+			return zones, nil
+		}
+
+		params.NextToken = page.NextToken
+	}
+}
+
+func (ac awsClient) listZones(ctx context.Context) ([]zone, error) {
+	zones := make([]zone, 0)
+
+	paginator := route53.NewListHostedZonesPaginator(ac.client, &route53.ListHostedZonesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hostedZone := range page.HostedZones {
+			zones = append(zones, translateHostedZone(hostedZone))
+		}
+	}
+
+	return zones, nil
+}
+
+// zoneForFQDN returns the most specific zone containing fqdn.
+func zoneForFQDN(zones []zone, fqdn string) (zone, error) {
+	name := normalizeName(fqdn)
+	matches := make([]zone, 0, 1)
+
+	for _, candidate := range zones {
+		if name != candidate.name && !strings.HasSuffix(name, "."+candidate.name) {
+			continue
+		}
+
+		if len(matches) > 0 && len(candidate.name) < len(matches[0].name) {
+			continue
+		}
+
+		if len(matches) > 0 && len(candidate.name) > len(matches[0].name) {
+			matches = matches[:0]
+		}
+
+		matches = append(matches, candidate)
+	}
+
+	switch len(matches) {
+	case 0:
+		return zone{}, fmt.Errorf("%w for %s", ErrZoneNotFound, fqdn)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, 0, len(matches))
+		for _, match := range matches {
+			ids = append(ids, match.id)
+		}
+		return zone{}, fmt.Errorf("%w: %s owned by %s", ErrAmbiguousZone, fqdn, strings.Join(ids, ", "))
+	}
+}
+
+func addRecordToZone(zones []zone, owner zone, record Record) []zone {
+	for i := range zones {
+		if zones[i].id == owner.id {
+			zones[i].records = append(zones[i].records, record)
+			return zones
+		}
+	}
+
+	owner.records = []Record{record}
+	return append(zones, owner)
+}
+
+func translateHostedZone(hostedZone types.HostedZone) zone {
+	return zone{
+		id:      trimZoneID(aws.ToString(hostedZone.Id)),
+		name:    normalizeName(aws.ToString(hostedZone.Name)),
+		private: hostedZone.Config != nil && hostedZone.Config.PrivateZone,
+	}
+}
+
+func trimZoneID(id string) string {
+	return strings.TrimPrefix(id, hostedZoneIDPrefix)
+}
+
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name
+}
@@ -0,0 +1,237 @@
+package r53
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/jorgesanchez-e/localenvironment/config"
+	"github.com/stretchr/testify/require"
+)
+
+func hostedZone(id, name string, private bool) types.HostedZone {
+	return types.HostedZone{
+		Id:     aws.String(hostedZoneIDPrefix + id),
+		Name:   aws.String(name),
+		Config: &types.HostedZoneConfig{PrivateZone: private},
+	}
+}
+
+func TestHostedZones(t *testing.T) {
+	zonesErr := errors.New("list hosted zones failed")
+	available := []types.HostedZone{
+		hostedZone("ZNET", "example.net.", false),
+		hostedZone("ZNETPRIV", "example.net.", true),
+		hostedZone("ZSUB", "home.example.net.", false),
+		hostedZone("ZORG", "example.org.", false),
+		hostedZone("ZORGPRIV", "example.org.", true),
+	}
+
+	testCases := []struct {
+		name          string
+		zones         []zone
+		records       []Record
+		hostedZones   []types.HostedZone
+		vpcZones      []types.HostedZoneSummary
+		zonesErr      error
+		expectedZones []zone
+		expectedError error
+	}{
+		{
+			name:          "zones configured by id are not looked up",
+			zones:         []zone{{id: "Z123", name: "example.com zone"}},
+			zonesErr:      zonesErr,
+			expectedZones: []zone{{id: "Z123", name: "example.com zone"}},
+		},
+		{
+			name: "names of zones configured by id looked up for ptr",
+			zones: []zone{
+				{id: "ZNET", records: []Record{{FQDN: "vpn.example.net.", RecordType: "A", ptr: true}}},
+				{id: "ZREV"},
+			},
+			hostedZones: []types.HostedZone{
+				hostedZone("ZNET", "example.net.", false),
+				hostedZone("ZREV", "10.IN-ADDR.ARPA.", false),
+			},
+			expectedZones: []zone{
+				{id: "ZNET", name: "example.net.", records: []Record{{FQDN: "vpn.example.net.", RecordType: "A", ptr: true}}},
+				{id: "ZREV", name: "10.in-addr.arpa."},
+			},
+		},
+		{
+			name:          "unknown zone id with ptr",
+			zones:         []zone{{id: "ZNET", records: []Record{{FQDN: "vpn.example.net.", RecordType: "A", ptr: true}}}},
+			expectedError: ErrZoneNotFound,
+		},
+		{
+			name:          "public zone resolved by name",
+			zones:         []zone{{name: "Example.NET"}},
+			hostedZones:   available,
+			expectedZones: []zone{{id: "ZNET", name: "Example.NET"}},
+		},
+		{
+			name:          "private zone resolved by name",
+			zones:         []zone{{name: "example.net.", private: true}},
+			hostedZones:   available,
+			expectedZones: []zone{{id: "ZNETPRIV", name: "example.net.", private: true}},
+		},
+		{
+			name:  "private zone resolved by vpc",
+			zones: []zone{{name: "example.net.", private: true, vpcID: "vpc-1", vpcRegion: "us-east-1"}},
+			vpcZones: []types.HostedZoneSummary{
+				{HostedZoneId: aws.String("ZVPCNET"), Name: aws.String("example.net.")},
+				{HostedZoneId: aws.String("ZVPCORG"), Name: aws.String("example.org.")},
+			},
+			expectedZones: []zone{
+				{id: "ZVPCNET", name: "example.net.", private: true, vpcID: "vpc-1", vpcRegion: "us-east-1"},
+			},
+		},
+		{
+			name: "zone with vpc is private",
+			zones: AWSConfig{Zones: []config.ZoneConfig{
+				{Name: "example.net.", VPCID: "vpc-1", VPCRegion: "us-east-1"},
+			}}.zones(),
+			vpcZones: []types.HostedZoneSummary{
+				{HostedZoneId: aws.String("ZVPCNET"), Name: aws.String("example.net.")},
+			},
+			expectedZones: []zone{
+				{id: "ZVPCNET", name: "example.net.", private: true, vpcID: "vpc-1", vpcRegion: "us-east-1"},
+			},
+		},
+		{
+			name:          "zone name not found",
+			zones:         []zone{{name: "example.com."}},
+			hostedZones:   available,
+			expectedError: ErrZoneNotFound,
+		},
+		{
+			name:  "ambiguous zone name",
+			zones: []zone{{name: "example.net."}},
+			hostedZones: []types.HostedZone{
+				hostedZone("ZONE1", "example.net.", false),
+				hostedZone("ZONE2", "example.net.", false),
+			},
+			expectedError: ErrAmbiguousZone,
+		},
+		{
+			name:          "lookup error",
+			zones:         []zone{{name: "example.net."}},
+			zonesErr:      zonesErr,
+			expectedError: zonesErr,
+		},
+		{
+			name:        "records merged into configured zone",
+			zones:       []zone{{id: "ZNET", name: "example.net.", records: []Record{{FQDN: "vpn.example.net.", RecordType: "A"}}}},
+			records:     []Record{{FQDN: "www.example.net.", RecordType: "A"}},
+			hostedZones: available[:1],
+			expectedZones: []zone{
+				{
+					id:   "ZNET",
+					name: "example.net.",
+					records: []Record{
+						{FQDN: "vpn.example.net.", RecordType: "A"},
+						{FQDN: "www.example.net.", RecordType: "A"},
+					},
+				},
+			},
+		},
+		{
+			name: "records in nested zones",
+			records: []Record{
+				{FQDN: "nas.home.example.net.", RecordType: "AAAA"},
+				{FQDN: "home.example.net", RecordType: "A"},
+			},
+			hostedZones: []types.HostedZone{
+				hostedZone("ZNET", "example.net.", false),
+				hostedZone("ZSUB", "home.example.net.", false),
+			},
+			expectedZones: []zone{
+				{
+					id:   "ZSUB",
+					name: "home.example.net.",
+					records: []Record{
+						{FQDN: "nas.home.example.net.", RecordType: "AAAA"},
+						{FQDN: "home.example.net", RecordType: "A"},
+					},
+				},
+			},
+		},
+		{
+			name:          "record owned by public and private zones",
+			records:       []Record{{FQDN: "vpn.example.org.", RecordType: "A"}},
+			hostedZones:   available,
+			expectedError: ErrAmbiguousZone,
+		},
+		{
+			name:          "record without zone",
+			records:       []Record{{FQDN: "vpn.example.com.", RecordType: "A"}},
+			hostedZones:   available,
+			expectedError: ErrZoneNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		name := testCase.name
+		zones := testCase.zones
+		records := testCase.records
+		mock := &mockUpdateGetter{
+			hostedZones: testCase.hostedZones,
+			vpcZones:    testCase.vpcZones,
+			zonesErr:    testCase.zonesErr,
+		}
+		expectedZones := testCase.expectedZones
+		expectedError := testCase.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			client := awsClient{
+				client:  mock,
+				zones:   zones,
+				records: records,
+				cache:   newZoneCache(time.Minute),
+			}
+
+			got, err := client.hostedZones(context.Background())
+
+			if expectedError != nil {
+				assert.ErrorIs(t, err, expectedError)
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, expectedZones, got)
+		})
+	}
+}
+
+func TestHostedZones_Cache(t *testing.T) {
+	mock := &mockUpdateGetter{
+		hostedZones: []types.HostedZone{hostedZone("ZNET", "example.net.", false)},
+	}
+	client := awsClient{
+		client:  mock,
+		zones:   []zone{{name: "example.net."}},
+		records: []Record{{FQDN: "vpn.example.net.", RecordType: "A"}},
+		cache:   newZoneCache(time.Minute),
+	}
+
+	first, err := client.hostedZones(context.Background())
+	require.NoError(t, err)
+	second, err := client.hostedZones(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, mock.zonesByNameCalls)
+	assert.Equal(t, 1, mock.listZonesCalls)
+
+	client.cache.expires = time.Now().Add(-time.Second)
+	_, err = client.hostedZones(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, mock.zonesByNameCalls)
+	assert.Equal(t, 2, mock.listZonesCalls)
+}
@@ -3,35 +3,91 @@ package updater
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/domain"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/logging"
+	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/tracing"
 	"github.com/jorgesanchez-e/localenvironment/apps/ddns/internal/infra/updater/r53"
 	"github.com/jorgesanchez-e/localenvironment/config"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+const providerRoute53 = "route53"
+
 type r53Updater interface {
 	GetRecords(ctx context.Context, domains []string) ([]r53.Record, error)
-	UpdateRecords(ctx context.Context, records []r53.Record) error
+	UpdateRecords(ctx context.Context, records []r53.Record) ([]r53.ZoneUpdate, error)
+	PlanRecords(ctx context.Context, records []r53.Record) ([]r53.PlannedChange, error)
 }
 
 type Updater struct {
 	domains    []string
+	specs      map[string]*domain.RecordSpec
 	r53Updater r53Updater
 }
 
+// awsConfigDomains returns the configured record names, each name once even
+// when several record types are configured for it.
 func awsConfigDomains(awsConfig *config.SimpleDDNS) []string {
 	domains := make([]string, 0)
+	seen := make(map[string]struct{})
+	addDomains := func(records []config.RecordConfig) {
+		for _, record := range records {
+			if _, ok := seen[record.FQDN]; ok {
+				continue
+			}
+
+			seen[record.FQDN] = struct{}{}
+			domains = append(domains, record.FQDN)
+		}
+	}
+
 	for _, awsConfig := range awsConfig.DDNS.AWS {
 		for _, zone := range awsConfig.Zones {
-			for _, record := range zone.Records {
-				domains = append(domains, record.FQDN)
-			}
+			addDomains(zone.Records)
 		}
+		addDomains(awsConfig.Records)
 	}
 
 	return domains
 }
 
+// awsConfigSpecs returns how the value of each derived record is built, keyed
+// by FQDN and record type.
+func awsConfigSpecs(awsConfig *config.SimpleDDNS) map[string]*domain.RecordSpec {
+	specs := make(map[string]*domain.RecordSpec)
+	addSpecs := func(records []config.RecordConfig) {
+		for _, record := range records {
+			if record.RecordType == "A" || record.RecordType == "AAAA" {
+				continue
+			}
+
+			specs[specKey(record.FQDN, record.RecordType)] = &domain.RecordSpec{
+				Target:   record.Target,
+				Port:     record.Port,
+				Priority: record.Priority,
+				Weight:   record.Weight,
+				Params:   record.Params,
+			}
+		}
+	}
+
+	for _, awsConfig := range awsConfig.DDNS.AWS {
+		for _, zone := range awsConfig.Zones {
+			addSpecs(zone.Records)
+		}
+		addSpecs(awsConfig.Records)
+	}
+
+	return specs
+}
+
+func specKey(fqdn, recordType string) string {
+	return strings.TrimSuffix(strings.ToLower(fqdn), ".") + "|" + recordType
+}
+
 func NewUpdater(ddnsConfig *config.SimpleDDNS) (*Updater, error) {
 	if ddnsConfig == nil {
 		return nil, errors.New("ddns config is required")
@@ -41,38 +97,123 @@ func NewUpdater(ddnsConfig *config.SimpleDDNS) (*Updater, error) {
 		return nil, errors.New("aws config is required")
 	}
 
+	r53Updater, err := r53.NewR53(ddnsConfig.DDNS.AWS)
+	if err != nil {
+		return nil, err
+	}
+
 	updater := &Updater{
-		r53Updater: r53.NewR53(ddnsConfig.DDNS.AWS),
+		r53Updater: r53Updater,
 		domains:    awsConfigDomains(ddnsConfig),
+		specs:      awsConfigSpecs(ddnsConfig),
 	}
 
 	return updater, nil
 }
 
-func (u *Updater) GetRecords(ctx context.Context) ([]domain.Record, error) {
+func (u *Updater) GetRecords(ctx context.Context) (_ []domain.Record, err error) {
+	ctx, span := tracing.Start(providerContext(ctx), "updater.GetRecords", attribute.String("ddns.provider", providerRoute53))
+	defer func() { tracing.End(span, err) }()
+
 	records, err := u.r53Updater.GetRecords(ctx, u.domains)
 	if err != nil {
 		return nil, err
 	}
+	logging.FromContext(ctx).Debugf("%d records read", len(records))
 
-	domainRecords := make([]domain.Record, 0, len(records))
-	for _, record := range records {
-		domainRecords = append(domainRecords, domain.Record{
-			IP:     record.IP,
-			IPType: record.RecordType,
-			FQDN:   record.FQDN,
-		})
+	domainRecords := r53RecordsToDomainRecords(records)
+	for i := range domainRecords {
+		domainRecords[i].Spec = u.specs[specKey(domainRecords[i].FQDN, domainRecords[i].IPType)]
 	}
 
 	return domainRecords, nil
 }
 
-func (u *Updater) UpdateRecords(ctx context.Context, records []domain.Record) error {
+func (u *Updater) UpdateRecords(ctx context.Context, records []domain.Record) (_ []domain.UpdateResult, err error) {
 	if len(records) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return u.r53Updater.UpdateRecords(ctx, domainRecordsToR53Records(records))
+	ctx, span := tracing.Start(providerContext(ctx), "updater.UpdateRecords",
+		attribute.String("ddns.provider", providerRoute53),
+		attribute.Int("ddns.records", len(records)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	logging.FromContext(ctx).Debugf("updating %d records", len(records))
+	updates, err := u.r53Updater.UpdateRecords(ctx, domainRecordsToR53Records(records))
+
+	results := make([]domain.UpdateResult, 0, len(updates))
+	for _, update := range updates {
+		results = append(results, domain.UpdateResult{
+			Provider:  providerRoute53,
+			Zone:      update.ZoneID,
+			Atomic:    update.Atomic,
+			ChangeIDs: update.ChangeIDs,
+			Updated:   r53RecordsToDomainRecords(update.Updated),
+			Failed:    r53RecordsToDomainRecords(update.Failed),
+			Err:       update.Err,
+		})
+	}
+
+	return results, err
+}
+
+func (u *Updater) PlanRecords(ctx context.Context, records []domain.Record) (_ []domain.PlannedChange, err error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	ctx, span := tracing.Start(providerContext(ctx), "updater.PlanRecords",
+		attribute.String("ddns.provider", providerRoute53),
+		attribute.Int("ddns.records", len(records)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	changes, err := u.r53Updater.PlanRecords(ctx, domainRecordsToR53Records(records))
+	if err != nil {
+		return nil, err
+	}
+
+	planned := make([]domain.PlannedChange, 0, len(changes))
+	for _, change := range changes {
+		planned = append(planned, domain.PlannedChange{
+			Provider:      providerRoute53,
+			Zone:          change.ZoneID,
+			Action:        change.Action,
+			FQDN:          change.FQDN,
+			Type:          change.RecordType,
+			TTL:           change.TTL,
+			Values:        change.Values,
+			Previous:      change.Previous,
+			HealthCheck:   change.HealthCheck,
+			HealthCheckID: change.HealthCheckID,
+		})
+	}
+
+	return planned, nil
+}
+
+// providerContext returns a context whose logs have the provider of the
+// updater.
+func providerContext(ctx context.Context) context.Context {
+	return logging.WithFields(ctx, log.Fields{logging.FieldProvider: providerRoute53})
+}
+
+func r53RecordsToDomainRecords(records []r53.Record) []domain.Record {
+	domainRecords := make([]domain.Record, 0, len(records))
+	for _, record := range records {
+		domainRecords = append(domainRecords, domain.Record{
+			IP:       record.IP,
+			IPType:   record.RecordType,
+			FQDN:     record.FQDN,
+			Previous: record.Previous,
+			Zone:     record.ZoneID,
+			Source:   ipSource(record.Source),
+		})
+	}
+
+	return domainRecords
 }
 
 func domainRecordsToR53Records(records []domain.Record) []r53.Record {
@@ -82,7 +223,22 @@ func domainRecordsToR53Records(records []domain.Record) []r53.Record {
 			IP:         record.IP,
 			RecordType: record.IPType,
 			FQDN:       record.FQDN,
+			Previous:   record.Previous,
+			ZoneID:     record.Zone,
 		})
 	}
 	return recordsList
 }
+
+func ipSource(source *config.IPSourceConfig) *domain.IPSource {
+	if source == nil {
+		return nil
+	}
+
+	return &domain.IPSource{
+		Type:      source.Type,
+		Interface: source.Interface,
+		IPv4:      source.IPv4,
+		IPv6:      source.IPv6,
+	}
+}
@@ -14,7 +14,8 @@ import (
 
 type mockR53Updater struct {
 	getRecordsFn    func(ctx context.Context, domains []string) ([]r53.Record, error)
-	updateRecordsFn func(ctx context.Context, records []r53.Record) error
+	updateRecordsFn func(ctx context.Context, records []r53.Record) ([]r53.ZoneUpdate, error)
+	planRecordsFn   func(ctx context.Context, records []r53.Record) ([]r53.PlannedChange, error)
 }
 
 func (m *mockR53Updater) GetRecords(ctx context.Context, domains []string) ([]r53.Record, error) {
@@ -24,11 +25,18 @@ func (m *mockR53Updater) GetRecords(ctx context.Context, domains []string) ([]r5
 	return nil, nil
 }
 
-func (m *mockR53Updater) UpdateRecords(ctx context.Context, records []r53.Record) error {
+func (m *mockR53Updater) UpdateRecords(ctx context.Context, records []r53.Record) ([]r53.ZoneUpdate, error) {
 	if m.updateRecordsFn != nil {
 		return m.updateRecordsFn(ctx, records)
 	}
-	return nil
+	return nil, nil
+}
+
+func (m *mockR53Updater) PlanRecords(ctx context.Context, records []r53.Record) ([]r53.PlannedChange, error) {
+	if m.planRecordsFn != nil {
+		return m.planRecordsFn(ctx, records)
+	}
+	return nil, nil
 }
 
 func TestNewUpdater(t *testing.T) {
@@ -68,15 +76,19 @@ func TestNewUpdater(t *testing.T) {
 									Name: "example.com",
 									Records: []config.RecordConfig{
 										{FQDN: "vpn.example.com", RecordType: "A", RecordTTL: 300},
+										{FQDN: "vpn.example.com", RecordType: "TXT", RecordTTL: 300},
 										{FQDN: "ipv6.example.com", RecordType: "AAAA", RecordTTL: 60},
 									},
 								},
 							},
+							Records: []config.RecordConfig{
+								{FQDN: "home.example.org", RecordType: "A", RecordTTL: 300},
+							},
 						},
 					},
 				},
 			},
-			expectedDomains: []string{"vpn.example.com", "ipv6.example.com"},
+			expectedDomains: []string{"vpn.example.com", "ipv6.example.com", "home.example.org"},
 		},
 	}
 
@@ -109,6 +121,7 @@ func TestUpdater_GetRecords(t *testing.T) {
 	testCases := []struct {
 		name            string
 		domains         []string
+		specs           map[string]*domain.RecordSpec
 		mock            *mockR53Updater
 		expectedRecords []domain.Record
 		expectedError   error
@@ -137,6 +150,33 @@ func TestUpdater_GetRecords(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "derived records carry their spec",
+			domains: []string{"_openvpn._udp.example.com."},
+			specs: map[string]*domain.RecordSpec{
+				specKey("_openvpn._udp.example.com", "SRV"): {Target: "vpn.example.com.", Port: 1194},
+			},
+			mock: &mockR53Updater{
+				getRecordsFn: func(context.Context, []string) ([]r53.Record, error) {
+					return []r53.Record{
+						{
+							FQDN:       "_openvpn._udp.example.com.",
+							IP:         "0 0 1194 vpn.example.com.",
+							RecordType: "SRV",
+							RecordTTL:  300,
+						},
+					}, nil
+				},
+			},
+			expectedRecords: []domain.Record{
+				{
+					FQDN:   "_openvpn._udp.example.com.",
+					IP:     "0 0 1194 vpn.example.com.",
+					IPType: "SRV",
+					Spec:   &domain.RecordSpec{Target: "vpn.example.com.", Port: 1194},
+				},
+			},
+		},
 		{
 			name:    "r53 error",
 			domains: []string{"vpn.example.com"},
@@ -153,6 +193,7 @@ func TestUpdater_GetRecords(t *testing.T) {
 	for _, testCase := range testCases {
 		name := testCase.name
 		domains := testCase.domains
+		specs := testCase.specs
 		mock := testCase.mock
 		expectedRecords := testCase.expectedRecords
 		expectedError := testCase.expectedError
@@ -160,6 +201,7 @@ func TestUpdater_GetRecords(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			u := &Updater{
 				domains:    domains,
+				specs:      specs,
 				r53Updater: mock,
 			}
 
@@ -179,18 +221,19 @@ func TestUpdater_UpdateRecords(t *testing.T) {
 	updateErr := errors.New("update records failed")
 
 	testCases := []struct {
-		name          string
-		records       []domain.Record
-		mock          *mockR53Updater
-		expectedError error
+		name            string
+		records         []domain.Record
+		mock            *mockR53Updater
+		expectedResults []domain.UpdateResult
+		expectedError   error
 	}{
 		{
 			name:    "empty records",
 			records: nil,
 			mock: &mockR53Updater{
-				updateRecordsFn: func(context.Context, []r53.Record) error {
+				updateRecordsFn: func(context.Context, []r53.Record) ([]r53.ZoneUpdate, error) {
 					t.Fatal("UpdateRecords should not be called for empty input")
-					return nil
+					return nil, nil
 				},
 			},
 		},
@@ -204,7 +247,7 @@ func TestUpdater_UpdateRecords(t *testing.T) {
 				},
 			},
 			mock: &mockR53Updater{
-				updateRecordsFn: func(_ context.Context, records []r53.Record) error {
+				updateRecordsFn: func(_ context.Context, records []r53.Record) ([]r53.ZoneUpdate, error) {
 					assert.Equal(t, []r53.Record{
 						{
 							FQDN:       "vpn.example.com",
@@ -212,7 +255,26 @@ func TestUpdater_UpdateRecords(t *testing.T) {
 							RecordType: "A",
 						},
 					}, records)
-					return nil
+					return []r53.ZoneUpdate{
+						{
+							ZoneID:    "Z123",
+							Atomic:    true,
+							ChangeIDs: []string{"C1"},
+							Updated:   records,
+						},
+					}, nil
+				},
+			},
+			expectedResults: []domain.UpdateResult{
+				{
+					Provider:  "route53",
+					Zone:      "Z123",
+					Atomic:    true,
+					ChangeIDs: []string{"C1"},
+					Updated: []domain.Record{
+						{FQDN: "vpn.example.com", IP: "192.0.2.1", IPType: "A"},
+					},
+					Failed: []domain.Record{},
 				},
 			},
 		},
@@ -226,8 +288,27 @@ func TestUpdater_UpdateRecords(t *testing.T) {
 				},
 			},
 			mock: &mockR53Updater{
-				updateRecordsFn: func(context.Context, []r53.Record) error {
-					return updateErr
+				updateRecordsFn: func(_ context.Context, records []r53.Record) ([]r53.ZoneUpdate, error) {
+					return []r53.ZoneUpdate{
+						{
+							ZoneID: "Z123",
+							Atomic: true,
+							Failed: records,
+							Err:    updateErr,
+						},
+					}, updateErr
+				},
+			},
+			expectedResults: []domain.UpdateResult{
+				{
+					Provider: "route53",
+					Zone:     "Z123",
+					Atomic:   true,
+					Updated:  []domain.Record{},
+					Failed: []domain.Record{
+						{FQDN: "vpn.example.com", IP: "192.0.2.1", IPType: "A"},
+					},
+					Err: updateErr,
 				},
 			},
 			expectedError: updateErr,
@@ -238,6 +319,7 @@ func TestUpdater_UpdateRecords(t *testing.T) {
 		name := testCase.name
 		records := testCase.records
 		mock := testCase.mock
+		expectedResults := testCase.expectedResults
 		expectedError := testCase.expectedError
 
 		t.Run(name, func(t *testing.T) {
@@ -245,8 +327,9 @@ func TestUpdater_UpdateRecords(t *testing.T) {
 				r53Updater: mock,
 			}
 
-			err := u.UpdateRecords(context.Background(), records)
+			results, err := u.UpdateRecords(context.Background(), records)
 
+			assert.Equal(t, expectedResults, results)
 			if expectedError != nil {
 				assert.EqualError(t, err, expectedError.Error())
 			} else {
@@ -1,7 +1,16 @@
 package config
 
 import (
+	"context"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
 )
 
 const (
@@ -9,6 +18,25 @@ const (
 	configName     string = "config"
 )
 
+// redacted is shown instead of the values of the secretKeys.
+const redacted = "REDACTED"
+
+var secretKeys = map[string]bool{
+	"access-key":  true,
+	"secret-key":  true,
+	"external-id": true,
+	"token":       true,
+	"password":    true,
+}
+
+// urlKeys hold URLs whose userinfo, path or query may carry credentials, such
+// as the ones of Slack incoming webhooks. Only their scheme and host are shown
+// when redacting.
+var urlKeys = map[string]bool{
+	"url":    true,
+	"broker": true,
+}
+
 var paths = []string{
 	"/usr/local/etc/localenvironment/",
 	"/etc/localenvironment/",
@@ -16,7 +44,10 @@ var paths = []string{
 	".",
 }
 
+// conf is the config read from a file. mu serializes the reads of the
+// file with the ones of the settings.
 type conf struct {
+	mu sync.Mutex
 	vp *viper.Viper
 }
 
@@ -36,3 +67,147 @@ func New() (*conf, error) {
 
 	return cnf, nil
 }
+
+// NewFromFile reads the config from path instead of searching the default
+// locations.
+func NewFromFile(path string) (*conf, error) {
+	cnf := new(conf)
+	cnf.vp = viper.New()
+
+	cnf.vp.SetConfigFile(path)
+	cnf.vp.SetConfigType(configFileType)
+	if err := cnf.vp.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	return cnf, nil
+}
+
+// File returns the path of the config file in use.
+func (c *conf) File() string {
+	return c.vp.ConfigFileUsed()
+}
+
+// Reload reads the config file again. The settings read before are kept
+// when it fails.
+func (c *conf) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.vp.ReadInConfig()
+}
+
+// Watch reads the config file again every time it is written, until ctx is
+// done, and calls onChange with the result. The directory is watched so
+// files replaced by editors and symlinks swapped by Kubernetes are noticed.
+func (c *conf) Watch(ctx context.Context, onChange func(err error)) error {
+	file, err := filepath.Abs(c.File())
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close() //nolint:errcheck
+		return err
+	}
+
+	target, _ := filepath.EvalSymlinks(file)
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				current, _ := filepath.EvalSymlinks(file)
+				written := filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				if !written && (current == "" || current == target) {
+					continue
+				}
+
+				target = current
+				onChange(c.Reload())
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				onChange(err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// WriteYAML writes the config as YAML, replacing credentials with REDACTED
+// when redact is true.
+func (c *conf) WriteYAML(w io.Writer, redact bool) error {
+	c.mu.Lock()
+	settings := any(c.vp.AllSettings())
+	c.mu.Unlock()
+
+	if redact {
+		settings = redactSecrets(settings)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(settings); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func redactSecrets(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			if secretKeys[key] && item != "" {
+				out[key] = redacted
+				continue
+			}
+			if raw, ok := item.(string); ok && urlKeys[key] && raw != "" {
+				out[key] = redactURL(raw)
+				continue
+			}
+			out[key] = redactSecrets(item)
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			out = append(out, redactSecrets(item))
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// redactURL keeps the scheme and host of raw and hides the rest.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+
+	if u.User == nil && strings.Trim(u.Path, "/") == "" && u.RawQuery == "" && u.Fragment == "" {
+		return u.Scheme + "://" + u.Host
+	}
+
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func withConfigPaths(t *testing.T, searchPaths []string) {
@@ -61,3 +64,156 @@ func TestNew_ReturnsErrorWhenConfigMissing(t *testing.T) {
 		t.Fatal("New() error = nil, want error")
 	}
 }
+
+func TestNewFromFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, validSimpleDDNSYAML)
+	path := filepath.Join(dir, configName+"."+configFileType)
+
+	c, err := NewFromFile(path)
+	if err != nil {
+		t.Fatalf("NewFromFile() error = %v", err)
+	}
+	if c.File() != path {
+		t.Errorf("File() = %q, want %q", c.File(), path)
+	}
+
+	if _, err := NewFromFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Fatal("NewFromFile() error = nil, want error")
+	}
+}
+
+func TestWriteYAML_Redacted(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, validSimpleDDNSYAML)
+
+	c, err := NewFromFile(filepath.Join(dir, configName+"."+configFileType))
+	if err != nil {
+		t.Fatalf("NewFromFile() error = %v", err)
+	}
+
+	var out strings.Builder
+	if err := c.WriteYAML(&out, true); err != nil {
+		t.Fatalf("WriteYAML() error = %v", err)
+	}
+
+	if strings.Contains(out.String(), "1234567890") {
+		t.Errorf("WriteYAML() = %q, want credentials redacted", out.String())
+	}
+	if !strings.Contains(out.String(), "secret-key: REDACTED") {
+		t.Errorf("WriteYAML() = %q, want secret-key: REDACTED", out.String())
+	}
+	if !strings.Contains(out.String(), "fqdn: vpn.example.net.") {
+		t.Errorf("WriteYAML() = %q, want the records", out.String())
+	}
+}
+
+func TestWriteYAML_RedactedURLs(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, validSimpleDDNSYAML+`  notifications:
+    notifiers:
+      - type: slack
+        url: https://hooks.slack.com/services/T000/B000/XXXXXXXX
+      - type: ntfy
+        url: https://ntfy.example.net
+        topic: home
+`)
+
+	c, err := NewFromFile(filepath.Join(dir, configName+"."+configFileType))
+	if err != nil {
+		t.Fatalf("NewFromFile() error = %v", err)
+	}
+
+	var out strings.Builder
+	if err := c.WriteYAML(&out, true); err != nil {
+		t.Fatalf("WriteYAML() error = %v", err)
+	}
+
+	if strings.Contains(out.String(), "XXXXXXXX") {
+		t.Errorf("WriteYAML() = %q, want the Slack webhook redacted", out.String())
+	}
+	if !strings.Contains(out.String(), "url: https://hooks.slack.com/REDACTED") {
+		t.Errorf("WriteYAML() = %q, want the Slack host kept", out.String())
+	}
+	if !strings.Contains(out.String(), "url: https://ntfy.example.net") {
+		t.Errorf("WriteYAML() = %q, want URLs without credentials kept", out.String())
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, validSimpleDDNSYAML)
+
+	c, err := NewFromFile(filepath.Join(dir, configName+"."+configFileType))
+	if err != nil {
+		t.Fatalf("NewFromFile() error = %v", err)
+	}
+
+	writeConfigFile(t, dir, strings.Replace(validSimpleDDNSYAML, "log-level: \"debug\"", "log-level: \"warn\"", 1))
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	got, err := c.GetSimpleDDNSConfig()
+	if err != nil {
+		t.Fatalf("GetSimpleDDNSConfig() error = %v", err)
+	}
+	if got.DDNS.LogLevel != "warn" {
+		t.Errorf("LogLevel = %q, want %q", got.DDNS.LogLevel, "warn")
+	}
+
+	writeConfigFile(t, dir, "ddns: [")
+	if err := c.Reload(); err == nil {
+		t.Fatal("Reload() error = nil, want error")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, validSimpleDDNSYAML)
+
+	c, err := NewFromFile(filepath.Join(dir, configName+"."+configFileType))
+	if err != nil {
+		t.Fatalf("NewFromFile() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan error, 10)
+	if err := c.Watch(ctx, func(err error) { changed <- err }); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// an unreadable file is reported
+	writeConfigFile(t, dir, "ddns: [")
+	waitChange(t, changed, func(err error) bool { return err != nil })
+
+	// writes may be seen before the file is complete
+	writeConfigFile(t, dir, strings.Replace(validSimpleDDNSYAML, "log-level: \"debug\"", "log-level: \"warn\"", 1))
+	waitChange(t, changed, func(err error) bool {
+		if err != nil {
+			return false
+		}
+
+		got, err := c.GetSimpleDDNSConfig()
+		return err == nil && got.DDNS.LogLevel == "warn"
+	})
+}
+
+// waitChange waits for a change reported by Watch for which done is true.
+func waitChange(t *testing.T, changed <-chan error, done func(err error) bool) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case err := <-changed:
+			if done(err) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Watch() did not report the change")
+		}
+	}
+}
@@ -1,54 +1,372 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
 const ddnsConfigName = "ddns"
 
+// dnsNameRegex accepts fully qualified names whose labels may start with an
+// underscore, as used by SRV records (_service._proto.example.net).
+var dnsNameRegex = regexp.MustCompile(`^(_?[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.?$`)
+
 type SimpleDDNS struct {
 	DDNS DDNSConfig `mapstructure:"ddns"`
 }
 
+// ErrUnknownAccount is returned when a view refers to an account that is not
+// configured.
+var ErrUnknownAccount = errors.New("unknown account")
+
+// DDNSConfig holds the settings of the ddns daemon. The last published
+// records are kept, on disk when StateDir is set, and the providers are only
+// read again every ReconcileEverySeconds or after a failed update. Without
+// either of them the providers are read every check, with StateDir alone
+// every hour. With DryRun the changes are only logged, nothing is changed at
+// the providers.
+// LogFormat is text or json. ShutdownGraceSeconds is how long a stop waits
+// for the running update, UpdateTimeoutSeconds when not set. A failed check
+// is retried after RetryInitialSeconds, doubling with every failure in a row
+// up to CheckEverySeconds, and every wait is moved by up to JitterPercent.
 type DDNSConfig struct {
-	LogLevel             string      `mapstructure:"log-level"`
-	CheckEverySeconds    int         `mapstructure:"check-every-seconds"`
-	UpdateTimeoutSeconds int         `mapstructure:"process-timeout-seconds"`
-	AWS                  []AWSConfig `mapstructure:"aws" validate:"dive"`
+	LogLevel              string              `mapstructure:"log-level"`
+	LogFormat             string              `mapstructure:"log-format" validate:"omitempty,oneof=text json"`
+	CheckEverySeconds     int                 `mapstructure:"check-every-seconds"`
+	UpdateTimeoutSeconds  int                 `mapstructure:"process-timeout-seconds"`
+	DryRun                bool                `mapstructure:"dry-run"`
+	StateDir              string              `mapstructure:"state-dir"`
+	ReconcileEverySeconds int                 `mapstructure:"reconcile-every-seconds" validate:"min=0"`
+	ShutdownGraceSeconds  int                 `mapstructure:"shutdown-grace-seconds" validate:"min=0"`
+	RetryInitialSeconds   int                 `mapstructure:"retry-initial-seconds" validate:"min=0"`
+	JitterPercent         int                 `mapstructure:"jitter-percent" validate:"min=0,max=50"`
+	History               HistoryConfig       `mapstructure:"history"`
+	HTTP                  HTTPConfig          `mapstructure:"http"`
+	Tracing               TracingConfig       `mapstructure:"tracing"`
+	Notifications         NotificationsConfig `mapstructure:"notifications"`
+	MQTT                  MQTTConfig          `mapstructure:"mqtt"`
+	Hooks                 []HookConfig        `mapstructure:"hooks" validate:"dive"`
+	Control               ControlConfig       `mapstructure:"control"`
+	AWS                   []AWSConfig         `mapstructure:"aws" validate:"dive"`
+	Records               []ViewRecordConfig  `mapstructure:"records" validate:"dive"`
+}
+
+// HTTPConfig enables the HTTP listener of the daemon, serving the Prometheus
+// metrics at /metrics and the /healthz and /readyz checks. The daemon is
+// unhealthy when no cycle finished in HealthIntervals check intervals.
+type HTTPConfig struct {
+	Listen          string `mapstructure:"listen" validate:"omitempty,hostname_port"`
+	HealthIntervals int    `mapstructure:"health-intervals" validate:"min=0"`
+}
+
+// TracingConfig exports OpenTelemetry traces of the update cycles to an
+// OTLP/HTTP collector at Endpoint, tracing is disabled when it is empty.
+// SampleRatio is the share of cycles traced, all of them when zero.
+type TracingConfig struct {
+	Endpoint    string  `mapstructure:"endpoint" validate:"omitempty,hostname_port"`
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sample-ratio" validate:"min=0,max=1"`
+}
+
+// MQTTConfig publishes the addresses and the status of ddns and of every
+// record as retained messages under TopicPrefix (ddns by default) of the
+// Broker, tcp://, ssl:// or ws:// URL. Publishing is disabled when Broker is
+// empty. Discovery announces them to Home Assistant under DiscoveryPrefix
+// (homeassistant by default), with a button forcing an update.
+type MQTTConfig struct {
+	Broker          string `mapstructure:"broker" validate:"omitempty,url"`
+	ClientID        string `mapstructure:"client-id"`
+	Username        string `mapstructure:"username"`
+	Password        string `mapstructure:"password" validate:"required_with=Username"`
+	TopicPrefix     string `mapstructure:"topic-prefix"`
+	Discovery       bool   `mapstructure:"discovery"`
+	DiscoveryPrefix string `mapstructure:"discovery-prefix"`
+}
+
+// ControlConfig is the Unix socket `ddns control` uses to force a
+// reconcile, pause and resume the updates and read the status of the
+// running daemon. The socket is not created when Socket is empty.
+type ControlConfig struct {
+	Socket string `mapstructure:"socket"`
+}
+
+// HookConfig is an action run after the public address of a family in
+// Families (ipv4, ipv6, both when empty) changed. command hooks run Command
+// with the addresses in DDNS_* environment variables. security-group hooks
+// replace the ingress rule of the old address of GroupID with one of the new
+// address, using the credentials of the AWS account named Account; tcp and
+// udp rules need FromPort. A hook is stopped after TimeoutSeconds, 60 by
+// default.
+type HookConfig struct {
+	Name           string   `mapstructure:"name"`
+	Type           string   `mapstructure:"type" validate:"required,oneof=command security-group"`
+	Families       []string `mapstructure:"families" validate:"dive,oneof=ipv4 ipv6"`
+	TimeoutSeconds int      `mapstructure:"timeout-seconds" validate:"min=0"`
+	Command        []string `mapstructure:"command" validate:"required_if=Type command"`
+	Account        string   `mapstructure:"account" validate:"required_if=Type security-group"`
+	Region         string   `mapstructure:"region"`
+	GroupID        string   `mapstructure:"group-id" validate:"required_if=Type security-group,omitempty,startswith=sg-"`
+	Protocol       string   `mapstructure:"protocol" validate:"omitempty,oneof=tcp udp icmp icmpv6 -1"`
+	FromPort       int      `mapstructure:"from-port" validate:"min=-1,max=65535"`
+	ToPort         int      `mapstructure:"to-port" validate:"min=-1,max=65535"`
+	Description    string   `mapstructure:"description"`
+}
+
+// NotificationsConfig sends notifications of the ddns events to the
+// configured notifiers. update-failed is sent once FailureThreshold cycles in
+// a row failed, recovered when a cycle succeeds after it.
+type NotificationsConfig struct {
+	FailureThreshold int              `mapstructure:"failure-threshold" validate:"min=0"`
+	Notifiers        []NotifierConfig `mapstructure:"notifiers" validate:"dive"`
+}
+
+// NotifierConfig is a destination of the notifications. Events limits the
+// events sent to it, all of them when empty. Templates replace the default
+// message of an event, they are Go templates executed with the event.
+// RateLimitPerHour caps the notifications sent in any hour, 0 is unlimited.
+//
+// smtp notifiers send emails through the server of URL, smtp://host:port
+// upgrading the connection with STARTTLS and smtps://host:port with implicit
+// TLS. Servers not offering STARTTLS are refused unless AllowUnencrypted is
+// set. Username and Password log in with Auth, plain by default.
+// HTMLTemplates add an HTML part to the emails of their events.
+type NotifierConfig struct {
+	Name             string            `mapstructure:"name"`
+	Type             string            `mapstructure:"type" validate:"required,oneof=webhook ntfy gotify slack matrix smtp"`
+	URL              string            `mapstructure:"url" validate:"required,url"`
+	Token            string            `mapstructure:"token" validate:"required_if=Type gotify,required_if=Type matrix"`
+	Topic            string            `mapstructure:"topic" validate:"required_if=Type ntfy"`
+	Room             string            `mapstructure:"room" validate:"required_if=Type matrix"`
+	From             string            `mapstructure:"from" validate:"required_if=Type smtp,omitempty,email"`
+	To               []string          `mapstructure:"to" validate:"required_if=Type smtp,dive,email"`
+	Username         string            `mapstructure:"username"`
+	Password         string            `mapstructure:"password" validate:"required_with=Username"`
+	Auth             string            `mapstructure:"auth" validate:"omitempty,oneof=plain login"`
+	AllowUnencrypted bool              `mapstructure:"allow-unencrypted"`
+	Events           []string          `mapstructure:"events" validate:"dive,oneof=ip-changed record-updated update-failed recovered"`
+	Templates        map[string]string `mapstructure:"templates" validate:"dive,keys,oneof=ip-changed record-updated update-failed recovered,endkeys"`
+	HTMLTemplates    map[string]string `mapstructure:"html-templates" validate:"dive,keys,oneof=ip-changed record-updated update-failed recovered,endkeys"`
+	RateLimitPerHour int               `mapstructure:"rate-limit-per-hour" validate:"min=0"`
+}
+
+// HistoryConfig enables the append-only history of address changes and
+// record updates. The file is rotated when it grows over MaxSizeMB, keeping
+// MaxFiles rotated files.
+type HistoryConfig struct {
+	Path      string `mapstructure:"path"`
+	MaxSizeMB int    `mapstructure:"max-size-mb" validate:"min=0"`
+	MaxFiles  int    `mapstructure:"max-files" validate:"min=0"`
 }
 
+// AWSConfig holds the settings of one AWS account. Static access keys are
+// optional, without them credentials come from the standard SDK chain
+// (environment, shared config Profile, SSO cache, EC2/ECS metadata). When
+// RoleARN is set that identity assumes the role, using the web identity
+// token in WebIdentityTokenFile if given. Schedule applies to the records of
+// the account that have none, CheckEverySeconds when empty.
 type AWSConfig struct {
-	AccountName string       `mapstructure:"account-name" validate:"required,alphanum"`
-	Region      string       `mapstructure:"region"`
-	AccessKey   string       `mapstructure:"access-key"`
-	SecretKey   string       `mapstructure:"secret-key"`
-	Zones       []ZoneConfig `mapstructure:"zones" validate:"dive"`
+	AccountName          string         `mapstructure:"account-name" validate:"required,alphanum"`
+	Region               string         `mapstructure:"region"`
+	AccessKey            string         `mapstructure:"access-key" validate:"required_with=SecretKey"`
+	SecretKey            string         `mapstructure:"secret-key" validate:"required_with=AccessKey"`
+	Profile              string         `mapstructure:"profile" validate:"excluded_with=AccessKey"`
+	RoleARN              string         `mapstructure:"role-arn" validate:"required_with=ExternalID SessionName WebIdentityTokenFile"`
+	ExternalID           string         `mapstructure:"external-id"`
+	SessionName          string         `mapstructure:"session-name"`
+	WebIdentityTokenFile string         `mapstructure:"web-identity-token-file"`
+	ZoneCacheSeconds     int            `mapstructure:"zone-cache-seconds" validate:"min=0"`
+	IsolateFailures      bool           `mapstructure:"isolate-failures"`
+	Schedule             string         `mapstructure:"schedule"`
+	Zones                []ZoneConfig   `mapstructure:"zones" validate:"dive"`
+	Records              []RecordConfig `mapstructure:"records" validate:"dive"`
 }
 
+// ZoneConfig identifies a hosted zone either by ID or by Name. When only the
+// name is given the zone is resolved at runtime, using Private and the
+// optional VPC to tell apart zones that share the same name. A zone with a
+// VPC is private. Schedule applies to the records of the zone that have
+// none.
 type ZoneConfig struct {
-	ID      string         `mapstructure:"id" validate:"required,alphanum"`
-	Name    string         `mapstructure:"name"`
-	Records []RecordConfig `mapstructure:"records" validate:"dive"`
+	ID        string         `mapstructure:"id" validate:"required_without=Name,omitempty,alphanum"`
+	Name      string         `mapstructure:"name" validate:"required_without=ID"`
+	Private   bool           `mapstructure:"private"`
+	VPCID     string         `mapstructure:"vpc-id"`
+	VPCRegion string         `mapstructure:"vpc-region" validate:"required_with=VPCID"`
+	Schedule  string         `mapstructure:"schedule"`
+	Records   []RecordConfig `mapstructure:"records" validate:"dive"`
 }
 
+// RecordConfig describes a record managed by ddns. A and AAAA records hold
+// the detected addresses, the other types are derived from them: TXT holds
+// the addresses and the time of the last update, CNAME points to Target, SRV
+// advertises Port on Target and HTTPS/SVCB carry the addresses as ipv4hint
+// and ipv6hint next to Params. PTR keeps the reverse record of an A or AAAA
+// record in sync when one of the account zones is the reverse zone of the
+// address. IPSource tells where the addresses come from, the public address
+// detector when it is not set. Schedule is when the record is checked, an
+// interval such as 1m or a cron expression, the one of its zone or account
+// when empty.
 type RecordConfig struct {
-	FQDN       string `mapstructure:"fqdn" validate:"required,fqdn"`
-	RecordType string `mapstructure:"record-type" validate:"required,oneof=A AAAA"`
-	RecordTTL  int    `mapstructure:"record-ttl" validate:"required,min=1"`
+	FQDN          string             `mapstructure:"fqdn" validate:"required,dnsname"`
+	RecordType    string             `mapstructure:"record-type" validate:"required,oneof=A AAAA TXT CNAME SRV HTTPS SVCB"`
+	RecordTTL     int                `mapstructure:"record-ttl" validate:"required,min=1"`
+	Target        string             `mapstructure:"target" validate:"required_if=RecordType CNAME,required_if=RecordType SRV"`
+	Port          int                `mapstructure:"port" validate:"required_if=RecordType SRV,min=0,max=65535"`
+	Priority      int                `mapstructure:"priority" validate:"min=0,max=65535"`
+	Weight        int                `mapstructure:"weight" validate:"min=0,max=65535"`
+	Params        string             `mapstructure:"params"`
+	SetIdentifier string             `mapstructure:"set-identifier" validate:"required_with=Failover"`
+	Failover      string             `mapstructure:"failover" validate:"omitempty,oneof=PRIMARY SECONDARY"`
+	HealthCheck   *HealthCheckConfig `mapstructure:"health-check"`
+	PTR           bool               `mapstructure:"ptr"`
+	IPSource      *IPSourceConfig    `mapstructure:"ip-source"`
+	Schedule      string             `mapstructure:"schedule"`
+}
+
+// IPSourceConfig is where the addresses published by a record come from: the
+// public address detector, the addresses of a local network interface or
+// static addresses.
+type IPSourceConfig struct {
+	Type      string `mapstructure:"type" validate:"required,oneof=public interface static"`
+	Interface string `mapstructure:"interface" validate:"required_if=Type interface,excluded_unless=Type interface"`
+	IPv4      string `mapstructure:"ipv4" validate:"excluded_unless=Type static,omitempty,ipv4"`
+	IPv6      string `mapstructure:"ipv6" validate:"excluded_unless=Type static,omitempty,ipv6"`
+}
+
+// ViewRecordConfig is a record published with a different address in each
+// of its views, e.g. the LAN address in a private zone and the WAN address
+// in the public one. Views are folded into the zones of their accounts when
+// the config is loaded.
+type ViewRecordConfig struct {
+	RecordConfig `mapstructure:",squash"`
+	Views        []ViewConfig `mapstructure:"views" validate:"required,min=1,dive"`
+}
+
+// ViewConfig binds a record to a hosted zone of an account, given by ID or
+// by name, and to the source of its addresses.
+type ViewConfig struct {
+	Account  string          `mapstructure:"account" validate:"required"`
+	ZoneID   string          `mapstructure:"zone-id" validate:"required_without=Zone,omitempty,alphanum"`
+	Zone     string          `mapstructure:"zone" validate:"required_without=ZoneID"`
+	Private  bool            `mapstructure:"private"`
+	IPSource *IPSourceConfig `mapstructure:"ip-source" validate:"required"`
+}
+
+// HealthCheckConfig describes a Route53 health check that ddns keeps pointed
+// at the address published in the record.
+type HealthCheckConfig struct {
+	Type             string `mapstructure:"type" validate:"required,oneof=HTTP HTTPS TCP"`
+	Port             int    `mapstructure:"port" validate:"required,min=1,max=65535"`
+	ResourcePath     string `mapstructure:"resource-path" validate:"excluded_if=Type TCP"`
+	Host             string `mapstructure:"host" validate:"omitempty,fqdn"`
+	RequestInterval  int    `mapstructure:"request-interval" validate:"omitempty,oneof=10 30"`
+	FailureThreshold int    `mapstructure:"failure-threshold" validate:"omitempty,min=1,max=10"`
 }
 
 func (c *conf) GetSimpleDDNSConfig() (*SimpleDDNS, error) {
 	var simpleDDNS SimpleDDNS
-	if err := c.vp.UnmarshalKey(ddnsConfigName, &simpleDDNS.DDNS); err != nil {
+	c.mu.Lock()
+	err := c.vp.UnmarshalKey(ddnsConfigName, &simpleDDNS.DDNS)
+	c.mu.Unlock()
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
 	validate := validator.New()
+	if err := validate.RegisterValidation("dnsname", validateDNSName); err != nil {
+		return nil, err
+	}
+
+	validate.RegisterStructValidation(validateIPSource, IPSourceConfig{})
+	validate.RegisterStructValidation(validateHook, HookConfig{})
+
 	if err := validate.Struct(simpleDDNS); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	if err := simpleDDNS.DDNS.foldViews(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &simpleDDNS, nil
 }
+
+// foldViews adds a record to the zone of each view, adding the zone to its
+// account when it is not already configured.
+func (c *DDNSConfig) foldViews() error {
+	for _, record := range c.Records {
+		for _, view := range record.Views {
+			account := c.account(view.Account)
+			if account == nil {
+				return fmt.Errorf("%w %s in view of %s", ErrUnknownAccount, view.Account, record.FQDN)
+			}
+
+			viewRecord := record.RecordConfig
+			viewRecord.IPSource = view.IPSource
+
+			zone := account.zone(view)
+			zone.Records = append(zone.Records, viewRecord)
+		}
+	}
+
+	return nil
+}
+
+func (c *DDNSConfig) account(name string) *AWSConfig {
+	for i := range c.AWS {
+		if c.AWS[i].AccountName == name {
+			return &c.AWS[i]
+		}
+	}
+
+	return nil
+}
+
+func (c *AWSConfig) zone(view ViewConfig) *ZoneConfig {
+	for i := range c.Zones {
+		zone := &c.Zones[i]
+		if view.ZoneID != "" && zone.ID == view.ZoneID {
+			return zone
+		}
+
+		if view.ZoneID == "" && zone.Private == view.Private && zoneName(zone.Name) == zoneName(view.Zone) {
+			return zone
+		}
+	}
+
+	c.Zones = append(c.Zones, ZoneConfig{ID: view.ZoneID, Name: view.Zone, Private: view.Private})
+	return &c.Zones[len(c.Zones)-1]
+}
+
+func zoneName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+func validateDNSName(fl validator.FieldLevel) bool {
+	return dnsNameRegex.MatchString(fl.Field().String())
+}
+
+// validateIPSource requires at least one address for static sources.
+func validateIPSource(sl validator.StructLevel) {
+	source, ok := sl.Current().Interface().(IPSourceConfig)
+	if ok && source.Type == "static" && source.IPv4 == "" && source.IPv6 == "" {
+		sl.ReportError(source.IPv4, "IPv4", "IPv4", "required_without_all", "")
+	}
+}
+
+// validateHook requires the port of the tcp and udp security group rules,
+// tcp being the default protocol.
+func validateHook(sl validator.StructLevel) {
+	hook, ok := sl.Current().Interface().(HookConfig)
+	if !ok || hook.Type != "security-group" {
+		return
+	}
+
+	if (hook.Protocol == "" || hook.Protocol == "tcp" || hook.Protocol == "udp") && hook.FromPort <= 0 {
+		sl.ReportError(hook.FromPort, "FromPort", "FromPort", "required_if", "")
+	}
+}
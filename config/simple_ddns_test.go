@@ -56,6 +56,362 @@ ddns:
             record-type: A
             record-ttl: 3600
 `
+
+	zoneByNameSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  log-format: "json"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  dry-run: true
+  state-dir: "/var/lib/ddns"
+  reconcile-every-seconds: 3600
+  history:
+    path: "/var/lib/ddns/history.jsonl"
+    max-size-mb: 5
+    max-files: 3
+  http:
+    listen: ":9102"
+    health-intervals: 5
+  tracing:
+    endpoint: "otel-collector:4318"
+    insecure: true
+    sample-ratio: 0.5
+  notifications:
+    failure-threshold: 2
+    notifiers:
+      - name: "phone"
+        type: ntfy
+        url: "https://ntfy.sh"
+        topic: "home-ddns"
+        events: ["ip-changed", "update-failed"]
+        rate-limit-per-hour: 10
+      - type: slack
+        url: "https://hooks.slack.com/services/T000/B000/XXXX"
+        templates:
+          record-updated: "{{.FQDN}} now points at {{.NewValue}}"
+      - type: smtp
+        url: "smtp://mail.example.net:587"
+        from: "ddns@example.net"
+        to: ["admin@example.net", "oncall@example.net"]
+        username: "ddns"
+        password: "1234567890"
+        auth: login
+        html-templates:
+          update-failed: "<b>{{.Error}}</b>"
+  mqtt:
+    broker: "tcp://mqtt.example.net:1883"
+    client-id: "ddns-home"
+    username: "ddns"
+    password: "1234567890"
+    topic-prefix: "home/ddns"
+    discovery: true
+  hooks:
+    - name: "wireguard"
+      type: command
+      families: ["ipv4"]
+      command: ["/usr/local/bin/update-peer", "--endpoint"]
+      timeout-seconds: 30
+    - type: security-group
+      account: "example"
+      group-id: "sg-0123456789abcdef0"
+      protocol: tcp
+      from-port: 22
+      to-port: 22
+      description: "home"
+    - type: security-group
+      account: "example"
+      group-id: "sg-0123456789abcdef0"
+      protocol: "-1"
+  aws:
+    - account-name: "example"
+      region: "us-east-1"
+      zone-cache-seconds: 600
+      isolate-failures: true
+      zones:
+        - name: "example.net."
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 3600
+        - name: "internal.example.net."
+          private: true
+          vpc-id: "vpc-0123456789abcdef0"
+          vpc-region: "us-east-1"
+          records:
+          - fqdn: "nas.internal.example.net."
+            record-type: A
+            record-ttl: 300
+      records:
+      - fqdn: "home.example.org."
+        record-type: AAAA
+        record-ttl: 300
+`
+
+	healthCheckSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 60
+            set-identifier: "home"
+            failover: PRIMARY
+            health-check:
+              type: HTTPS
+              port: 443
+              resource-path: "/health"
+              host: "vpn.example.net"
+              request-interval: 30
+              failure-threshold: 3
+`
+
+	invalidHealthCheckSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 60
+            failover: PRIMARY
+            health-check:
+              type: TCP
+              port: 1194
+`
+
+	credentialChainSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "production"
+      region: "us-east-1"
+      profile: "ddns"
+      role-arn: "arn:aws:iam::111122223333:role/ddns"
+      external-id: "ddns-external-id"
+      session-name: "ddns-home"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 60
+    - account-name: "staging"
+      role-arn: "arn:aws:iam::444455556666:role/ddns"
+      web-identity-token-file: "/var/run/secrets/token"
+      zones:
+        - id: "Z0123456789ABCDEG"
+          records:
+          - fqdn: "vpn.example.org."
+            record-type: A
+            record-ttl: 60
+`
+
+	invalidCredentialsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      access-key: "1234567890"
+      external-id: "ddns-external-id"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 60
+`
+
+	derivedRecordsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: TXT
+            record-ttl: 60
+          - fqdn: "www.example.net."
+            record-type: CNAME
+            record-ttl: 60
+            target: "vpn.example.net."
+          - fqdn: "_openvpn._udp.example.net."
+            record-type: SRV
+            record-ttl: 60
+            target: "vpn.example.net."
+            port: 1194
+            priority: 10
+            weight: 5
+          - fqdn: "vpn.example.net."
+            record-type: HTTPS
+            record-ttl: 60
+            params: 'alpn="h2,h3"'
+`
+
+	invalidDerivedRecordsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "_openvpn._udp.example.net."
+            record-type: SRV
+            record-ttl: 60
+            target: "vpn.example.net."
+`
+
+	reverseRecordsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: AAAA
+            record-ttl: 60
+            ptr: true
+        - name: "8.b.d.0.1.0.0.2.ip6.arpa."
+`
+
+	viewsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - id: "Z0123456789ABCDEF"
+          records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 60
+  records:
+    - fqdn: "nas.example.net."
+      record-type: A
+      record-ttl: 300
+      views:
+        - account: "example"
+          zone-id: "Z0123456789ABCDEF"
+          ip-source:
+            type: public
+        - account: "example"
+          zone: "example.net."
+          private: true
+          ip-source:
+            type: static
+            ipv4: "192.168.1.10"
+`
+
+	invalidViewsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+  records:
+    - fqdn: "nas.example.net."
+      record-type: A
+      record-ttl: 300
+      views:
+        - account: "example"
+          zone: "example.net."
+          ip-source:
+            type: static
+`
+
+	unknownAccountViewsSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+  records:
+    - fqdn: "nas.example.net."
+      record-type: A
+      record-ttl: 300
+      views:
+        - account: "other"
+          zone: "example.net."
+          ip-source:
+            type: interface
+            interface: "eth0"
+`
+
+	invalidDaemonSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  log-format: "xml"
+  http:
+    listen: "9102"
+  notifications:
+    notifiers:
+      - type: matrix
+        url: "https://matrix.example.net"
+        templates:
+          ip-change: "{{.NewValue}}"
+      - type: smtp
+        url: "smtps://mail.example.net"
+        from: "ddns"
+        username: "ddns"
+  mqtt:
+    broker: "mqtt.example.net"
+  hooks:
+    - type: security-group
+      group-id: "0123"
+      families: ["ipv5"]
+  check-every-seconds: 300
+  aws:
+    - account-name: "example"
+      region: "us-east-1"
+      records:
+        - fqdn: "vpn.example.net."
+          record-type: A
+          record-ttl: 300
+`
+
+	zoneWithoutIDOrNameSimpleDDNSYAML = `
+ddns:
+  log-level: "info"
+  check-every-seconds: 300
+  process-timeout-seconds: 20
+  aws:
+    - account-name: "example"
+      zones:
+        - records:
+          - fqdn: "vpn.example.net."
+            record-type: A
+            record-ttl: 3600
+`
 )
 
 func TestGetSimpleDDNSConfig(t *testing.T) {
@@ -123,6 +479,348 @@ func TestGetSimpleDDNSConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "zones resolved by name and records without zone",
+			yaml: zoneByNameSimpleDDNSYAML,
+			expectedConfig: &SimpleDDNS{
+				DDNS: DDNSConfig{
+					LogLevel:              "info",
+					LogFormat:             "json",
+					CheckEverySeconds:     300,
+					UpdateTimeoutSeconds:  20,
+					DryRun:                true,
+					StateDir:              "/var/lib/ddns",
+					ReconcileEverySeconds: 3600,
+					History: HistoryConfig{
+						Path:      "/var/lib/ddns/history.jsonl",
+						MaxSizeMB: 5,
+						MaxFiles:  3,
+					},
+					HTTP: HTTPConfig{Listen: ":9102", HealthIntervals: 5},
+					Tracing: TracingConfig{
+						Endpoint:    "otel-collector:4318",
+						Insecure:    true,
+						SampleRatio: 0.5,
+					},
+					Notifications: NotificationsConfig{
+						FailureThreshold: 2,
+						Notifiers: []NotifierConfig{
+							{
+								Name:             "phone",
+								Type:             "ntfy",
+								URL:              "https://ntfy.sh",
+								Topic:            "home-ddns",
+								Events:           []string{"ip-changed", "update-failed"},
+								RateLimitPerHour: 10,
+							},
+							{
+								Type:      "slack",
+								URL:       "https://hooks.slack.com/services/T000/B000/XXXX",
+								Templates: map[string]string{"record-updated": "{{.FQDN}} now points at {{.NewValue}}"},
+							},
+							{
+								Type:          "smtp",
+								URL:           "smtp://mail.example.net:587",
+								From:          "ddns@example.net",
+								To:            []string{"admin@example.net", "oncall@example.net"},
+								Username:      "ddns",
+								Password:      "1234567890",
+								Auth:          "login",
+								HTMLTemplates: map[string]string{"update-failed": "<b>{{.Error}}</b>"},
+							},
+						},
+					},
+					MQTT: MQTTConfig{
+						Broker:      "tcp://mqtt.example.net:1883",
+						ClientID:    "ddns-home",
+						Username:    "ddns",
+						Password:    "1234567890",
+						TopicPrefix: "home/ddns",
+						Discovery:   true,
+					},
+					Hooks: []HookConfig{
+						{
+							Name:           "wireguard",
+							Type:           "command",
+							Families:       []string{"ipv4"},
+							Command:        []string{"/usr/local/bin/update-peer", "--endpoint"},
+							TimeoutSeconds: 30,
+						},
+						{
+							Type:        "security-group",
+							Account:     "example",
+							GroupID:     "sg-0123456789abcdef0",
+							Protocol:    "tcp",
+							FromPort:    22,
+							ToPort:      22,
+							Description: "home",
+						},
+						{
+							Type:     "security-group",
+							Account:  "example",
+							GroupID:  "sg-0123456789abcdef0",
+							Protocol: "-1",
+						},
+					},
+					AWS: []AWSConfig{
+						{
+							AccountName:      "example",
+							Region:           "us-east-1",
+							ZoneCacheSeconds: 600,
+							IsolateFailures:  true,
+							Zones: []ZoneConfig{
+								{
+									Name: "example.net.",
+									Records: []RecordConfig{
+										{FQDN: "vpn.example.net.", RecordType: "A", RecordTTL: 3600},
+									},
+								},
+								{
+									Name:      "internal.example.net.",
+									Private:   true,
+									VPCID:     "vpc-0123456789abcdef0",
+									VPCRegion: "us-east-1",
+									Records: []RecordConfig{
+										{FQDN: "nas.internal.example.net.", RecordType: "A", RecordTTL: 300},
+									},
+								},
+							},
+							Records: []RecordConfig{
+								{FQDN: "home.example.org.", RecordType: "AAAA", RecordTTL: 300},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "record with failover and health check",
+			yaml: healthCheckSimpleDDNSYAML,
+			expectedConfig: &SimpleDDNS{
+				DDNS: DDNSConfig{
+					LogLevel:             "info",
+					CheckEverySeconds:    300,
+					UpdateTimeoutSeconds: 20,
+					AWS: []AWSConfig{
+						{
+							AccountName: "example",
+							Zones: []ZoneConfig{
+								{
+									ID: "Z0123456789ABCDEF",
+									Records: []RecordConfig{
+										{
+											FQDN:          "vpn.example.net.",
+											RecordType:    "A",
+											RecordTTL:     60,
+											SetIdentifier: "home",
+											Failover:      "PRIMARY",
+											HealthCheck: &HealthCheckConfig{
+												Type:             "HTTPS",
+												Port:             443,
+												ResourcePath:     "/health",
+												Host:             "vpn.example.net",
+												RequestInterval:  30,
+												FailureThreshold: 3,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "failover record without set identifier",
+			yaml:           invalidHealthCheckSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: Key: 'SimpleDDNS.DDNS.AWS[0].Zones[0].Records[0].SetIdentifier' Error:Field validation for 'SetIdentifier' failed on the 'required_with' tag"),
+		},
+		{
+			name: "credentials from the sdk chain and assumed roles",
+			yaml: credentialChainSimpleDDNSYAML,
+			expectedConfig: &SimpleDDNS{
+				DDNS: DDNSConfig{
+					LogLevel:             "info",
+					CheckEverySeconds:    300,
+					UpdateTimeoutSeconds: 20,
+					AWS: []AWSConfig{
+						{
+							AccountName: "production",
+							Region:      "us-east-1",
+							Profile:     "ddns",
+							RoleARN:     "arn:aws:iam::111122223333:role/ddns",
+							ExternalID:  "ddns-external-id",
+							SessionName: "ddns-home",
+							Zones: []ZoneConfig{
+								{
+									ID: "Z0123456789ABCDEF",
+									Records: []RecordConfig{
+										{FQDN: "vpn.example.net.", RecordType: "A", RecordTTL: 60},
+									},
+								},
+							},
+						},
+						{
+							AccountName:          "staging",
+							RoleARN:              "arn:aws:iam::444455556666:role/ddns",
+							WebIdentityTokenFile: "/var/run/secrets/token",
+							Zones: []ZoneConfig{
+								{
+									ID: "Z0123456789ABCDEG",
+									Records: []RecordConfig{
+										{FQDN: "vpn.example.org.", RecordType: "A", RecordTTL: 60},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "access key without secret key and external id without role",
+			yaml:           invalidCredentialsSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: Key: 'SimpleDDNS.DDNS.AWS[0].SecretKey' Error:Field validation for 'SecretKey' failed on the 'required_with' tag\nKey: 'SimpleDDNS.DDNS.AWS[0].RoleARN' Error:Field validation for 'RoleARN' failed on the 'required_with' tag"),
+		},
+		{
+			name: "records derived from the addresses",
+			yaml: derivedRecordsSimpleDDNSYAML,
+			expectedConfig: &SimpleDDNS{
+				DDNS: DDNSConfig{
+					LogLevel:             "info",
+					CheckEverySeconds:    300,
+					UpdateTimeoutSeconds: 20,
+					AWS: []AWSConfig{
+						{
+							AccountName: "example",
+							Zones: []ZoneConfig{
+								{
+									ID: "Z0123456789ABCDEF",
+									Records: []RecordConfig{
+										{FQDN: "vpn.example.net.", RecordType: "TXT", RecordTTL: 60},
+										{FQDN: "www.example.net.", RecordType: "CNAME", RecordTTL: 60, Target: "vpn.example.net."},
+										{
+											FQDN:       "_openvpn._udp.example.net.",
+											RecordType: "SRV",
+											RecordTTL:  60,
+											Target:     "vpn.example.net.",
+											Port:       1194,
+											Priority:   10,
+											Weight:     5,
+										},
+										{FQDN: "vpn.example.net.", RecordType: "HTTPS", RecordTTL: 60, Params: `alpn="h2,h3"`},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "srv record without port",
+			yaml:           invalidDerivedRecordsSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: Key: 'SimpleDDNS.DDNS.AWS[0].Zones[0].Records[0].Port' Error:Field validation for 'Port' failed on the 'required_if' tag"),
+		},
+		{
+			name: "record with reverse dns",
+			yaml: reverseRecordsSimpleDDNSYAML,
+			expectedConfig: &SimpleDDNS{
+				DDNS: DDNSConfig{
+					LogLevel:             "info",
+					CheckEverySeconds:    300,
+					UpdateTimeoutSeconds: 20,
+					AWS: []AWSConfig{
+						{
+							AccountName: "example",
+							Zones: []ZoneConfig{
+								{
+									ID: "Z0123456789ABCDEF",
+									Records: []RecordConfig{
+										{FQDN: "vpn.example.net.", RecordType: "AAAA", RecordTTL: 60, PTR: true},
+									},
+								},
+								{Name: "8.b.d.0.1.0.0.2.ip6.arpa."},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "record views folded into the account zones",
+			yaml: viewsSimpleDDNSYAML,
+			expectedConfig: &SimpleDDNS{
+				DDNS: DDNSConfig{
+					LogLevel:             "info",
+					CheckEverySeconds:    300,
+					UpdateTimeoutSeconds: 20,
+					AWS: []AWSConfig{
+						{
+							AccountName: "example",
+							Zones: []ZoneConfig{
+								{
+									ID: "Z0123456789ABCDEF",
+									Records: []RecordConfig{
+										{FQDN: "vpn.example.net.", RecordType: "A", RecordTTL: 60},
+										{FQDN: "nas.example.net.", RecordType: "A", RecordTTL: 300, IPSource: &IPSourceConfig{Type: "public"}},
+									},
+								},
+								{
+									Name:    "example.net.",
+									Private: true,
+									Records: []RecordConfig{
+										{
+											FQDN:       "nas.example.net.",
+											RecordType: "A",
+											RecordTTL:  300,
+											IPSource:   &IPSourceConfig{Type: "static", IPv4: "192.168.1.10"},
+										},
+									},
+								},
+							},
+						},
+					},
+					Records: []ViewRecordConfig{
+						{
+							RecordConfig: RecordConfig{FQDN: "nas.example.net.", RecordType: "A", RecordTTL: 300},
+							Views: []ViewConfig{
+								{Account: "example", ZoneID: "Z0123456789ABCDEF", IPSource: &IPSourceConfig{Type: "public"}},
+								{Account: "example", Zone: "example.net.", Private: true, IPSource: &IPSourceConfig{Type: "static", IPv4: "192.168.1.10"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "static view without address",
+			yaml:           invalidViewsSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: Key: 'SimpleDDNS.DDNS.Records[0].Views[0].IPSource.IPv4' Error:Field validation for 'IPv4' failed on the 'required_without_all' tag"),
+		},
+		{
+			name:           "view of an unknown account",
+			yaml:           unknownAccountViewsSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: unknown account other in view of nas.example.net."),
+		},
+		{
+			name:           "invalid logging, listener, notifiers, mqtt and hooks",
+			yaml:           invalidDaemonSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: Key: 'SimpleDDNS.DDNS.LogFormat' Error:Field validation for 'LogFormat' failed on the 'oneof' tag\nKey: 'SimpleDDNS.DDNS.HTTP.Listen' Error:Field validation for 'Listen' failed on the 'hostname_port' tag\nKey: 'SimpleDDNS.DDNS.Notifications.Notifiers[0].Token' Error:Field validation for 'Token' failed on the 'required_if' tag\nKey: 'SimpleDDNS.DDNS.Notifications.Notifiers[0].Room' Error:Field validation for 'Room' failed on the 'required_if' tag\nKey: 'SimpleDDNS.DDNS.Notifications.Notifiers[0].Templates[ip-change]' Error:Field validation for 'Templates[ip-change]' failed on the 'oneof' tag\nKey: 'SimpleDDNS.DDNS.Notifications.Notifiers[1].From' Error:Field validation for 'From' failed on the 'email' tag\nKey: 'SimpleDDNS.DDNS.Notifications.Notifiers[1].To' Error:Field validation for 'To' failed on the 'required_if' tag\nKey: 'SimpleDDNS.DDNS.Notifications.Notifiers[1].Password' Error:Field validation for 'Password' failed on the 'required_with' tag\nKey: 'SimpleDDNS.DDNS.MQTT.Broker' Error:Field validation for 'Broker' failed on the 'url' tag\nKey: 'SimpleDDNS.DDNS.Hooks[0].Families[0]' Error:Field validation for 'Families[0]' failed on the 'oneof' tag\nKey: 'SimpleDDNS.DDNS.Hooks[0].Account' Error:Field validation for 'Account' failed on the 'required_if' tag\nKey: 'SimpleDDNS.DDNS.Hooks[0].GroupID' Error:Field validation for 'GroupID' failed on the 'startswith' tag\nKey: 'SimpleDDNS.DDNS.Hooks[0].FromPort' Error:Field validation for 'FromPort' failed on the 'required_if' tag"),
+		},
+		{
+			name:           "zone without id or name",
+			yaml:           zoneWithoutIDOrNameSimpleDDNSYAML,
+			expectedConfig: nil,
+			expectedError:  errors.New("invalid config: Key: 'SimpleDDNS.DDNS.AWS[0].Zones[0].ID' Error:Field validation for 'ID' failed on the 'required_without' tag\nKey: 'SimpleDDNS.DDNS.AWS[0].Zones[0].Name' Error:Field validation for 'Name' failed on the 'required_without' tag"),
+		},
 		{
 			name:           "invalid config simple DDNS configuration",
 			yaml:           invalidConfigSimpleDDNSYAML,